@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/service"
+)
+
+// runDedup merges the duplicate POIs of a region, or with dryRun only
+// reports them.
+func runDedup(ctx context.Context, dedup *service.DedupService, region string, opts service.DedupOptions, dryRun bool) {
+	groups, err := dedup.Run(ctx, region, opts, dryRun)
+	if err != nil {
+		log.Printf("Failed to merge duplicate POIs: %v", err)
+		return
+	}
+
+	merged := 0
+	for _, g := range groups {
+		merged += len(g.Duplicates)
+	}
+
+	if dryRun {
+		for _, g := range groups {
+			fmt.Printf("%s (%s)\n", g.Canonical.Name, poiRef(g.Canonical))
+			for _, d := range g.Duplicates {
+				fmt.Printf("  <- %s (%s): %.0f m, similarity %.2f\n", d.POI.Name, poiRef(d.POI), d.DistanceM, d.Similarity)
+			}
+		}
+		fmt.Printf("\nDry run: %d duplicates in %d groups would be merged\n", merged, len(groups))
+		return
+	}
+
+	fmt.Printf("\nDuplicates merged: %d into %d POIs\n", merged, len(groups))
+}
+
+func poiRef(p domain.POI) string {
+	if p.OsmID != nil {
+		return fmt.Sprintf("%s/%d", p.OsmType, *p.OsmID)
+	}
+	return p.ID.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dremotha/mapbot/internal/service"
+)
+
+// runEnrich fills POIs of a region from local Wikidata and Wikipedia dumps.
+func runEnrich(ctx context.Context, enrich *service.EnrichService, region string, dumps service.WikiDumps) {
+	if dumps.Wikidata == "" {
+		log.Fatalf("-enrich requires -wikidata")
+	}
+
+	stats, err := enrich.Run(ctx, region, dumps)
+	if err != nil {
+		log.Fatalf("Failed to enrich POIs: %v", err)
+	}
+
+	fmt.Printf("\nEnrichment completed. POIs with links: %d, Wikidata entities: %d, Wikipedia abstracts: %d, changed: %d\n",
+		stats.Linked, stats.Entities, stats.Abstracts, stats.Changed)
+}
@@ -5,21 +5,45 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dremotha/mapbot/internal/categories"
 	"github.com/dremotha/mapbot/internal/config"
+	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
 	"github.com/dremotha/mapbot/internal/infrastructure/postgres"
 	"github.com/dremotha/mapbot/internal/infrastructure/qdrant"
+	infraredis "github.com/dremotha/mapbot/internal/infrastructure/redis"
 	"github.com/dremotha/mapbot/internal/osm"
+	"github.com/dremotha/mapbot/internal/presets"
+	"github.com/dremotha/mapbot/internal/regions"
 	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/service"
 	"github.com/dremotha/mapbot/pkg/embedding"
 	pkgosm "github.com/dremotha/mapbot/pkg/osm"
 )
 
 func main() {
 	var (
-		queryType = flag.String("type", "all", "Query type: all, churches, memorials, historic")
+		queryType   = flag.String("type", "all", "Query type: all, churches, memorials, historic")
+		presetsPath = flag.String("presets", "", "Import preset routes from a YAML/JSON file instead of OSM data")
+		pbfPath     = flag.String("pbf", "", "Read OSM data from a local PBF extract instead of the Overpass API")
+		update      = flag.Bool("update", false, "Apply OSM replication diffs published since the last run")
+		replication = flag.String("replication", "", "Replication URL or local directory (default OSM_REPLICATION_URL)")
+		fromSeq     = flag.Int64("from-seq", -1, "Apply diffs after this sequence number instead of the stored one")
+		regionID    = flag.String("region", "", "Import region from the regions file (default OSM_REGION)")
+		bboxFlag    = flag.String("bbox", "", "Import area south,west,north,east instead of the region's own; POIs are still tagged with -region")
+		tileSize    = flag.Float64("tile-size", 0.5, "Split the area into Overpass queries of at most this many degrees per side")
+		restart     = flag.Bool("restart", false, "Discard the progress of an interrupted Overpass import and start over")
+		catPath     = flag.String("categories", "", "Category mapping file (default OSM_CATEGORIES_FILE)")
+		syncCats    = flag.Bool("sync-categories", false, "Sync the categories table with the category mapping file and exit")
+		dedupOnly   = flag.Bool("dedup", false, "Only merge duplicate POIs of the region and exit")
+		dryRun      = flag.Bool("dry-run", false, "With -dedup, report duplicates without merging them")
+		dedupDist   = flag.Float64("dedup-distance", service.DefaultDedupDistanceM, "Largest distance between duplicate POIs, meters")
+		dedupSim    = flag.Float64("dedup-similarity", service.DefaultDedupSimilarity, "Least name similarity of duplicate POIs, 0-1")
+		enrich      = flag.Bool("enrich", false, "Only enrich POIs of the region from Wikidata/Wikipedia dumps and exit")
+		wikidata    = flag.String("wikidata", "", "With -enrich, Wikidata JSON dump (.json, .json.gz or .json.bz2)")
+		wikipedia   = flag.String("wikipedia", "", "With -enrich, Russian Wikipedia abstract dump (ruwiki-*-abstract.xml[.gz])")
 	)
 	flag.Parse()
 
@@ -34,6 +58,57 @@ func main() {
 	}
 	defer pool.Close()
 
+	if *catPath == "" {
+		*catPath = cfg.OSM.CategoriesFile
+	}
+	mapping, err := categories.Load(*catPath)
+	if err != nil {
+		log.Fatalf("Failed to load categories: %v", err)
+	}
+
+	if *presetsPath != "" {
+		importPresets(ctx, pool, osrm.NewClient(cfg.OSRM), *presetsPath, mapping)
+		return
+	}
+
+	if _, ok := tagFilters[*queryType]; !ok {
+		log.Fatalf("Unknown query type: %s", *queryType)
+	}
+
+	if *regionID == "" {
+		*regionID = cfg.OSM.Region
+	}
+	region := resolveRegion(cfg.OSM.RegionsFile, *regionID, *bboxFlag)
+
+	// Redis (optional): invalidates server caches and keeps embeddings of
+	// unchanged POIs between imports
+	var cacheManager *service.CacheManager
+	redisClient, err := infraredis.NewClient(ctx, cfg.Redis)
+	if err != nil {
+		log.Printf("Warning: Redis not available: %v. Server caches will expire by TTL.", err)
+	} else {
+		defer redisClient.Close()
+		cacheManager, err = service.NewCacheManager(redisClient, cfg.Cache)
+		if err != nil {
+			log.Fatalf("Invalid cache config: %v", err)
+		}
+	}
+
+	categoryRepo := repository.NewCategoryRepository(pool)
+	if *syncCats {
+		syncCategories(ctx, categoryRepo, mapping, cacheManager)
+		return
+	}
+
+	// POIs must not get categories the API does not know about
+	missing, err := categoryRepo.Missing(ctx, mapping.IDs())
+	if err != nil {
+		log.Fatalf("Failed to check categories: %v", err)
+	}
+	if len(missing) > 0 {
+		log.Fatalf("Unknown categories %v in %s; run the importer with -sync-categories first", missing, *catPath)
+	}
+
 	// Qdrant (optional)
 	var qdrantClient *qdrant.Client
 	var embeddingClient *embedding.Client
@@ -63,100 +138,216 @@ func main() {
 				defer embeddingClient.Close()
 
 				// Create repository for indexing
-				qdrantRepo = repository.NewQdrantPOIRepository(qdrantClient, embeddingClient)
+				embedder := service.NewCachedEmbeddingClient(embeddingClient, cacheManager, cfg.Embedding.CacheSize)
+				qdrantRepo = repository.NewQdrantPOIRepository(qdrantClient, embedder)
 			}
 		}
 	}
 
 	poiRepo := repository.NewPOIRepository(pool)
-	client := pkgosm.NewOverpassClient()
-	parser := osm.NewParser()
-
-	bbox := pkgosm.MoscowBBox
-	log.Printf("Using bbox: %.4f,%.4f,%.4f,%.4f", bbox.South, bbox.West, bbox.North, bbox.East)
-
-	var resp *pkgosm.OverpassResponse
-
-	// Retry Overpass queries to handle occasional 504s
-	maxAttempts := 5
-	backoff := 15 * time.Second
-	var lastErr error
-
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		switch *queryType {
-		case "churches":
-			log.Printf("Querying churches from OSM... (attempt %d/%d)", attempt, maxAttempts)
-			resp, err = client.QueryChurches(ctx, bbox)
-		case "memorials":
-			log.Printf("Querying memorials from OSM... (attempt %d/%d)", attempt, maxAttempts)
-			resp, err = client.QueryMemorials(ctx, bbox)
-		case "historic", "all":
-			log.Printf("Querying all historic places from OSM... (attempt %d/%d)", attempt, maxAttempts)
-			resp, err = client.QueryHistoricPlaces(ctx, bbox)
-		default:
-			log.Fatalf("Unknown query type: %s", *queryType)
-		}
+	factRepo := repository.NewHistoricalFactRepository(pool)
+	factService := service.NewHistoricalFactService(factRepo, poiRepo, qdrantRepo)
+	if cacheManager != nil {
+		pending := cacheManager.Deferred()
+		poiRepo.OnChange(pending.POIsChanged)
+		defer pending.Flush(ctx)
+	}
+	parser := osm.NewParser(mapping)
 
-		if err == nil {
-			break
-		}
+	log.Printf("Using region %s, bbox: %s", region.ID, region.BBox)
 
-		lastErr = err
-		log.Printf("Failed to query OSM (attempt %d/%d): %v", attempt, maxAttempts, err)
-		time.Sleep(backoff)
+	dedup := service.NewDedupService(poiRepo, factRepo, qdrantRepo)
+	dedupOpts := service.DedupOptions{MaxDistanceM: *dedupDist, MinSimilarity: *dedupSim}
+	if *dedupOnly {
+		runDedup(ctx, dedup, region.ID, dedupOpts, *dryRun)
+		return
 	}
 
-	if err != nil {
-		log.Fatalf("Failed to query OSM after %d attempts: %v", maxAttempts, lastErr)
+	if *enrich {
+		dumps := service.WikiDumps{Wikidata: *wikidata, Wikipedia: *wikipedia}
+		runEnrich(ctx, service.NewEnrichService(poiRepo, factRepo, qdrantRepo), region.ID, dumps)
+		return
 	}
 
-	log.Printf("Received %d elements from OSM", len(resp.Elements))
-
-	pois := parser.ParseElements(resp.Elements)
-	log.Printf("Parsed %d POIs", len(pois))
+	if *update {
+		if !fullImportTypes[*queryType] {
+			log.Fatalf("-update requires -type all or historic")
+		}
+		source := *replication
+		if source == "" {
+			source = cfg.OSM.ReplicationURL
+		}
+		applyUpdates(ctx, updater{
+			replication: pkgosm.NewReplication(source),
+			source:      source,
+			stateKey:    region.ID + "@" + source,
+			state:       repository.NewReplicationRepository(pool),
+			poiRepo:     poiRepo,
+			qdrantRepo:  qdrantRepo,
+			facts:       factService,
+			parser:      parser,
+			filter:      tagFilters[*queryType],
+			region:      region,
+		}, *fromSeq)
+		runDedup(ctx, dedup, region.ID, dedupOpts, false)
+		return
+	}
 
-	if len(pois) == 0 {
-		log.Println("No POIs to import")
-		os.Exit(0)
+	imp := &poiImporter{
+		poiRepo:    poiRepo,
+		qdrantRepo: qdrantRepo,
+		facts:      factService,
+		parser:     parser,
+		region:     region,
 	}
 
-	batchSize := 50
-	imported := 0
-	indexed := 0
+	var seen []repository.OSMRef
+	complete := true
 
-	for i := 0; i < len(pois); i += batchSize {
-		end := i + batchSize
-		if end > len(pois) {
-			end = len(pois)
+	if *pbfPath != "" {
+		log.Printf("Reading OSM extract %s...", *pbfPath)
+		elements, err := pkgosm.ReadPBF(ctx, *pbfPath, tagFilters[*queryType], &region.BBox)
+		if err != nil {
+			log.Fatalf("Failed to read PBF extract: %v", err)
 		}
+		log.Printf("Received %d elements from OSM", len(elements))
 
-		batch := pois[i:end]
+		seen, complete = imp.importElements(ctx, elements)
+	} else {
+		tiles := region.Tiles(*tileSize)
+		runKey := fmt.Sprintf("%s:%s:%g:%s", region.ID, *queryType, *tileSize, region.BBox)
 
-		// Import to PostgreSQL
-		if err := poiRepo.CreateBatch(ctx, batch); err != nil {
-			log.Printf("Failed to import batch %d-%d to Postgres: %v", i, end, err)
-			continue
+		checkpoints := repository.NewImportCheckpointRepository(pool)
+		if *restart {
+			if err := checkpoints.Clear(ctx, runKey); err != nil {
+				log.Fatalf("Failed to clear import checkpoints: %v", err)
+			}
 		}
 
-		imported += len(batch)
-		log.Printf("Imported to Postgres: %d/%d POIs", imported, len(pois))
+		log.Printf("Querying Overpass at %s in %d tiles", cfg.OSM.OverpassURL, len(tiles))
+		client := pkgosm.NewOverpassClient(cfg.OSM.OverpassURL, cfg.OSM.OverpassTimeout)
+		seen, complete = imp.importTiles(ctx, client, checkpoints, runKey, *queryType, tiles)
 
-		// Index in Qdrant if available
-		if qdrantRepo != nil {
-			if err := qdrantRepo.IndexBatch(ctx, batch); err != nil {
-				log.Printf("Warning: Failed to index batch %d-%d in Qdrant: %v", i, end, err)
-			} else {
-				indexed += len(batch)
-				log.Printf("Indexed in Qdrant: %d/%d POIs", indexed, len(pois))
+		if complete {
+			if err := checkpoints.Clear(ctx, runKey); err != nil {
+				log.Printf("Warning: %v", err)
 			}
+		} else {
+			log.Println("Some tiles failed; run the importer again to resume from them")
+		}
+	}
+
+	// Only a full, successful import shows which POIs disappeared from OSM
+	switch {
+	case !complete:
+		log.Println("Import incomplete, skipping removal of missing POIs")
+	case len(seen) == 0:
+		log.Println("No POIs imported, skipping removal of missing POIs")
+	case !fullImportTypes[*queryType]:
+		log.Printf("Partial import (-type %s), skipping removal of missing POIs", *queryType)
+	default:
+		imp.removeMissing(ctx, seen)
+	}
+
+	imp.report()
+
+	if complete {
+		runDedup(ctx, dedup, region.ID, dedupOpts, false)
+	}
+}
+
+// fullImportTypes select every POI the importer knows about, so objects
+// missing from their results were deleted in OSM.
+var fullImportTypes = map[string]bool{
+	"all":      true,
+	"historic": true,
+}
+
+// tagFilters mirror the Overpass queries selected by -type; they select
+// elements from PBF extracts and replication diffs.
+var tagFilters = map[string]pkgosm.TagFilter{
+	"all":       pkgosm.HistoricPlacesFilter,
+	"historic":  pkgosm.HistoricPlacesFilter,
+	"churches":  pkgosm.ChurchesFilter,
+	"memorials": pkgosm.MemorialsFilter,
+}
+
+// resolveRegion returns the region to import. An explicit bbox replaces
+// the region's area but keeps its ID for tagging.
+func resolveRegion(regionsFile, id, bbox string) regions.Region {
+	if bbox != "" {
+		b, err := pkgosm.ParseBBox(bbox)
+		if err != nil {
+			log.Fatalf("Invalid -bbox: %v", err)
 		}
+		return regions.Region{ID: id, BBox: b}
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	list, err := regions.Load(regionsFile)
+	if err != nil {
+		log.Fatalf("Failed to load regions: %v", err)
 	}
 
-	fmt.Printf("\nImport completed. Total POIs imported to Postgres: %d", imported)
-	if qdrantRepo != nil {
-		fmt.Printf(", indexed in Qdrant: %d", indexed)
+	region, ok := regions.Find(list, id)
+	if !ok {
+		log.Fatalf("Unknown region %q in %s", id, regionsFile)
 	}
-	fmt.Println()
+	return region
+}
+
+// filterRegion drops elements outside the region's polygon.
+func filterRegion(elements []pkgosm.Element, region regions.Region) []pkgosm.Element {
+	kept := elements[:0]
+	for _, el := range elements {
+		lat, lon := el.Lat, el.Lon
+		if el.Center != nil {
+			lat, lon = el.Center.Lat, el.Center.Lon
+		}
+		if region.Contains(lat, lon) {
+			kept = append(kept, el)
+		}
+	}
+	return kept
+}
+
+func importPresets(ctx context.Context, pool *pgxpool.Pool, osrmClient *osrm.Client, path string, mapping *categories.Mapping) {
+	defs, err := presets.Load(path)
+	if err != nil {
+		log.Fatalf("Failed to load preset routes: %v", err)
+	}
+	for _, def := range defs {
+		if def.Category != "" && !mapping.Has(def.Category) {
+			log.Fatalf("Preset %s: unknown category %q", def.ID, def.Category)
+		}
+	}
+	log.Printf("Loaded %d preset routes from %s", len(defs), path)
+
+	presetService := service.NewPresetRouteService(
+		repository.NewPresetRouteRepository(pool),
+		repository.NewPOIRepository(pool),
+		osrmClient,
+		nil,
+	)
+
+	unresolved, err := presetService.Import(ctx, defs)
+	if err != nil {
+		log.Fatalf("Failed to import preset routes: %v", err)
+	}
+
+	fmt.Printf("\nPreset import completed. Routes: %d, stops without POI: %d\n", len(defs), unresolved)
+}
+
+func syncCategories(ctx context.Context, repo *repository.CategoryRepository, mapping *categories.Mapping, cacheManager *service.CacheManager) {
+	removed, err := repo.Sync(ctx, mapping.Categories)
+	if err != nil {
+		log.Fatalf("Failed to sync categories: %v", err)
+	}
+
+	if cacheManager != nil {
+		if err := cacheManager.Invalidate(ctx, service.CacheNamespacePOI); err != nil {
+			log.Printf("Warning: Failed to invalidate caches: %v", err)
+		}
+	}
+
+	fmt.Printf("\nCategories synced: %d, removed: %d %v\n", len(mapping.Categories), len(removed), removed)
 }
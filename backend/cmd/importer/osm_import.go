@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/osm"
+	"github.com/dremotha/mapbot/internal/regions"
+	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/service"
+	pkgosm "github.com/dremotha/mapbot/pkg/osm"
+)
+
+// poiImporter writes OSM elements of one region to Postgres and Qdrant and
+// accumulates the statistics of the run.
+type poiImporter struct {
+	poiRepo    *repository.POIRepository
+	qdrantRepo *repository.QdrantPOIRepository
+	facts      *service.HistoricalFactService
+	parser     *osm.Parser
+	region     regions.Region
+
+	stats   repository.UpsertStats
+	indexed int
+}
+
+// importElements upserts the elements in batches and indexes changed POIs.
+// It returns the OSM objects that became POIs and whether all batches
+// were written.
+func (imp *poiImporter) importElements(ctx context.Context, elements []pkgosm.Element) ([]repository.OSMRef, bool) {
+	pois := imp.parser.ParseElements(filterRegion(elements, imp.region))
+	for i := range pois {
+		pois[i].Region = imp.region.ID
+	}
+
+	batchSize := 50
+	ok := true
+	seen := make([]repository.OSMRef, 0, len(pois))
+
+	for i := 0; i < len(pois); i += batchSize {
+		end := i + batchSize
+		if end > len(pois) {
+			end = len(pois)
+		}
+
+		// Upsert to PostgreSQL
+		changed, batchStats, err := imp.poiRepo.UpsertOSM(ctx, pois[i:end])
+		if err != nil {
+			log.Printf("Failed to import batch %d-%d to Postgres: %v", i, end, err)
+			ok = false
+			continue
+		}
+
+		imp.stats.Add(batchStats)
+		for _, poi := range pois[i:end] {
+			seen = append(seen, repository.OSMRef{Type: poi.OsmType, ID: *poi.OsmID})
+		}
+		log.Printf("Imported to Postgres: %d/%d POIs (%d new, %d updated)", end, len(pois), batchStats.Inserted, batchStats.Updated)
+
+		// Index changed POIs in Qdrant if available
+		if imp.qdrantRepo != nil && len(changed) > 0 {
+			if err := imp.qdrantRepo.IndexBatch(ctx, changed); err != nil {
+				log.Printf("Warning: Failed to index batch %d-%d in Qdrant: %v", i, end, err)
+			} else {
+				imp.indexed += len(changed)
+				log.Printf("Indexed in Qdrant: %d POIs", imp.indexed)
+			}
+			if err := imp.facts.Index(ctx, poiIDs(changed)); err != nil {
+				log.Printf("Warning: Failed to index facts of batch %d-%d in Qdrant: %v", i, end, err)
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return seen, ok
+}
+
+// importTiles queries Overpass tile by tile and checkpoints every imported
+// tile, so a rerun with the same parameters skips them. It returns the OSM
+// objects of all tiles, including those of earlier runs, and whether every
+// tile is done.
+func (imp *poiImporter) importTiles(ctx context.Context, client *pkgosm.OverpassClient, checkpoints *repository.ImportCheckpointRepository, runKey, queryType string, tiles []pkgosm.BBox) ([]repository.OSMRef, bool) {
+	completed, err := checkpoints.Completed(ctx, runKey)
+	if err != nil {
+		log.Fatalf("Failed to load import checkpoints: %v", err)
+	}
+
+	var seen []repository.OSMRef
+	done := make(map[int]bool, len(completed))
+	for _, cp := range completed {
+		if cp.Tile >= len(tiles) || cp.BBox != tiles[cp.Tile].String() {
+			log.Printf("Import checkpoints for %s do not match the tiles, starting over", runKey)
+			if err := checkpoints.Clear(ctx, runKey); err != nil {
+				log.Fatalf("Failed to clear import checkpoints: %v", err)
+			}
+			seen, done = nil, map[int]bool{}
+			break
+		}
+		done[cp.Tile] = true
+		seen = append(seen, cp.Seen...)
+	}
+	if len(done) > 0 {
+		log.Printf("Resuming import: %d/%d tiles already done", len(done), len(tiles))
+	}
+
+	// Ways and relations crossing tile borders are returned by several tiles
+	seenSet := make(map[repository.OSMRef]bool, len(seen))
+	for _, ref := range seen {
+		seenSet[ref] = true
+	}
+
+	complete := true
+	for i, tile := range tiles {
+		if done[i] {
+			continue
+		}
+		log.Printf("Tile %d/%d: %s", i+1, len(tiles), tile)
+
+		resp, err := queryTile(ctx, client, queryType, tile)
+		if err != nil {
+			log.Printf("Failed to query tile %d: %v", i+1, err)
+			complete = false
+			continue
+		}
+
+		fresh := make([]pkgosm.Element, 0, len(resp.Elements))
+		for _, el := range resp.Elements {
+			if !seenSet[repository.OSMRef{Type: el.Type, ID: el.ID}] {
+				fresh = append(fresh, el)
+			}
+		}
+		log.Printf("Received %d elements from OSM", len(fresh))
+
+		tileSeen, ok := imp.importElements(ctx, fresh)
+		for _, ref := range tileSeen {
+			seenSet[ref] = true
+		}
+		seen = append(seen, tileSeen...)
+
+		if !ok {
+			complete = false
+			continue
+		}
+		if err := checkpoints.Complete(ctx, runKey, i, tile.String(), tileSeen); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	return seen, complete
+}
+
+// removeMissing soft-deletes POIs of the region that the import did not see.
+func (imp *poiImporter) removeMissing(ctx context.Context, seen []repository.OSMRef) {
+	removed, err := imp.poiRepo.DeleteMissingOSM(ctx, imp.region.WKT(), seen)
+	if err != nil {
+		log.Printf("Failed to remove missing POIs: %v", err)
+		return
+	}
+
+	imp.stats.Removed = len(removed)
+	if imp.qdrantRepo != nil {
+		if err := imp.qdrantRepo.Delete(ctx, removed); err != nil {
+			log.Printf("Warning: Failed to remove missing POIs from Qdrant: %v", err)
+		}
+	}
+}
+
+func poiIDs(pois []domain.POI) []uuid.UUID {
+	ids := make([]uuid.UUID, len(pois))
+	for i := range pois {
+		ids[i] = pois[i].ID
+	}
+	return ids
+}
+
+func (imp *poiImporter) report() {
+	fmt.Printf("\nImport completed. Inserted: %d, updated: %d, unchanged: %d, removed: %d",
+		imp.stats.Inserted, imp.stats.Updated, imp.stats.Unchanged, imp.stats.Removed)
+	if imp.qdrantRepo != nil {
+		fmt.Printf(", indexed in Qdrant: %d", imp.indexed)
+	}
+	fmt.Println()
+}
+
+func queryTile(ctx context.Context, client *pkgosm.OverpassClient, queryType string, bbox pkgosm.BBox) (*pkgosm.OverpassResponse, error) {
+	switch queryType {
+	case "churches":
+		log.Println("Querying churches from OSM...")
+		return client.QueryChurches(ctx, bbox)
+	case "memorials":
+		log.Println("Querying memorials from OSM...")
+		return client.QueryMemorials(ctx, bbox)
+	default:
+		log.Println("Querying all historic places from OSM...")
+		return client.QueryHistoricPlaces(ctx, bbox)
+	}
+}
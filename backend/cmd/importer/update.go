@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dremotha/mapbot/internal/osm"
+	"github.com/dremotha/mapbot/internal/regions"
+	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/service"
+	pkgosm "github.com/dremotha/mapbot/pkg/osm"
+)
+
+// updater applies OSM replication diffs to the POI tables of one region.
+type updater struct {
+	replication *pkgosm.Replication
+	source      string
+	stateKey    string // progress of this region in this source
+	state       *repository.ReplicationRepository
+	poiRepo     *repository.POIRepository
+	qdrantRepo  *repository.QdrantPOIRepository
+	facts       *service.HistoricalFactService
+	parser      *osm.Parser
+	filter      pkgosm.TagFilter
+	region      regions.Region
+}
+
+// applyUpdates applies every diff after the last applied one (or fromSeq).
+// The sequence number is saved after each diff; since the upsert is
+// idempotent, a diff interrupted halfway is simply applied again.
+func applyUpdates(ctx context.Context, u updater, fromSeq int64) {
+	latest, err := u.replication.State(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read replication state from %s: %v", u.source, err)
+	}
+
+	last := fromSeq
+	if last < 0 {
+		last, err = u.state.GetSequence(ctx, u.stateKey)
+		if errors.Is(err, repository.ErrNoReplicationState) {
+			// Nothing tells which diffs the data already contains
+			if err := u.state.SaveSequence(ctx, u.stateKey, latest.Sequence, latest.Timestamp); err != nil {
+				log.Fatalf("Failed to save replication state: %v", err)
+			}
+			log.Printf("No replication state for %s, starting from sequence %d. Use -from-seq to apply older diffs.", u.source, latest.Sequence)
+			return
+		}
+		if err != nil {
+			log.Fatalf("Failed to load replication state: %v", err)
+		}
+	}
+
+	if last >= latest.Sequence {
+		log.Printf("Up to date at sequence %d (%s)", last, latest.Timestamp.Format("2006-01-02 15:04"))
+		return
+	}
+
+	log.Printf("Applying diffs %d-%d from %s", last+1, latest.Sequence, u.source)
+
+	var total repository.UpsertStats
+	for seq := last + 1; seq <= latest.Sequence; seq++ {
+		stats, err := u.applyDiff(ctx, seq)
+		if err != nil {
+			log.Fatalf("Failed to apply diff %d: %v", seq, err)
+		}
+		total.Add(stats)
+	}
+
+	fmt.Printf("\nUpdate completed up to sequence %d. Inserted: %d, updated: %d, unchanged: %d, removed: %d\n",
+		latest.Sequence, total.Inserted, total.Updated, total.Unchanged, total.Removed)
+}
+
+func (u updater) applyDiff(ctx context.Context, seq int64) (repository.UpsertStats, error) {
+	var stats repository.UpsertStats
+
+	state, err := u.replication.DiffState(ctx, seq)
+	if err != nil {
+		return stats, err
+	}
+
+	rc, err := u.replication.Diff(ctx, seq)
+	if err != nil {
+		return stats, err
+	}
+	changes, err := pkgosm.ReadChange(rc, u.filter, &u.region.BBox)
+	rc.Close()
+	if err != nil {
+		return stats, err
+	}
+
+	// Ways and relations whose nodes did not change keep their location
+	var unlocated []repository.OSMRef
+	for _, el := range changes.Upserts {
+		if el.Type != "node" && el.Center == nil {
+			unlocated = append(unlocated, repository.OSMRef{Type: el.Type, ID: el.ID})
+		}
+	}
+	locations, err := u.poiRepo.LocationsByOSMRefs(ctx, unlocated)
+	if err != nil {
+		return stats, err
+	}
+
+	upserts := make([]pkgosm.Element, 0, len(changes.Upserts))
+	skipped := 0
+	for _, el := range changes.Upserts {
+		if el.Type != "node" && el.Center == nil {
+			loc, ok := locations[repository.OSMRef{Type: el.Type, ID: el.ID}]
+			if !ok {
+				skipped++
+				continue
+			}
+			el.Center = &pkgosm.Point{Lat: loc.Lat, Lon: loc.Lng}
+		}
+		upserts = append(upserts, el)
+	}
+
+	// Elements that moved out of the region polygon are dropped and then
+	// deleted below as unparsed
+	inside := filterRegion(append([]pkgosm.Element(nil), upserts...), u.region)
+
+	pois := u.parser.ParseElements(inside)
+	for i := range pois {
+		pois[i].Region = u.region.ID
+	}
+
+	// Elements the parser rejects (e.g. the name was removed) are no
+	// longer POIs
+	parsed := make(map[repository.OSMRef]bool, len(pois))
+	for _, poi := range pois {
+		parsed[repository.OSMRef{Type: poi.OsmType, ID: *poi.OsmID}] = true
+	}
+	var deletes []repository.OSMRef
+	for _, el := range upserts {
+		if ref := (repository.OSMRef{Type: el.Type, ID: el.ID}); !parsed[ref] {
+			deletes = append(deletes, ref)
+		}
+	}
+	for _, el := range changes.Deletes {
+		deletes = append(deletes, repository.OSMRef{Type: el.Type, ID: el.ID})
+	}
+
+	changed, stats, err := u.poiRepo.UpsertOSM(ctx, pois)
+	if err != nil {
+		return stats, err
+	}
+
+	removed, err := u.poiRepo.DeleteOSM(ctx, u.region.ID, deletes)
+	if err != nil {
+		return stats, err
+	}
+	stats.Removed = len(removed)
+
+	if u.qdrantRepo != nil {
+		if err := u.qdrantRepo.IndexBatch(ctx, changed); err != nil {
+			log.Printf("Warning: Failed to index diff %d in Qdrant: %v", seq, err)
+		}
+		if err := u.facts.Index(ctx, poiIDs(changed)); err != nil {
+			log.Printf("Warning: Failed to index facts of diff %d in Qdrant: %v", seq, err)
+		}
+		if err := u.qdrantRepo.Delete(ctx, removed); err != nil {
+			log.Printf("Warning: Failed to remove POIs of diff %d from Qdrant: %v", seq, err)
+		}
+	}
+
+	if err := u.state.SaveSequence(ctx, u.stateKey, seq, state.Timestamp); err != nil {
+		return stats, err
+	}
+
+	log.Printf("Applied diff %d (%s): %d new, %d updated, %d removed, %d without location skipped",
+		seq, state.Timestamp.Format("2006-01-02 15:04"), stats.Inserted, stats.Updated, stats.Removed, skipped)
+
+	return stats, nil
+}
@@ -9,12 +9,9 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
-
 	grpcapi "github.com/dremotha/mapbot/internal/api/grpc"
 	"github.com/dremotha/mapbot/internal/api/rest"
 	"github.com/dremotha/mapbot/internal/config"
-	"github.com/dremotha/mapbot/internal/domain"
 	"github.com/dremotha/mapbot/internal/infrastructure/metrics"
 	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
 	"github.com/dremotha/mapbot/internal/infrastructure/postgres"
@@ -22,6 +19,7 @@ import (
 	infraredis "github.com/dremotha/mapbot/internal/infrastructure/redis"
 	"github.com/dremotha/mapbot/internal/repository"
 	"github.com/dremotha/mapbot/internal/service"
+	"github.com/dremotha/mapbot/internal/transit"
 	"github.com/dremotha/mapbot/pkg/embedding"
 )
 
@@ -33,6 +31,7 @@ func main() {
 
 	// Initialize Prometheus metrics
 	rest.InitMetrics()
+	metrics.RegisterCacheMetrics()
 	log.Println("Prometheus metrics initialized")
 
 	// PostgreSQL
@@ -83,35 +82,68 @@ func main() {
 	// OSRM
 	osrmClient := osrm.NewClient(cfg.OSRM)
 
+	location, err := time.LoadLocation(cfg.Server.Timezone)
+	if err != nil {
+		log.Printf("Warning: unknown timezone %s, using UTC+3: %v", cfg.Server.Timezone, err)
+		location = time.FixedZone("MSK", 3*60*60)
+	}
+
+	// Public transport timetable (optional)
+	var transitPlanner *transit.Planner
+	if cfg.Transit.GTFSPath != "" {
+		feed, err := transit.Load(cfg.Transit.GTFSPath)
+		if err != nil {
+			log.Printf("Warning: GTFS feed not loaded, transit mode disabled: %v", err)
+		} else {
+			transitPlanner = transit.NewPlanner(feed, location)
+			log.Printf("Loaded GTFS feed: %d stops, %d trips, %d connections",
+				len(feed.Stops), len(feed.Trips), len(feed.Connections))
+		}
+	}
+
 	// Repositories
 	poiRepo := repository.NewPOIRepository(pool)
+	routeRepo := repository.NewRouteRepository(pool)
+	presetRepo := repository.NewPresetRouteRepository(pool)
+	factRepo := repository.NewHistoricalFactRepository(pool)
 
-	// Search service - use semantic if available, fallback to basic
-	var searchService interface {
-		Search(ctx context.Context, query string, filters domain.SearchFilters) (*domain.SearchResult, error)
-		GetByID(ctx context.Context, id uuid.UUID) (*domain.POI, error)
-		GetCategories(ctx context.Context) ([]domain.Category, error)
+	cacheManager, err := service.NewCacheManager(redisClient, cfg.Cache)
+	if err != nil {
+		log.Fatalf("Invalid cache config: %v", err)
+	}
+	poiRepo.OnChange(cacheManager.InvalidatePOIs)
+	if redisClient != nil {
+		go cacheManager.Listen(ctx)
+	} else {
+		log.Println("Redis unavailable, caching in process memory only")
 	}
 
+	// Search service - use semantic if available, fallback to basic
+	var searchService service.POISearcher
+	var qdrantRepo *repository.QdrantPOIRepository
+
 	if qdrantClient != nil && embeddingClient != nil {
-		qdrantRepo := repository.NewQdrantPOIRepository(qdrantClient, embeddingClient)
+		embedder := service.NewCachedEmbeddingClient(embeddingClient, cacheManager, cfg.Embedding.CacheSize)
+		qdrantRepo = repository.NewQdrantPOIRepository(qdrantClient, embedder)
 		searchService = service.NewSemanticSearchService(poiRepo, qdrantRepo)
 		log.Println("Using semantic search")
 	} else {
 		searchService = service.NewSearchService(poiRepo)
 		log.Println("Using basic text search")
 	}
+	searchService = service.NewCachedSearchService(searchService, cacheManager)
 
 	// Services
-	routingService := service.NewRoutingService(osrmClient, poiRepo)
+	routingService := service.NewCachedRoutingService(
+		service.NewRoutingService(osrmClient, poiRepo, transitPlanner, location),
+		cacheManager,
+	)
+	savedRouteService := service.NewSavedRouteService(routeRepo)
+	factService := service.NewHistoricalFactService(factRepo, poiRepo, qdrantRepo)
 	intentClassifier := service.NewIntentClassifier()
 	responseGenerator := service.NewResponseGenerator()
 
-	var cacheManager *service.CacheManager
-	if redisClient != nil {
-		cacheManager = service.NewCacheManager(redisClient)
-	}
-	_ = cacheManager
+	presetService := service.NewPresetRouteService(presetRepo, poiRepo, osrmClient, cacheManager)
 
 	// Start metrics collector
 	metricsCollector := metrics.NewCollector(pool, redisClient, 15*time.Second)
@@ -119,9 +151,11 @@ func main() {
 	log.Println("Metrics collector started")
 
 	// HTTP handlers
-	handler := rest.NewHandler(searchService, intentClassifier, responseGenerator)
+	handler := rest.NewHandler(searchService, factService, intentClassifier, responseGenerator)
 	routeHandler := rest.NewRouteHandler(routingService, searchService)
-	router := rest.NewRouter(handler, routeHandler)
+	savedRouteHandler := rest.NewSavedRouteHandler(savedRouteService)
+	presetHandler := rest.NewPresetHandler(presetService)
+	router := rest.NewRouter(handler, routeHandler, savedRouteHandler, presetHandler)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.HTTPPort,
@@ -131,7 +165,7 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	grpcServer := grpcapi.NewServer(searchService, routingService, intentClassifier)
+	grpcServer := grpcapi.NewServer(searchService, factService, routingService, savedRouteService, intentClassifier)
 
 	go func() {
 		log.Printf("gRPC server listening on :%s", cfg.Server.GRPCPort)
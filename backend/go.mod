@@ -12,7 +12,10 @@ require (
 	github.com/prometheus/client_golang v1.23.2
 	github.com/qdrant/go-client v1.12.0
 	github.com/redis/go-redis/v9 v9.7.0
+	go.yaml.in/yaml/v2 v2.4.2
+	golang.org/x/sync v0.16.0
 	google.golang.org/grpc v1.68.1
+	google.golang.org/protobuf v1.36.8
 )
 
 require (
@@ -27,12 +30,9 @@ require (
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.66.1 // indirect
 	github.com/prometheus/procfs v0.16.1 // indirect
-	go.yaml.in/yaml/v2 v2.4.2 // indirect
 	golang.org/x/crypto v0.41.0 // indirect
 	golang.org/x/net v0.43.0 // indirect
-	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241118233622-e639e219e697 // indirect
-	google.golang.org/protobuf v1.36.8 // indirect
 )
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -15,6 +16,8 @@ type SearchRequest struct {
 	RadiusKm   float64
 	Limit      int32
 	Offset     int32
+	Region     string
+	Period     string
 }
 
 type SearchResponse struct {
@@ -30,6 +33,14 @@ type GetPOIRequest struct {
 
 type GetCategoriesRequest struct{}
 
+type GetPOIFactsRequest struct {
+	PoiId string
+}
+
+type GetPOIFactsResponse struct {
+	Facts []*HistoricalFact
+}
+
 type GetCategoriesResponse struct {
 	Categories []*Category
 }
@@ -51,6 +62,30 @@ type POI struct {
 	Source           string
 	OsmId            *int64
 	PopularityScore  float64
+	OpeningHours     string
+	Region           string
+	Aliases          []string
+	Wikidata         string
+	Wikipedia        string
+	Heritage         string
+	Image            string
+
+	// YearBuiltPrecision and YearDestroyedPrecision are domain.DatePrecision
+	// values.
+	YearBuiltPrecision     string
+	YearDestroyedPrecision string
+}
+
+type HistoricalFact struct {
+	Id        string
+	PoiId     string
+	FactType  string
+	Title     string
+	Content   string
+	YearFrom  *int32
+	YearTo    *int32
+	SourceUrl string
+	Source    string
 }
 
 type Coordinate struct {
@@ -69,16 +104,19 @@ type Category struct {
 }
 
 type BuildRouteRequest struct {
-	Start     *Coordinate
-	End       *Coordinate
-	Waypoints []*Coordinate
-	Mode      string
+	Start         *Coordinate
+	End           *Coordinate
+	Waypoints     []*Coordinate
+	Mode          string
+	Alternatives  int32
+	DepartureTime int64
 }
 
 type BuildRouteResponse struct {
-	Route   *Route
-	Message string
-	Pois    []*POI
+	Route        *Route
+	Message      string
+	Pois         []*POI
+	Alternatives []*Route
 }
 
 type Route struct {
@@ -87,13 +125,36 @@ type Route struct {
 	Geometry    string
 	Waypoints   []*Waypoint
 	Mode        string
+	Sequence    []int32
+	NearbyPois  []*POI
+	Summary     string
+	Legs        []*RouteLeg
+	LegsMin     []float64
+}
+
+type RouteLeg struct {
+	Kind        string
+	Line        string
+	From        string
+	To          string
+	Departure   int64
+	Arrival     int64
+	DistanceKm  float64
+	DurationMin float64
+	Geometry    string
+	Start       *Coordinate
+	End         *Coordinate
 }
 
 type Waypoint struct {
-	Poi      *POI
-	Location *Coordinate
-	Name     string
-	Order    int32
+	Poi       *POI
+	Location  *Coordinate
+	Name      string
+	Order     int32
+	Arrival   int64
+	Departure int64
+	Closed    bool
+	HoursNote string
 }
 
 type HealthCheckRequest struct{}
@@ -106,10 +167,19 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 	filters := domain.SearchFilters{
 		Categories: req.Categories,
 		RadiusKm:   req.RadiusKm,
+		Region:     req.Region,
 		Limit:      int(req.Limit),
 		Offset:     int(req.Offset),
 	}
 
+	if req.Period != "" {
+		period, err := domain.ParsePeriod(req.Period)
+		if err != nil {
+			return nil, err
+		}
+		filters.Period = period.String()
+	}
+
 	if req.Center != nil {
 		filters.Center = &domain.Coordinate{
 			Lat: req.Center.Lat,
@@ -149,6 +219,25 @@ func (s *Server) GetPOI(ctx context.Context, req *GetPOIRequest) (*POI, error) {
 	return domainPOIToGRPC(poi), nil
 }
 
+func (s *Server) GetPOIFacts(ctx context.Context, req *GetPOIFactsRequest) (*GetPOIFactsResponse, error) {
+	id, err := uuid.Parse(req.PoiId)
+	if err != nil {
+		return nil, err
+	}
+
+	facts, err := s.factService.ListByPOI(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcFacts := make([]*HistoricalFact, len(facts))
+	for i, f := range facts {
+		grpcFacts[i] = domainFactToGRPC(&f)
+	}
+
+	return &GetPOIFactsResponse{Facts: grpcFacts}, nil
+}
+
 func (s *Server) GetCategories(ctx context.Context, req *GetCategoriesRequest) (*GetCategoriesResponse, error) {
 	categories, err := s.searchService.GetCategories(ctx)
 	if err != nil {
@@ -170,8 +259,9 @@ func (s *Server) BuildRoute(ctx context.Context, req *BuildRouteRequest) (*Build
 	}
 
 	routeReq := domain.RouteRequest{
-		Waypoints: waypoints,
-		Mode:      domain.TransportMode(req.Mode),
+		Waypoints:    waypoints,
+		Mode:         domain.TransportMode(req.Mode),
+		Alternatives: int(req.Alternatives),
 	}
 
 	if req.Start != nil {
@@ -180,6 +270,10 @@ func (s *Server) BuildRoute(ctx context.Context, req *BuildRouteRequest) (*Build
 	if req.End != nil {
 		routeReq.End = &domain.Coordinate{Lat: req.End.Lat, Lng: req.End.Lng}
 	}
+	if req.DepartureTime > 0 {
+		departure := time.Unix(req.DepartureTime, 0)
+		routeReq.DepartureTime = &departure
+	}
 
 	result, err := s.routingService.BuildRoute(ctx, routeReq)
 	if err != nil {
@@ -208,6 +302,15 @@ func domainPOIToGRPC(p *domain.POI) *POI {
 		HistoricalPeriod: p.HistoricalPeriod,
 		Source:           p.Source,
 		PopularityScore:  p.PopularityScore,
+		Region:           p.Region,
+		Aliases:          p.Aliases,
+		Wikidata:         p.Wikidata,
+		Wikipedia:        p.Wikipedia,
+		Heritage:         p.Heritage,
+		Image:            p.Image,
+
+		YearBuiltPrecision:     string(p.YearBuiltPrecision),
+		YearDestroyedPrecision: string(p.YearDestroyedPrecision),
 	}
 
 	if p.YearBuilt != nil {
@@ -221,6 +324,9 @@ func domainPOIToGRPC(p *domain.POI) *POI {
 	if p.OsmID != nil {
 		poi.OsmId = p.OsmID
 	}
+	if p.OpeningHours != nil {
+		poi.OpeningHours = p.OpeningHours.Raw
+	}
 
 	return poi
 }
@@ -245,31 +351,36 @@ func domainCategoryToGRPC(c *domain.Category) *Category {
 	return cat
 }
 
+func domainFactToGRPC(f *domain.HistoricalFact) *HistoricalFact {
+	fact := &HistoricalFact{
+		Id:        f.ID.String(),
+		PoiId:     f.POIID.String(),
+		FactType:  f.FactType,
+		Title:     f.Title,
+		Content:   f.Content,
+		SourceUrl: f.SourceURL,
+		Source:    f.Source,
+	}
+
+	if f.YearFrom != nil {
+		from := int32(*f.YearFrom)
+		fact.YearFrom = &from
+	}
+	if f.YearTo != nil {
+		to := int32(*f.YearTo)
+		fact.YearTo = &to
+	}
+
+	return fact
+}
+
 func domainRouteResponseToGRPC(r *domain.RouteResponse) *BuildRouteResponse {
 	resp := &BuildRouteResponse{
 		Message: r.Message,
 	}
 
 	if r.Route != nil {
-		resp.Route = &Route{
-			DistanceKm:  r.Route.DistanceKm,
-			DurationMin: r.Route.DurationMin,
-			Geometry:    r.Route.Geometry,
-			Mode:        string(r.Route.Mode),
-		}
-
-		resp.Route.Waypoints = make([]*Waypoint, len(r.Route.Waypoints))
-		for i, wp := range r.Route.Waypoints {
-			grpcWp := &Waypoint{
-				Location: &Coordinate{Lat: wp.Location.Lat, Lng: wp.Location.Lng},
-				Name:     wp.Name,
-				Order:    int32(wp.Order),
-			}
-			if wp.POI != nil {
-				grpcWp.Poi = domainPOIToGRPC(wp.POI)
-			}
-			resp.Route.Waypoints[i] = grpcWp
-		}
+		resp.Route = domainRouteToGRPC(r.Route)
 	}
 
 	if len(r.POIs) > 0 {
@@ -279,9 +390,74 @@ func domainRouteResponseToGRPC(r *domain.RouteResponse) *BuildRouteResponse {
 		}
 	}
 
+	if len(r.Alternatives) > 0 {
+		resp.Alternatives = make([]*Route, len(r.Alternatives))
+		for i := range r.Alternatives {
+			resp.Alternatives[i] = domainRouteToGRPC(&r.Alternatives[i])
+		}
+	}
+
 	return resp
 }
 
+func domainRouteToGRPC(r *domain.Route) *Route {
+	route := &Route{
+		DistanceKm:  r.DistanceKm,
+		DurationMin: r.DurationMin,
+		Geometry:    r.Geometry,
+		Mode:        string(r.Mode),
+		Summary:     r.Summary,
+		LegsMin:     r.LegsMin,
+	}
 
+	route.Waypoints = make([]*Waypoint, len(r.Waypoints))
+	for i, wp := range r.Waypoints {
+		grpcWp := &Waypoint{
+			Location:  &Coordinate{Lat: wp.Location.Lat, Lng: wp.Location.Lng},
+			Name:      wp.Name,
+			Order:     int32(wp.Order),
+			Closed:    wp.Closed,
+			HoursNote: wp.HoursNote,
+		}
+		if wp.POI != nil {
+			grpcWp.Poi = domainPOIToGRPC(wp.POI)
+		}
+		if wp.Arrival != nil {
+			grpcWp.Arrival = wp.Arrival.Unix()
+		}
+		if wp.Departure != nil {
+			grpcWp.Departure = wp.Departure.Unix()
+		}
+		route.Waypoints[i] = grpcWp
+	}
 
+	route.Sequence = make([]int32, len(r.Sequence))
+	for i, idx := range r.Sequence {
+		route.Sequence[i] = int32(idx)
+	}
 
+	if len(r.NearbyPOIs) > 0 {
+		route.NearbyPois = make([]*POI, len(r.NearbyPOIs))
+		for i, p := range r.NearbyPOIs {
+			route.NearbyPois[i] = domainPOIToGRPC(&p)
+		}
+	}
+
+	for _, leg := range r.Legs {
+		route.Legs = append(route.Legs, &RouteLeg{
+			Kind:        leg.Kind,
+			Line:        leg.Line,
+			From:        leg.From,
+			To:          leg.To,
+			Departure:   leg.Departure.Unix(),
+			Arrival:     leg.Arrival.Unix(),
+			DistanceKm:  leg.DistanceKm,
+			DurationMin: leg.DurationMin,
+			Geometry:    leg.Geometry,
+			Start:       &Coordinate{Lat: leg.Start.Lat, Lng: leg.Start.Lng},
+			End:         &Coordinate{Lat: leg.End.Lat, Lng: leg.End.Lng},
+		})
+	}
+
+	return route
+}
@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+type PlanItineraryRequest struct {
+	PoiIds     []string
+	Query      string
+	Categories []string
+	Limit      int32
+	Days       int32
+	DailyHours float64
+	Base       *Coordinate
+	Mode       string
+	StartTime  int64
+	SkipClosed bool
+}
+
+type ItineraryDay struct {
+	Day      int32
+	Date     string
+	Route    *Route
+	Pois     []*POI
+	VisitMin float64
+	TotalMin float64
+}
+
+type PlanItineraryResponse struct {
+	Days        []*ItineraryDay
+	Unscheduled []*POI
+	Mode        string
+	Message     string
+}
+
+func (s *Server) PlanItinerary(ctx context.Context, req *PlanItineraryRequest) (*PlanItineraryResponse, error) {
+	itineraryReq := domain.ItineraryRequest{
+		POIIDs:     req.PoiIds,
+		Query:      req.Query,
+		Categories: req.Categories,
+		Limit:      int(req.Limit),
+		Days:       int(req.Days),
+		DailyHours: req.DailyHours,
+		Mode:       domain.TransportMode(req.Mode),
+		SkipClosed: req.SkipClosed,
+	}
+
+	if req.StartTime > 0 {
+		startTime := time.Unix(req.StartTime, 0)
+		itineraryReq.StartTime = &startTime
+	}
+
+	if req.Base != nil {
+		itineraryReq.Base = &domain.Coordinate{Lat: req.Base.Lat, Lng: req.Base.Lng}
+	}
+
+	var pois []domain.POI
+	if len(req.PoiIds) == 0 {
+		if req.Query == "" {
+			return nil, fmt.Errorf("poi_ids or query required")
+		}
+
+		limit := itineraryReq.Limit
+		if limit == 0 {
+			limit = 5 * itineraryReq.Days
+		}
+
+		filters := domain.SearchFilters{
+			Categories: req.Categories,
+			Limit:      limit,
+		}
+		if itineraryReq.Base != nil {
+			filters.Center = itineraryReq.Base
+			filters.RadiusKm = 150
+		}
+
+		result, err := s.searchService.Search(ctx, req.Query, filters)
+		if err != nil {
+			return nil, err
+		}
+		pois = result.POIs
+	}
+
+	itinerary, err := s.routingService.PlanItinerary(ctx, itineraryReq, pois)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PlanItineraryResponse{
+		Mode:    string(itinerary.Mode),
+		Message: itinerary.Message,
+	}
+
+	for _, day := range itinerary.Days {
+		grpcDay := &ItineraryDay{
+			Day:      int32(day.Day),
+			Date:     day.Date,
+			Route:    domainRouteToGRPC(day.Route),
+			VisitMin: day.VisitMin,
+			TotalMin: day.TotalMin,
+		}
+		for i := range day.POIs {
+			grpcDay.Pois = append(grpcDay.Pois, domainPOIToGRPC(&day.POIs[i]))
+		}
+		resp.Days = append(resp.Days, grpcDay)
+	}
+
+	for i := range itinerary.Unscheduled {
+		resp.Unscheduled = append(resp.Unscheduled, domainPOIToGRPC(&itinerary.Unscheduled[i]))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+type SaveRouteRequest struct {
+	Title    string
+	Route    *Route
+	Pois     []*POI
+	Metadata map[string]string
+}
+
+type SavedRoute struct {
+	Id        string
+	ShareCode string
+	Title     string
+	Route     *Route
+	Pois      []*POI
+	Metadata  map[string]string
+	CreatedAt int64
+
+	// DeleteToken is set only in the response of SaveRoute.
+	DeleteToken string
+}
+
+type GetSavedRouteRequest struct {
+	ShareCode string
+}
+
+type ListSavedRoutesRequest struct {
+	ShareCodes []string
+}
+
+type ListSavedRoutesResponse struct {
+	Routes []*SavedRoute
+}
+
+type DeleteSavedRouteRequest struct {
+	Id          string
+	DeleteToken string
+}
+
+type DeleteSavedRouteResponse struct{}
+
+func (s *Server) SaveRoute(ctx context.Context, req *SaveRouteRequest) (*SavedRoute, error) {
+	var route *domain.Route
+	if req.Route != nil {
+		route = grpcRouteToDomain(req.Route)
+	}
+
+	pois := make([]domain.POI, 0, len(req.Pois))
+	for _, p := range req.Pois {
+		pois = append(pois, *grpcPOIToDomain(p))
+	}
+
+	saved, err := s.savedRouteService.Save(ctx, req.Title, route, pois, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return domainSavedRouteToGRPC(saved), nil
+}
+
+func (s *Server) GetSavedRoute(ctx context.Context, req *GetSavedRouteRequest) (*SavedRoute, error) {
+	saved, err := s.savedRouteService.GetByShareCode(ctx, req.ShareCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return domainSavedRouteToGRPC(saved), nil
+}
+
+func (s *Server) ListSavedRoutes(ctx context.Context, req *ListSavedRoutesRequest) (*ListSavedRoutesResponse, error) {
+	routes, err := s.savedRouteService.ListByShareCodes(ctx, req.ShareCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListSavedRoutesResponse{Routes: make([]*SavedRoute, len(routes))}
+	for i := range routes {
+		resp.Routes[i] = domainSavedRouteToGRPC(&routes[i])
+	}
+
+	return resp, nil
+}
+
+func (s *Server) DeleteSavedRoute(ctx context.Context, req *DeleteSavedRouteRequest) (*DeleteSavedRouteResponse, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.savedRouteService.Delete(ctx, id, req.DeleteToken); err != nil {
+		return nil, err
+	}
+
+	return &DeleteSavedRouteResponse{}, nil
+}
+
+func domainSavedRouteToGRPC(r *domain.SavedRoute) *SavedRoute {
+	saved := &SavedRoute{
+		Id:        r.ID.String(),
+		ShareCode: r.ShareCode,
+		Title:     r.Title,
+		Route:     domainRouteToGRPC(r.Route()),
+		Metadata:  r.Metadata,
+		CreatedAt: r.CreatedAt.Unix(),
+
+		DeleteToken: r.DeleteToken,
+	}
+
+	saved.Pois = make([]*POI, len(r.POIs))
+	for i, p := range r.POIs {
+		saved.Pois[i] = domainPOIToGRPC(&p)
+	}
+
+	return saved
+}
+
+func grpcRouteToDomain(r *Route) *domain.Route {
+	route := &domain.Route{
+		DistanceKm:  r.DistanceKm,
+		DurationMin: r.DurationMin,
+		Geometry:    r.Geometry,
+		Mode:        domain.TransportMode(r.Mode),
+		Summary:     r.Summary,
+	}
+
+	route.Waypoints = make([]domain.Waypoint, len(r.Waypoints))
+	for i, wp := range r.Waypoints {
+		route.Waypoints[i] = domain.Waypoint{
+			Name:  wp.Name,
+			Order: int(wp.Order),
+		}
+		if wp.Location != nil {
+			route.Waypoints[i].Location = domain.Coordinate{Lat: wp.Location.Lat, Lng: wp.Location.Lng}
+		}
+		if wp.Poi != nil {
+			route.Waypoints[i].POI = grpcPOIToDomain(wp.Poi)
+		}
+	}
+
+	route.Sequence = make([]int, len(r.Sequence))
+	for i, idx := range r.Sequence {
+		route.Sequence[i] = int(idx)
+	}
+
+	return route
+}
+
+func grpcPOIToDomain(p *POI) *domain.POI {
+	poi := &domain.POI{
+		Name:             p.Name,
+		Description:      p.Description,
+		ShortDescription: p.ShortDescription,
+		Lat:              p.Lat,
+		Lng:              p.Lng,
+		Address:          p.Address,
+		Category:         p.Category,
+		Subcategory:      p.Subcategory,
+		Tags:             p.Tags,
+		HistoricalPeriod: p.HistoricalPeriod,
+		Source:           p.Source,
+		OsmID:            p.OsmId,
+		Region:           p.Region,
+		Aliases:          p.Aliases,
+		Wikidata:         p.Wikidata,
+		Wikipedia:        p.Wikipedia,
+		Heritage:         p.Heritage,
+		Image:            p.Image,
+		PopularityScore:  p.PopularityScore,
+
+		YearBuiltPrecision:     domain.DatePrecision(p.YearBuiltPrecision),
+		YearDestroyedPrecision: domain.DatePrecision(p.YearDestroyedPrecision),
+	}
+
+	if id, err := uuid.Parse(p.Id); err == nil {
+		poi.ID = id
+	}
+	if p.YearBuilt != nil {
+		yb := int(*p.YearBuilt)
+		poi.YearBuilt = &yb
+	}
+	if p.YearDestroyed != nil {
+		yd := int(*p.YearDestroyed)
+		poi.YearDestroyed = &yd
+	}
+
+	return poi
+}
@@ -19,16 +19,29 @@ type GRPCSearchService interface {
 	GetCategories(ctx context.Context) ([]domain.Category, error)
 }
 
+type GRPCFactService interface {
+	ListByPOI(ctx context.Context, poiID uuid.UUID) ([]domain.HistoricalFact, error)
+}
+
+type GRPCRoutingService interface {
+	BuildRoute(ctx context.Context, req domain.RouteRequest) (*domain.RouteResponse, error)
+	PlanItinerary(ctx context.Context, req domain.ItineraryRequest, pois []domain.POI) (*domain.Itinerary, error)
+}
+
 type Server struct {
 	grpcServer       *grpc.Server
 	searchService    GRPCSearchService
-	routingService   *service.RoutingService
-	intentClassifier *service.IntentClassifier
+	factService      GRPCFactService
+	routingService    GRPCRoutingService
+	savedRouteService *service.SavedRouteService
+	intentClassifier  *service.IntentClassifier
 }
 
 func NewServer(
 	searchService GRPCSearchService,
-	routingService *service.RoutingService,
+	factService GRPCFactService,
+	routingService GRPCRoutingService,
+	savedRouteService *service.SavedRouteService,
 	intentClassifier *service.IntentClassifier,
 ) *Server {
 	grpcServer := grpc.NewServer(
@@ -37,10 +50,12 @@ func NewServer(
 	)
 
 	s := &Server{
-		grpcServer:       grpcServer,
-		searchService:    searchService,
-		routingService:   routingService,
-		intentClassifier: intentClassifier,
+		grpcServer:        grpcServer,
+		searchService:     searchService,
+		factService:       factService,
+		routingService:    routingService,
+		savedRouteService: savedRouteService,
+		intentClassifier:  intentClassifier,
 	}
 
 	RegisterSearchServiceServer(grpcServer, s)
@@ -68,11 +83,17 @@ func (s *Server) Stop() {
 type SearchServiceServer interface {
 	Search(context.Context, *SearchRequest) (*SearchResponse, error)
 	GetPOI(context.Context, *GetPOIRequest) (*POI, error)
+	GetPOIFacts(context.Context, *GetPOIFactsRequest) (*GetPOIFactsResponse, error)
 	GetCategories(context.Context, *GetCategoriesRequest) (*GetCategoriesResponse, error)
 }
 
 type RouteServiceServer interface {
 	BuildRoute(context.Context, *BuildRouteRequest) (*BuildRouteResponse, error)
+	SaveRoute(context.Context, *SaveRouteRequest) (*SavedRoute, error)
+	GetSavedRoute(context.Context, *GetSavedRouteRequest) (*SavedRoute, error)
+	ListSavedRoutes(context.Context, *ListSavedRoutesRequest) (*ListSavedRoutesResponse, error)
+	DeleteSavedRoute(context.Context, *DeleteSavedRouteRequest) (*DeleteSavedRouteResponse, error)
+	PlanItinerary(context.Context, *PlanItineraryRequest) (*PlanItineraryResponse, error)
 }
 
 type HealthServiceServer interface {
@@ -97,6 +118,7 @@ var _SearchService_serviceDesc = grpc.ServiceDesc{
 	Methods: []grpc.MethodDesc{
 		{MethodName: "Search", Handler: _SearchService_Search_Handler},
 		{MethodName: "GetPOI", Handler: _SearchService_GetPOI_Handler},
+		{MethodName: "GetPOIFacts", Handler: _SearchService_GetPOIFacts_Handler},
 		{MethodName: "GetCategories", Handler: _SearchService_GetCategories_Handler},
 	},
 	Streams: []grpc.StreamDesc{},
@@ -107,6 +129,11 @@ var _RouteService_serviceDesc = grpc.ServiceDesc{
 	HandlerType: (*RouteServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{MethodName: "BuildRoute", Handler: _RouteService_BuildRoute_Handler},
+		{MethodName: "SaveRoute", Handler: _RouteService_SaveRoute_Handler},
+		{MethodName: "GetSavedRoute", Handler: _RouteService_GetSavedRoute_Handler},
+		{MethodName: "ListSavedRoutes", Handler: _RouteService_ListSavedRoutes_Handler},
+		{MethodName: "DeleteSavedRoute", Handler: _RouteService_DeleteSavedRoute_Handler},
+		{MethodName: "PlanItinerary", Handler: _RouteService_PlanItinerary_Handler},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -136,6 +163,14 @@ func _SearchService_GetPOI_Handler(srv interface{}, ctx context.Context, dec fun
 	return srv.(SearchServiceServer).GetPOI(ctx, in)
 }
 
+func _SearchService_GetPOIFacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPOIFactsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(SearchServiceServer).GetPOIFacts(ctx, in)
+}
+
 func _SearchService_GetCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetCategoriesRequest)
 	if err := dec(in); err != nil {
@@ -152,6 +187,46 @@ func _RouteService_BuildRoute_Handler(srv interface{}, ctx context.Context, dec
 	return srv.(RouteServiceServer).BuildRoute(ctx, in)
 }
 
+func _RouteService_SaveRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveRouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(RouteServiceServer).SaveRoute(ctx, in)
+}
+
+func _RouteService_GetSavedRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSavedRouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(RouteServiceServer).GetSavedRoute(ctx, in)
+}
+
+func _RouteService_ListSavedRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSavedRoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(RouteServiceServer).ListSavedRoutes(ctx, in)
+}
+
+func _RouteService_DeleteSavedRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSavedRouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(RouteServiceServer).DeleteSavedRoute(ctx, in)
+}
+
+func _RouteService_PlanItinerary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlanItineraryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(RouteServiceServer).PlanItinerary(ctx, in)
+}
+
 func _HealthService_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HealthCheckRequest)
 	if err := dec(in); err != nil {
@@ -3,6 +3,7 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -18,19 +19,26 @@ type SearchService interface {
 	GetCategories(ctx context.Context) ([]domain.Category, error)
 }
 
+type FactService interface {
+	ListByPOI(ctx context.Context, poiID uuid.UUID) ([]domain.HistoricalFact, error)
+}
+
 type Handler struct {
 	searchService     SearchService
+	factService       FactService
 	intentClassifier  *service.IntentClassifier
 	responseGenerator *service.ResponseGenerator
 }
 
 func NewHandler(
 	searchService SearchService,
+	factService FactService,
 	intentClassifier *service.IntentClassifier,
 	responseGenerator *service.ResponseGenerator,
 ) *Handler {
 	return &Handler{
 		searchService:     searchService,
+		factService:       factService,
 		intentClassifier:  intentClassifier,
 		responseGenerator: responseGenerator,
 	}
@@ -42,6 +50,8 @@ type SearchRequest struct {
 	Lat        *float64 `json:"lat,omitempty"`
 	Lng        *float64 `json:"lng,omitempty"`
 	RadiusKm   float64  `json:"radius_km,omitempty"`
+	Region     string   `json:"region,omitempty"`
+	Period     string   `json:"period,omitempty"`
 	Limit      int      `json:"limit,omitempty"`
 	Offset     int      `json:"offset,omitempty"`
 }
@@ -56,10 +66,20 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	filters := domain.SearchFilters{
 		Categories: req.Categories,
 		RadiusKm:   req.RadiusKm,
+		Region:     req.Region,
 		Limit:      req.Limit,
 		Offset:     req.Offset,
 	}
 
+	if req.Period != "" {
+		period, err := domain.ParsePeriod(req.Period)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid period")
+			return
+		}
+		filters.Period = period.String()
+	}
+
 	if req.Lat != nil && req.Lng != nil {
 		filters.Center = &domain.Coordinate{
 			Lat: *req.Lat,
@@ -116,7 +136,12 @@ func (h *Handler) Chat(w http.ResponseWriter, r *http.Request) {
 		if err != nil || len(result.POIs) == 0 {
 			response = h.responseGenerator.GenerateErrorResponse(err)
 		} else {
-			response = h.responseGenerator.GenerateInfoResponse(&result.POIs[0])
+			poi := &result.POIs[0]
+			facts, err := h.factService.ListByPOI(r.Context(), poi.ID)
+			if err != nil {
+				log.Printf("Warning: Failed to get facts of %s: %v", poi.ID, err)
+			}
+			response = h.responseGenerator.GenerateInfoResponse(poi, facts)
 		}
 
 	default:
@@ -149,6 +174,29 @@ func (h *Handler) GetPOI(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, poi)
 }
 
+// GetPOIFacts returns the historical facts of a POI in chronological order.
+func (h *Handler) GetPOIFacts(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid POI ID")
+		return
+	}
+
+	if _, err := h.searchService.GetByID(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, "POI not found")
+		return
+	}
+
+	facts, err := h.factService.ListByPOI(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get facts")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, facts)
+}
+
 func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
 	categories, err := h.searchService.GetCategories(r.Context())
 	if err != nil {
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/service"
+)
+
+type PresetHandler struct {
+	presetService *service.PresetRouteService
+}
+
+func NewPresetHandler(presetService *service.PresetRouteService) *PresetHandler {
+	return &PresetHandler{presetService: presetService}
+}
+
+func (h *PresetHandler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filters := domain.PresetRouteFilters{
+		Category:   q.Get("category"),
+		Mode:       domain.TransportMode(q.Get("mode")),
+		Difficulty: q.Get("difficulty"),
+		Tag:        q.Get("tag"),
+	}
+
+	presets, err := h.presetService.List(r.Context(), filters)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list preset routes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presets)
+}
+
+func (h *PresetHandler) GetPreset(w http.ResponseWriter, r *http.Request) {
+	preset, err := h.presetService.Get(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, repository.ErrPresetNotFound) {
+		writeError(w, http.StatusNotFound, "preset route not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get preset route")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preset)
+}
+
+func (h *PresetHandler) GetPresetRoute(w http.ResponseWriter, r *http.Request) {
+	result, err := h.presetService.GetRoute(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, repository.ErrPresetNotFound) {
+		writeError(w, http.StatusNotFound, "preset route not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build route")
+		return
+	}
+
+	writeRoute(w, r, result)
+}
@@ -3,11 +3,16 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/export"
 	"github.com/dremotha/mapbot/internal/service"
 )
 
@@ -17,12 +22,21 @@ type RouteSearchService interface {
 	GetCategories(ctx context.Context) ([]domain.Category, error)
 }
 
+type RoutingService interface {
+	BuildRoute(ctx context.Context, req domain.RouteRequest) (*domain.RouteResponse, error)
+	BuildRouteFromPOIs(ctx context.Context, poiIDs []string, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error)
+	BuildRouteFromSearch(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error)
+	DiscoverAlongRoute(ctx context.Context, req domain.CorridorRequest) (*domain.CorridorResponse, error)
+	FindReachable(ctx context.Context, req domain.ReachabilityRequest) (*domain.ReachabilityResponse, error)
+	PlanItinerary(ctx context.Context, req domain.ItineraryRequest, pois []domain.POI) (*domain.Itinerary, error)
+}
+
 type RouteHandler struct {
-	routingService *service.RoutingService
+	routingService RoutingService
 	searchService  RouteSearchService
 }
 
-func NewRouteHandler(routingService *service.RoutingService, searchService RouteSearchService) *RouteHandler {
+func NewRouteHandler(routingService RoutingService, searchService RouteSearchService) *RouteHandler {
 	return &RouteHandler{
 		routingService: routingService,
 		searchService:  searchService,
@@ -30,16 +44,20 @@ func NewRouteHandler(routingService *service.RoutingService, searchService Route
 }
 
 type BuildRouteRequest struct {
-	Start     *domain.Coordinate   `json:"start,omitempty"`
-	End       *domain.Coordinate   `json:"end,omitempty"`
-	Waypoints []domain.Coordinate  `json:"waypoints,omitempty"`
-	Mode      domain.TransportMode `json:"mode,omitempty"`
+	Start        *domain.Coordinate   `json:"start,omitempty"`
+	End          *domain.Coordinate   `json:"end,omitempty"`
+	Waypoints    []domain.Coordinate  `json:"waypoints,omitempty"`
+	Mode         domain.TransportMode `json:"mode,omitempty"`
+	Alternatives int                  `json:"alternatives,omitempty"`
+	// DepartureTime is used by the transit mode (RFC 3339).
+	DepartureTime *time.Time `json:"departure_time,omitempty"`
 }
 
 type BuildRouteFromPOIsRequest struct {
 	POIIDs []string             `json:"poi_ids"`
 	Start  *domain.Coordinate   `json:"start,omitempty"`
 	Mode   domain.TransportMode `json:"mode,omitempty"`
+	domain.TourOptions
 }
 
 type BuildRouteFromQueryRequest struct {
@@ -48,6 +66,7 @@ type BuildRouteFromQueryRequest struct {
 	Mode       domain.TransportMode `json:"mode,omitempty"`
 	Limit      int                  `json:"limit,omitempty"`
 	Categories []string             `json:"categories,omitempty"`
+	domain.TourOptions
 }
 
 func (h *RouteHandler) BuildRoute(w http.ResponseWriter, r *http.Request) {
@@ -58,19 +77,21 @@ func (h *RouteHandler) BuildRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	routeReq := domain.RouteRequest{
-		Start:     req.Start,
-		End:       req.End,
-		Waypoints: req.Waypoints,
-		Mode:      req.Mode,
+		Start:         req.Start,
+		End:           req.End,
+		Waypoints:     req.Waypoints,
+		Mode:          req.Mode,
+		Alternatives:  req.Alternatives,
+		DepartureTime: req.DepartureTime,
 	}
 
 	result, err := h.routingService.BuildRoute(r.Context(), routeReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to build route")
+		writeServiceError(w, err, "failed to build route")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeRoute(w, r, result)
 }
 
 func (h *RouteHandler) BuildRouteFromPOIs(w http.ResponseWriter, r *http.Request) {
@@ -85,13 +106,13 @@ func (h *RouteHandler) BuildRouteFromPOIs(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	result, err := h.routingService.BuildRouteFromPOIs(r.Context(), req.POIIDs, req.Start, req.Mode)
+	result, err := h.routingService.BuildRouteFromPOIs(r.Context(), req.POIIDs, req.Start, req.Mode, req.TourOptions)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to build route")
+		writeServiceError(w, err, "failed to build route")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeRoute(w, r, result)
 }
 
 func (h *RouteHandler) BuildRouteFromQuery(w http.ResponseWriter, r *http.Request) {
@@ -132,15 +153,246 @@ func (h *RouteHandler) BuildRouteFromQuery(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	result, err := h.routingService.BuildRouteFromSearch(r.Context(), searchResult.POIs, req.Start, req.Mode)
+	result, err := h.routingService.BuildRouteFromSearch(r.Context(), searchResult.POIs, req.Start, req.Mode, req.TourOptions)
+	if err != nil {
+		writeServiceError(w, err, "failed to build route")
+		return
+	}
+
+	writeRoute(w, r, result)
+}
+
+type RouteCorridorRequest struct {
+	Geometry   string               `json:"geometry,omitempty"`
+	Route      *BuildRouteRequest   `json:"route,omitempty"`
+	Mode       domain.TransportMode `json:"mode,omitempty"`
+	BufferM    float64              `json:"buffer_m,omitempty"`
+	Categories []string             `json:"categories,omitempty"`
+	Limit      int                  `json:"limit,omitempty"`
+}
+
+func (h *RouteHandler) DiscoverAlongRoute(w http.ResponseWriter, r *http.Request) {
+	var req RouteCorridorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Geometry == "" && req.Route == nil {
+		writeError(w, http.StatusBadRequest, "geometry or route required")
+		return
+	}
+
+	corridorReq := domain.CorridorRequest{
+		Geometry:   req.Geometry,
+		Mode:       req.Mode,
+		BufferM:    req.BufferM,
+		Categories: req.Categories,
+		Limit:      req.Limit,
+	}
+
+	if req.Route != nil {
+		corridorReq.Route = &domain.RouteRequest{
+			Start:         req.Route.Start,
+			End:           req.Route.End,
+			Waypoints:     req.Route.Waypoints,
+			Mode:          req.Route.Mode,
+			Alternatives:  req.Route.Alternatives,
+			DepartureTime: req.Route.DepartureTime,
+		}
+	}
+
+	result, err := h.routingService.DiscoverAlongRoute(r.Context(), corridorReq)
+	if err != nil {
+		writeServiceError(w, err, "failed to search along route")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+type ReachableRequest struct {
+	Location   *domain.Coordinate   `json:"location"`
+	Mode       domain.TransportMode `json:"mode,omitempty"`
+	MaxMinutes float64              `json:"max_minutes,omitempty"`
+	Categories []string             `json:"categories,omitempty"`
+	Limit      int                  `json:"limit,omitempty"`
+}
+
+func (h *RouteHandler) FindReachable(w http.ResponseWriter, r *http.Request) {
+	var req ReachableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Location == nil {
+		writeError(w, http.StatusBadRequest, "location required")
+		return
+	}
+
+	result, err := h.routingService.FindReachable(r.Context(), domain.ReachabilityRequest{
+		Location:   *req.Location,
+		Mode:       req.Mode,
+		MaxMinutes: req.MaxMinutes,
+		Categories: req.Categories,
+		Limit:      req.Limit,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to find reachable places")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+type ExportRouteRequest struct {
+	Title string        `json:"title,omitempty"`
+	Route *domain.Route `json:"route"`
+	POIs  []domain.POI  `json:"pois,omitempty"`
+}
+
+// ExportRoute converts a previously built route into a GPX, KML or GeoJSON
+// document for navigation apps.
+func (h *RouteHandler) ExportRoute(w http.ResponseWriter, r *http.Request) {
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "format must be one of gpx, kml, geojson")
+		return
+	}
+
+	var req ExportRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Route == nil || req.Route.Geometry == "" {
+		writeError(w, http.StatusBadRequest, "route with geometry required")
+		return
+	}
+
+	writeExport(w, format, req.Title, req.Route, req.POIs)
+}
+
+type PlanItineraryRequest struct {
+	POIIDs     []string             `json:"poi_ids,omitempty"`
+	Query      string               `json:"query,omitempty"`
+	Categories []string             `json:"categories,omitempty"`
+	Limit      int                  `json:"limit,omitempty"`
+	Days       int                  `json:"days"`
+	DailyHours float64              `json:"daily_hours,omitempty"`
+	Base       *domain.Coordinate   `json:"base,omitempty"`
+	Mode       domain.TransportMode `json:"mode,omitempty"`
+	StartTime  *time.Time           `json:"start_time,omitempty"`
+	SkipClosed bool                 `json:"skip_closed,omitempty"`
+}
+
+// PlanItinerary splits POIs given by ID or found by a query into daily
+// routes.
+func (h *RouteHandler) PlanItinerary(w http.ResponseWriter, r *http.Request) {
+	var req PlanItineraryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if len(req.POIIDs) == 0 && req.Query == "" {
+		writeError(w, http.StatusBadRequest, "poi_ids or query required")
+		return
+	}
+
+	if req.Days <= 0 || req.Days > service.MaxItineraryDays {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", service.MaxItineraryDays))
+		return
+	}
+
+	itineraryReq := domain.ItineraryRequest{
+		POIIDs:     req.POIIDs,
+		Query:      req.Query,
+		Categories: req.Categories,
+		Limit:      req.Limit,
+		Days:       req.Days,
+		DailyHours: req.DailyHours,
+		Base:       req.Base,
+		Mode:       req.Mode,
+		StartTime:  req.StartTime,
+		SkipClosed: req.SkipClosed,
+	}
+
+	var pois []domain.POI
+	if len(req.POIIDs) == 0 {
+		limit := req.Limit
+		if limit == 0 {
+			limit = 5 * req.Days
+		}
+
+		filters := domain.SearchFilters{
+			Categories: req.Categories,
+			Limit:      limit,
+		}
+		if req.Base != nil {
+			filters.Center = req.Base
+			filters.RadiusKm = 150
+		}
+
+		searchResult, err := h.searchService.Search(r.Context(), req.Query, filters)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "search failed")
+			return
+		}
+
+		if len(searchResult.POIs) == 0 {
+			writeError(w, http.StatusNotFound, "no POIs found for query")
+			return
+		}
+		pois = searchResult.POIs
+	}
+
+	result, err := h.routingService.PlanItinerary(r.Context(), itineraryReq, pois)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to build route")
+		writeServiceError(w, err, "failed to plan itinerary")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, result)
 }
 
+// writeServiceError responds with 400 and the service's message to a
+// request the service rejected, and with 500 and message otherwise.
+func writeServiceError(w http.ResponseWriter, err error, message string) {
+	var invalid *service.InvalidRequestError
+	if errors.As(err, &invalid) {
+		writeError(w, http.StatusBadRequest, invalid.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, message)
+}
 
+// writeRoute responds with JSON unless an export format is requested via
+// the format query parameter.
+func writeRoute(w http.ResponseWriter, r *http.Request, result *domain.RouteResponse) {
+	formatParam := r.URL.Query().Get("format")
+	if formatParam == "" || formatParam == "json" {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
 
+	format, err := export.ParseFormat(formatParam)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "format must be one of json, gpx, kml, geojson")
+		return
+	}
+
+	writeExport(w, format, r.URL.Query().Get("title"), result.Route, result.POIs)
+}
 
+func writeExport(w http.ResponseWriter, format export.Format, title string, route *domain.Route, pois []domain.POI) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="route.%s"`, format.Extension()))
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.Write(w, format, title, route, pois); err != nil {
+		log.Printf("Route export failed: %v", err)
+	}
+}
@@ -7,13 +7,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func NewRouter(handler *Handler, routeHandler *RouteHandler) *chi.Mux {
+func NewRouter(handler *Handler, routeHandler *RouteHandler, savedRouteHandler *SavedRouteHandler, presetHandler *PresetHandler) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Delete-Token"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -32,11 +32,25 @@ func NewRouter(handler *Handler, routeHandler *RouteHandler) *chi.Mux {
 		r.Post("/search", handler.Search)
 		r.Post("/chat", handler.Chat)
 		r.Get("/poi/{id}", handler.GetPOI)
+		r.Get("/poi/{id}/facts", handler.GetPOIFacts)
 		r.Get("/categories", handler.GetCategories)
 
 		r.Post("/route", routeHandler.BuildRoute)
 		r.Post("/route/pois", routeHandler.BuildRouteFromPOIs)
 		r.Post("/route/query", routeHandler.BuildRouteFromQuery)
+		r.Post("/route/corridor", routeHandler.DiscoverAlongRoute)
+		r.Post("/route/export", routeHandler.ExportRoute)
+		r.Post("/reachable", routeHandler.FindReachable)
+		r.Post("/itinerary", routeHandler.PlanItinerary)
+
+		r.Post("/routes", savedRouteHandler.SaveRoute)
+		r.Get("/routes", savedRouteHandler.ListRoutes)
+		r.Get("/routes/{id}", savedRouteHandler.GetRoute)
+		r.Delete("/routes/{id}", savedRouteHandler.DeleteRoute)
+
+		r.Get("/presets", presetHandler.ListPresets)
+		r.Get("/presets/{id}", presetHandler.GetPreset)
+		r.Get("/presets/{id}/route", presetHandler.GetPresetRoute)
 	})
 
 	return r
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/service"
+)
+
+type SavedRouteHandler struct {
+	savedRouteService *service.SavedRouteService
+}
+
+func NewSavedRouteHandler(savedRouteService *service.SavedRouteService) *SavedRouteHandler {
+	return &SavedRouteHandler{savedRouteService: savedRouteService}
+}
+
+type SaveRouteRequest struct {
+	Title    string            `json:"title,omitempty"`
+	Route    *domain.Route     `json:"route"`
+	POIs     []domain.POI      `json:"pois,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (h *SavedRouteHandler) SaveRoute(w http.ResponseWriter, r *http.Request) {
+	var req SaveRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Route == nil || req.Route.Geometry == "" {
+		writeError(w, http.StatusBadRequest, "route with geometry required")
+		return
+	}
+
+	saved, err := h.savedRouteService.Save(r.Context(), req.Title, req.Route, req.POIs, req.Metadata)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save route")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// ListRoutes returns the saved routes with the share codes listed in the
+// codes query parameter, e.g. the ones the client saved earlier.
+func (h *SavedRouteHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	codes := r.URL.Query().Get("codes")
+	if codes == "" {
+		writeError(w, http.StatusBadRequest, "codes required")
+		return
+	}
+
+	routes, err := h.savedRouteService.ListByShareCodes(r.Context(), strings.Split(codes, ","))
+	if err != nil {
+		writeServiceError(w, err, "failed to list routes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, routes)
+}
+
+// GetRoute looks a saved route up by its ID or share code.
+func (h *SavedRouteHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "id")
+
+	var saved *domain.SavedRoute
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		saved, err = h.savedRouteService.GetByID(r.Context(), id)
+	} else {
+		saved, err = h.savedRouteService.GetByShareCode(r.Context(), ref)
+	}
+
+	if errors.Is(err, repository.ErrRouteNotFound) {
+		writeError(w, http.StatusNotFound, "route not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get route")
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "json" {
+		writeRoute(w, r, &domain.RouteResponse{Route: saved.Route(), POIs: saved.POIs})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// DeleteRoute removes a saved route. The token returned by SaveRoute is
+// passed in the X-Delete-Token header.
+func (h *SavedRouteHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid route ID")
+		return
+	}
+
+	token := r.Header.Get("X-Delete-Token")
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, "delete token required")
+		return
+	}
+
+	err = h.savedRouteService.Delete(r.Context(), id, token)
+	if errors.Is(err, repository.ErrRouteNotFound) {
+		writeError(w, http.StatusNotFound, "route not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete route")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,304 @@
+// Package categories loads the category tree and the OSM tag mapping rules
+// from configs/categories.yaml.
+package categories
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// File is the format of configs/categories.yaml.
+type File struct {
+	// Default is the top-level category of POIs no rule matches.
+	Default string `yaml:"default"`
+	// KeyOrder ranks rules by their OSM keys: among rules of equal
+	// priority, a rule on a key listed earlier wins. Keys not listed rank
+	// after all listed ones.
+	KeyOrder   []string    `yaml:"key_order"`
+	Categories Definitions `yaml:"categories"`
+}
+
+// Definition describes a category. Every entry of OsmTags is a rule: one
+// or more "key=value" conditions joined by "+" that must all hold; "*"
+// matches any value. Priority applies to the rules of this category only.
+type Definition struct {
+	NameRu        string      `yaml:"name_ru"`
+	NameEn        string      `yaml:"name_en"`
+	Icon          string      `yaml:"icon"`
+	Priority      int         `yaml:"priority"`
+	OsmTags       []string    `yaml:"osm_tags"`
+	Subcategories Definitions `yaml:"subcategories"`
+}
+
+// Definitions keeps the order of the YAML mapping: among equally good
+// rules the one declared first wins.
+type Definitions []NamedDefinition
+
+type NamedDefinition struct {
+	ID string
+	Definition
+}
+
+func (d *Definitions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var order yaml.MapSlice
+	if err := unmarshal(&order); err != nil {
+		return err
+	}
+	var defs map[string]Definition
+	if err := unmarshal(&defs); err != nil {
+		return err
+	}
+
+	*d = make(Definitions, 0, len(order))
+	for _, item := range order {
+		id, ok := item.Key.(string)
+		if !ok {
+			return fmt.Errorf("category id %v is not a string", item.Key)
+		}
+		*d = append(*d, NamedDefinition{ID: id, Definition: defs[id]})
+	}
+	return nil
+}
+
+// Mapping assigns OSM elements to categories.
+type Mapping struct {
+	// Categories is the flat tree, every parent before its children.
+	Categories []domain.Category
+	Default    string
+
+	rules []rule
+	known map[string]string // id -> parent id
+}
+
+type rule struct {
+	conditions  []condition
+	category    string
+	subcategory string
+	priority    int
+	rank        int // position of the rule's best key in KeyOrder
+	order       int
+}
+
+type condition struct {
+	key   string
+	value string // empty for "*"
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,49}$`)
+
+// Load reads and validates the category file.
+func Load(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read categories: %w", err)
+	}
+
+	var file File
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parse categories: %w", err)
+	}
+
+	m := &Mapping{Default: file.Default, known: make(map[string]string)}
+	seenRules := make(map[string]string)
+
+	keyRank := make(map[string]int, len(file.KeyOrder))
+	for i, key := range file.KeyOrder {
+		if _, ok := keyRank[key]; ok {
+			return nil, fmt.Errorf("key_order: duplicate key %s", key)
+		}
+		keyRank[key] = i
+	}
+
+	var add func(def NamedDefinition, parent string) error
+	add = func(def NamedDefinition, parent string) error {
+		if !idPattern.MatchString(def.ID) {
+			return fmt.Errorf("category %q: id must be a lowercase slug", def.ID)
+		}
+		if _, ok := m.known[def.ID]; ok {
+			return fmt.Errorf("category %s: duplicate id", def.ID)
+		}
+		if def.NameRu == "" {
+			return fmt.Errorf("category %s: name_ru required", def.ID)
+		}
+		m.known[def.ID] = parent
+
+		category, subcategory := def.ID, ""
+		if parent != "" {
+			category, subcategory = parent, def.ID
+		}
+
+		for _, tags := range def.OsmTags {
+			r, key, err := parseRule(tags)
+			if err != nil {
+				return fmt.Errorf("category %s: %w", def.ID, err)
+			}
+			if other, ok := seenRules[key]; ok {
+				return fmt.Errorf("category %s: rule %q is already used by %s", def.ID, tags, other)
+			}
+			seenRules[key] = def.ID
+
+			r.category, r.subcategory = category, subcategory
+			r.priority = def.Priority
+			r.rank = len(file.KeyOrder)
+			for _, c := range r.conditions {
+				if rank, ok := keyRank[c.key]; ok && rank < r.rank {
+					r.rank = rank
+				}
+			}
+			r.order = len(m.rules)
+			m.rules = append(m.rules, r)
+		}
+
+		m.Categories = append(m.Categories, domain.Category{
+			ID:       def.ID,
+			NameRu:   def.NameRu,
+			NameEn:   def.NameEn,
+			ParentID: parent,
+			Icon:     def.Icon,
+			OsmTags:  append([]string{}, def.OsmTags...),
+		})
+		return nil
+	}
+
+	for _, def := range file.Categories {
+		if err := add(def, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, def := range file.Categories {
+		for _, sub := range def.Subcategories {
+			if len(sub.Subcategories) > 0 {
+				return nil, fmt.Errorf("category %s: subcategories cannot be nested", sub.ID)
+			}
+			if err := add(sub, def.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(m.Categories) == 0 {
+		return nil, fmt.Errorf("no categories in %s", path)
+	}
+	if parent, ok := m.known[m.Default]; !ok || parent != "" {
+		return nil, fmt.Errorf("default %q must be a top-level category", m.Default)
+	}
+
+	return m, nil
+}
+
+// parseRule parses "k1=v1+k2=v2" and returns the rule with a key that is
+// the same for equal rules written in a different order.
+func parseRule(s string) (rule, string, error) {
+	var r rule
+	parts := strings.Split(s, "+")
+	norm := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return rule{}, "", fmt.Errorf("invalid osm tag %q, want key=value", s)
+		}
+		norm = append(norm, key+"="+value)
+		if value == "*" {
+			value = ""
+		}
+		r.conditions = append(r.conditions, condition{key: key, value: value})
+	}
+
+	sort.Strings(norm)
+	return r, strings.Join(norm, "+"), nil
+}
+
+func (r rule) matches(tags map[string]string) bool {
+	for _, c := range r.conditions {
+		v, ok := tags[c.key]
+		if !ok || (c.value != "" && v != c.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// exact counts the conditions with a fixed value.
+func (r rule) exact() int {
+	n := 0
+	for _, c := range r.conditions {
+		if c.value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// better orders matching rules: higher priority, then a key earlier in
+// KeyOrder, then more conditions, then fewer wildcards, then earlier in
+// the file.
+func (r rule) better(o rule) bool {
+	if r.priority != o.priority {
+		return r.priority > o.priority
+	}
+	if r.rank != o.rank {
+		return r.rank < o.rank
+	}
+	if len(r.conditions) != len(o.conditions) {
+		return len(r.conditions) > len(o.conditions)
+	}
+	if r.exact() != o.exact() {
+		return r.exact() > o.exact()
+	}
+	return r.order < o.order
+}
+
+// Match returns the category and subcategory (possibly empty) for the
+// tags of an OSM element.
+func (m *Mapping) Match(tags map[string]string) (string, string) {
+	var best *rule
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.matches(tags) && (best == nil || r.better(*best)) {
+			best = r
+		}
+	}
+
+	if best == nil {
+		return m.Default, ""
+	}
+	return best.category, best.subcategory
+}
+
+// Known reports whether the category and subcategory exist and the
+// subcategory belongs to the category.
+func (m *Mapping) Known(category, subcategory string) bool {
+	parent, ok := m.known[category]
+	if !ok || parent != "" {
+		return false
+	}
+	if subcategory == "" {
+		return true
+	}
+	parent, ok = m.known[subcategory]
+	return ok && parent == category
+}
+
+// Has reports whether a category or subcategory with the ID exists.
+func (m *Mapping) Has(id string) bool {
+	_, ok := m.known[id]
+	return ok
+}
+
+// IDs returns the IDs of all categories and subcategories.
+func (m *Mapping) IDs() []string {
+	ids := make([]string, len(m.Categories))
+	for i, c := range m.Categories {
+		ids[i] = c.ID
+	}
+	return ids
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// ItineraryRequest asks for a multi-day trip. POIs are taken from POIIDs or,
+// when empty, found by Query.
+type ItineraryRequest struct {
+	POIIDs     []string `json:"poi_ids,omitempty"`
+	Query      string   `json:"query,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+	Days       int      `json:"days"`
+	DailyHours float64  `json:"daily_hours,omitempty"`
+	// Base is the overnight location every day starts and ends at.
+	Base *Coordinate   `json:"base,omitempty"`
+	Mode TransportMode `json:"mode,omitempty"`
+	// StartTime is the departure on the first day; later days start at the
+	// same time of day. Defaults to 09:00 tomorrow.
+	StartTime *time.Time `json:"start_time,omitempty"`
+	// SkipClosed moves POIs closed on their planned day to another day or
+	// leaves them unscheduled.
+	SkipClosed bool `json:"skip_closed,omitempty"`
+}
+
+// ItineraryDay is the route for a single day of an itinerary.
+type ItineraryDay struct {
+	Day   int    `json:"day"`
+	Date  string `json:"date"`
+	Route *Route `json:"route"`
+	POIs  []POI  `json:"pois"`
+	// VisitMin is the time spent at POIs, TotalMin adds travel time.
+	VisitMin float64 `json:"visit_min"`
+	TotalMin float64 `json:"total_min"`
+}
+
+type Itinerary struct {
+	Days []ItineraryDay `json:"days"`
+	// Unscheduled are POIs that did not fit into the daily time budget.
+	Unscheduled []POI         `json:"unscheduled,omitempty"`
+	Mode        TransportMode `json:"mode"`
+	Message     string        `json:"message"`
+}
@@ -0,0 +1,103 @@
+package domain
+
+import "time"
+
+const minutesPerDay = 24 * 60
+
+// OpeningHours is a weekly schedule parsed from the OSM opening_hours tag.
+type OpeningHours struct {
+	Raw string `json:"raw"`
+	// Week holds open intervals per weekday, Sunday first as in
+	// time.Weekday. An empty day means closed.
+	Week [7][]TimeRange `json:"week"`
+	// Unparsed is set when Raw uses syntax the parser does not support;
+	// the schedule is then unknown.
+	Unparsed bool `json:"unparsed,omitempty"`
+}
+
+// TimeRange is an open interval in minutes after midnight. To may exceed
+// 24:00 for intervals that end after midnight.
+type TimeRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Known reports whether the schedule can be evaluated.
+func (h *OpeningHours) Known() bool {
+	return h != nil && !h.Unparsed
+}
+
+// IsOpen reports whether the place is open at t. The caller is responsible
+// for passing t in the place's local time zone.
+func (h *OpeningHours) IsOpen(t time.Time) bool {
+	_, ok := h.currentRange(t)
+	return ok
+}
+
+// ClosesAt returns the closing time of the interval containing t. Intervals
+// that continue on the next day from midnight, as in "24/7", are joined.
+func (h *OpeningHours) ClosesAt(t time.Time) (time.Time, bool) {
+	closes, ok := h.currentRange(t)
+	if !ok {
+		return closes, false
+	}
+
+	for day := 0; day < 7; day++ {
+		if closes.Hour() != 0 || closes.Minute() != 0 {
+			break
+		}
+		next, ok := h.currentRange(closes)
+		if !ok || !next.After(closes) {
+			break
+		}
+		closes = next
+	}
+
+	return closes, true
+}
+
+// NextOpening returns the first opening time after t within a week.
+func (h *OpeningHours) NextOpening(t time.Time) (time.Time, bool) {
+	if !h.Known() {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for day := 0; day <= 7; day++ {
+		date := midnight.AddDate(0, 0, day)
+		for _, r := range h.Week[date.Weekday()] {
+			open := date.Add(time.Duration(r.From) * time.Minute)
+			if open.After(t) {
+				return open, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// currentRange returns the end of the open interval containing t.
+func (h *OpeningHours) currentRange(t time.Time) (time.Time, bool) {
+	if !h.Known() {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	minute := int(t.Sub(midnight) / time.Minute)
+
+	for _, r := range h.Week[t.Weekday()] {
+		if minute >= r.From && minute < r.To {
+			return midnight.Add(time.Duration(r.To) * time.Minute), true
+		}
+	}
+
+	// Intervals of the previous day that run past midnight.
+	previous := midnight.AddDate(0, 0, -1)
+	for _, r := range h.Week[previous.Weekday()] {
+		if r.To > minutesPerDay && minute+minutesPerDay >= r.From && minute+minutesPerDay < r.To {
+			return previous.Add(time.Duration(r.To) * time.Minute), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DatePrecision tells how exactly YearBuilt or YearDestroyed is known.
+type DatePrecision string
+
+const (
+	DatePrecisionDay   DatePrecision = "day"
+	DatePrecisionMonth DatePrecision = "month"
+	DatePrecisionYear  DatePrecision = "year"
+	// DatePrecisionCirca is an approximate year ("~1700").
+	DatePrecisionCirca DatePrecision = "circa"
+	// DatePrecisionRange is a year within a range ("1890..1895"); the
+	// year is the start of the range.
+	DatePrecisionRange DatePrecision = "range"
+	// DatePrecisionDecade is a decade ("1890s"); the year is its first one.
+	DatePrecisionDecade DatePrecision = "decade"
+	// DatePrecisionCentury is a century ("C18"); the year is its first one
+	// (1701).
+	DatePrecisionCentury DatePrecision = "century"
+)
+
+// Period is an inclusive range of years; search keeps the POIs built in it.
+type Period struct {
+	From int
+	To   int
+}
+
+var (
+	periodYear    = regexp.MustCompile(`^\d{3,4}$`)
+	periodRange   = regexp.MustCompile(`^(\d{1,4})\s*(?:-|–|\.\.)\s*(\d{1,4})$`)
+	periodDecade  = regexp.MustCompile(`^(\d{3}0)(?:s|-е)$`)
+	periodCentury = regexp.MustCompile(`^(?:c\s*)?([ivxlc]+|\d{1,2})(?:\s*(?:век|в\.?))?$`)
+)
+
+// ParsePeriod parses a year ("1812"), a range ("1700-1799", "1700..1799"),
+// a decade ("1890s", "1890-е") or a century ("C18", "18 век", "XVIII").
+func ParsePeriod(s string) (*Period, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	// Cyrillic letters typed instead of Roman numerals
+	v = strings.NewReplacer("х", "x", "і", "i", "с", "c").Replace(v)
+
+	switch {
+	case periodRange.MatchString(v):
+		m := periodRange.FindStringSubmatch(v)
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		if from > to {
+			return nil, fmt.Errorf("invalid period %q", s)
+		}
+		return &Period{From: from, To: to}, nil
+	case periodDecade.MatchString(v):
+		m := periodDecade.FindStringSubmatch(v)
+		from, _ := strconv.Atoi(m[1])
+		return &Period{From: from, To: from + 9}, nil
+	case periodYear.MatchString(v):
+		year, _ := strconv.Atoi(v)
+		return &Period{From: year, To: year}, nil
+	case periodCentury.MatchString(v):
+		m := periodCentury.FindStringSubmatch(v)
+		century, err := strconv.Atoi(m[1])
+		if err != nil {
+			century = romanToInt(m[1])
+		}
+		if century < 1 || century > 21 {
+			return nil, fmt.Errorf("invalid period %q", s)
+		}
+		return &Period{From: (century-1)*100 + 1, To: century * 100}, nil
+	}
+
+	return nil, fmt.Errorf("invalid period %q", s)
+}
+
+// String returns the period in a form ParsePeriod accepts.
+func (p Period) String() string {
+	return fmt.Sprintf("%d-%d", p.From, p.To)
+}
+
+// Century returns the century of a year: 18 for 1701..1800.
+func Century(year int) int {
+	return (year + 99) / 100
+}
+
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"},
+	{10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+}
+
+// romanToInt returns 0 for invalid numerals.
+func romanToInt(s string) int {
+	n := 0
+	rest := s
+	for _, r := range romanNumerals {
+		for strings.HasPrefix(rest, r.symbol) {
+			n += r.value
+			rest = rest[len(r.symbol):]
+		}
+	}
+	if rest != "" || strings.ToLower(IntToRoman(n)) != s {
+		return 0
+	}
+	return n
+}
+
+// IntToRoman formats a century number: 18 -> "XVIII".
+func IntToRoman(n int) string {
+	var b strings.Builder
+	for _, r := range romanNumerals {
+		for n >= r.value {
+			b.WriteString(r.symbol)
+			n -= r.value
+		}
+	}
+	return strings.ToUpper(b.String())
+}
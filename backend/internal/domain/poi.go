@@ -7,24 +7,34 @@ import (
 )
 
 type POI struct {
-	ID               uuid.UUID `json:"id"`
-	Name             string    `json:"name"`
-	Description      string    `json:"description,omitempty"`
-	ShortDescription string    `json:"short_description,omitempty"`
-	Lat              float64   `json:"lat"`
-	Lng              float64   `json:"lng"`
-	Address          string    `json:"address,omitempty"`
-	Category         string    `json:"category"`
-	Subcategory      string    `json:"subcategory,omitempty"`
-	Tags             []string  `json:"tags,omitempty"`
-	HistoricalPeriod string    `json:"historical_period,omitempty"`
-	YearBuilt        *int      `json:"year_built,omitempty"`
-	YearDestroyed    *int      `json:"year_destroyed,omitempty"`
-	Source           string    `json:"source"`
-	OsmID            *int64    `json:"osm_id,omitempty"`
-	PopularityScore  float64   `json:"popularity_score"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                     uuid.UUID     `json:"id"`
+	Name                   string        `json:"name"`
+	Description            string        `json:"description,omitempty"`
+	ShortDescription       string        `json:"short_description,omitempty"`
+	Lat                    float64       `json:"lat"`
+	Lng                    float64       `json:"lng"`
+	Address                string        `json:"address,omitempty"`
+	Category               string        `json:"category"`
+	Subcategory            string        `json:"subcategory,omitempty"`
+	Tags                   []string      `json:"tags,omitempty"`
+	Aliases                []string      `json:"aliases,omitempty"`
+	HistoricalPeriod       string        `json:"historical_period,omitempty"`
+	YearBuilt              *int          `json:"year_built,omitempty"`
+	YearBuiltPrecision     DatePrecision `json:"year_built_precision,omitempty"`
+	YearDestroyed          *int          `json:"year_destroyed,omitempty"`
+	YearDestroyedPrecision DatePrecision `json:"year_destroyed_precision,omitempty"`
+	OpeningHours           *OpeningHours `json:"opening_hours,omitempty"`
+	Source                 string        `json:"source"`
+	OsmID                  *int64        `json:"osm_id,omitempty"`
+	OsmType                string        `json:"osm_type,omitempty"`
+	Region                 string        `json:"region,omitempty"`
+	Wikidata               string        `json:"wikidata,omitempty"`
+	Wikipedia              string        `json:"wikipedia,omitempty"`
+	Heritage               string        `json:"heritage,omitempty"`
+	Image                  string        `json:"image,omitempty"`
+	PopularityScore        float64       `json:"popularity_score"`
+	CreatedAt              time.Time     `json:"created_at"`
+	UpdatedAt              time.Time     `json:"updated_at"`
 }
 
 type Category struct {
@@ -46,6 +56,13 @@ type HistoricalFact struct {
 	YearFrom  *int      `json:"year_from,omitempty"`
 	YearTo    *int      `json:"year_to,omitempty"`
 	SourceURL string    `json:"source_url,omitempty"`
+	Source    string    `json:"source,omitempty"`
+}
+
+// POIInfo is a POI with its facts in chronological order.
+type POIInfo struct {
+	*POI
+	Facts []HistoricalFact `json:"facts,omitempty"`
 }
 
 type Coordinate struct {
@@ -58,6 +75,7 @@ type SearchFilters struct {
 	Center     *Coordinate
 	RadiusKm   float64
 	Period     string
+	Region     string
 	Limit      int
 	Offset     int
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PresetRoute is an editor-curated themed route through an ordered list of
+// stops.
+type PresetRoute struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Category is the route theme, one of the trails subcategories
+	// (war_path, trade_route, pilgrimage).
+	Category      string        `json:"category"`
+	Mode          TransportMode `json:"mode"`
+	Difficulty    string        `json:"difficulty"`
+	DurationHours float64       `json:"duration_hours"`
+	DistanceKm    float64       `json:"distance_km"`
+	Image         string        `json:"image,omitempty"`
+	Tags          []string      `json:"tags"`
+	POIIDs        []string      `json:"poi_ids"`
+	Stops         []PresetStop  `json:"stops,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// PresetStop is a single stop of a preset route. POIID is empty when the
+// stop could not be matched to a POI during import.
+type PresetStop struct {
+	Position int        `json:"position"`
+	POIID    *uuid.UUID `json:"poi_id,omitempty"`
+	POI      *POI       `json:"poi,omitempty"`
+	Name     string     `json:"name"`
+	Note     string     `json:"note,omitempty"`
+	Location Coordinate `json:"location"`
+}
+
+type PresetRouteFilters struct {
+	Category   string
+	Mode       TransportMode
+	Difficulty string
+	Tag        string
+}
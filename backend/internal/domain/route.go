@@ -1,11 +1,40 @@
 package domain
 
+import "time"
+
 type Route struct {
 	DistanceKm  float64       `json:"distance_km"`
 	DurationMin float64       `json:"duration_min"`
 	Geometry    string        `json:"geometry"`
 	Waypoints   []Waypoint    `json:"waypoints"`
 	Mode        TransportMode `json:"mode"`
+	// Sequence holds, in visiting order, the index of each waypoint in the
+	// request that produced the route.
+	Sequence []int `json:"sequence,omitempty"`
+	// NearbyPOIs are the POIs the route passes close to, ordered along the route.
+	NearbyPOIs []POI `json:"nearby_pois,omitempty"`
+	// Summary compares an alternative route with the main one.
+	Summary string `json:"summary,omitempty"`
+	// Legs describe the walking and riding parts of a transit route.
+	Legs []RouteLeg `json:"legs,omitempty"`
+	// LegsMin is the travel time in minutes between consecutive waypoints.
+	LegsMin []float64 `json:"legs_min,omitempty"`
+}
+
+// RouteLeg is a single walking or public transport ride of a transit route.
+type RouteLeg struct {
+	// Kind is walk, subway, bus, tram, trolleybus, rail, monorail or ferry.
+	Kind        string     `json:"kind"`
+	Line        string     `json:"line,omitempty"`
+	From        string     `json:"from,omitempty"`
+	To          string     `json:"to,omitempty"`
+	Departure   time.Time  `json:"departure"`
+	Arrival     time.Time  `json:"arrival"`
+	DistanceKm  float64    `json:"distance_km"`
+	DurationMin float64    `json:"duration_min"`
+	Geometry    string     `json:"geometry"`
+	Start       Coordinate `json:"start"`
+	End         Coordinate `json:"end"`
 }
 
 type Waypoint struct {
@@ -13,6 +42,14 @@ type Waypoint struct {
 	Location Coordinate `json:"location"`
 	Name     string     `json:"name"`
 	Order    int        `json:"order"`
+	// Arrival and Departure are set for scheduled tours; Departure includes
+	// the visit and any wait for opening.
+	Arrival   *time.Time `json:"arrival,omitempty"`
+	Departure *time.Time `json:"departure,omitempty"`
+	// Closed marks a POI that is closed when the tour gets there.
+	Closed bool `json:"closed,omitempty"`
+	// HoursNote explains the opening hours situation, e.g. "закрывается в 18:00".
+	HoursNote string `json:"hours_note,omitempty"`
 }
 
 type TransportMode string
@@ -21,6 +58,7 @@ const (
 	TransportWalking TransportMode = "walking"
 	TransportDriving TransportMode = "driving"
 	TransportCycling TransportMode = "cycling"
+	TransportTransit TransportMode = "transit"
 )
 
 type RouteRequest struct {
@@ -30,11 +68,84 @@ type RouteRequest struct {
 	Waypoints []Coordinate  `json:"waypoints,omitempty"`
 	Mode      TransportMode `json:"mode"`
 	POIIDs    []string      `json:"poi_ids,omitempty"`
+	// Alternatives is the number of alternative routes to return in addition
+	// to the main one.
+	Alternatives int `json:"alternatives,omitempty"`
+	// DepartureTime is used by the transit mode; the current time is used
+	// when it is not set.
+	DepartureTime *time.Time `json:"departure_time,omitempty"`
 }
 
 type RouteResponse struct {
-	Route   *Route `json:"route"`
-	Message string `json:"message"`
-	POIs    []POI  `json:"pois"`
+	Route        *Route  `json:"route"`
+	Message      string  `json:"message"`
+	POIs         []POI   `json:"pois"`
+	Alternatives []Route `json:"alternatives,omitempty"`
+	// Skipped are POIs left out because they are closed on arrival.
+	Skipped []POI `json:"skipped,omitempty"`
+}
+
+// TourOptions controls scheduling of routes through POIs.
+type TourOptions struct {
+	// DepartureTime defaults to the current time.
+	DepartureTime *time.Time `json:"departure_time,omitempty"`
+	// SkipClosed drops POIs that would be closed on arrival instead of only
+	// flagging them.
+	SkipClosed bool `json:"skip_closed,omitempty"`
+}
+
+// CorridorPOI is a POI found within a buffer around a route line.
+type CorridorPOI struct {
+	POI       POI     `json:"poi"`
+	DistanceM float64 `json:"distance_m"`
+	// Position is the fraction of the route length (0..1) at which the POI
+	// is closest to the route.
+	Position        float64 `json:"position"`
+	DistanceAlongKm float64 `json:"distance_along_km"`
+	// DetourM and DetourMin estimate the extra distance and time needed to
+	// leave the route, visit the POI and come back.
+	DetourM   float64 `json:"detour_m"`
+	DetourMin float64 `json:"detour_min"`
+}
+
+// CorridorRequest asks for POIs along a route. Either an encoded Geometry of
+// an already built route or a Route request to build is required.
+type CorridorRequest struct {
+	Geometry   string        `json:"geometry,omitempty"`
+	Route      *RouteRequest `json:"route,omitempty"`
+	Mode       TransportMode `json:"mode,omitempty"`
+	BufferM    float64       `json:"buffer_m,omitempty"`
+	Categories []string      `json:"categories,omitempty"`
+	Limit      int           `json:"limit,omitempty"`
+}
+
+type CorridorResponse struct {
+	Route   *Route        `json:"route,omitempty"`
+	POIs    []CorridorPOI `json:"pois"`
+	Message string        `json:"message"`
+}
+
+// ReachabilityRequest asks which POIs can be reached from Location within
+// MaxMinutes of travel.
+type ReachabilityRequest struct {
+	Location   Coordinate    `json:"location"`
+	Mode       TransportMode `json:"mode"`
+	MaxMinutes float64       `json:"max_minutes"`
+	Categories []string      `json:"categories,omitempty"`
+	Limit      int           `json:"limit,omitempty"`
+}
+
+type ReachablePOI struct {
+	POI        POI     `json:"poi"`
+	TravelMin  float64 `json:"travel_min"`
+	DistanceKm float64 `json:"distance_km"`
 }
 
+type ReachabilityResponse struct {
+	POIs []ReachablePOI `json:"pois"`
+	// Area is an approximate polygon of the reachable area.
+	Area       []Coordinate  `json:"area"`
+	Mode       TransportMode `json:"mode"`
+	MaxMinutes float64       `json:"max_minutes"`
+	Message    string        `json:"message"`
+}
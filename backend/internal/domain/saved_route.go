@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedRoute is a route persisted so it can be reopened later or shared by
+// its short code.
+type SavedRoute struct {
+	ID          uuid.UUID         `json:"id"`
+	ShareCode   string            `json:"share_code"`
+	Title       string            `json:"title"`
+	Mode        TransportMode     `json:"mode"`
+	DistanceKm  float64           `json:"distance_km"`
+	DurationMin float64           `json:"duration_min"`
+	Geometry    string            `json:"geometry"`
+	Waypoints   []Waypoint        `json:"waypoints"`
+	POIs        []POI             `json:"pois"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	// DeleteToken is returned only when the route is saved; only its hash
+	// is stored.
+	DeleteToken string `json:"delete_token,omitempty"`
+}
+
+// Route returns the saved route in the shape returned by route building.
+func (r *SavedRoute) Route() *Route {
+	return &Route{
+		DistanceKm:  r.DistanceKm,
+		DurationMin: r.DurationMin,
+		Geometry:    r.Geometry,
+		Waypoints:   r.Waypoints,
+		Mode:        r.Mode,
+	}
+}
@@ -0,0 +1,313 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
+)
+
+type Format string
+
+const (
+	FormatGPX     Format = "gpx"
+	FormatKML     Format = "kml"
+	FormatGeoJSON Format = "geojson"
+)
+
+const creator = "MapBot"
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatGPX:
+		return FormatGPX, nil
+	case FormatKML:
+		return FormatKML, nil
+	case FormatGeoJSON:
+		return FormatGeoJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", s)
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatGPX:
+		return "application/gpx+xml"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	default:
+		return "application/geo+json"
+	}
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// point is a named location exported as a GPX waypoint or KML placemark.
+type point struct {
+	Location    domain.Coordinate
+	Name        string
+	Description string
+	Category    string
+}
+
+// Write encodes the route track and its POIs in the given format.
+func Write(w io.Writer, format Format, title string, route *domain.Route, pois []domain.POI) error {
+	if route == nil {
+		return fmt.Errorf("route is empty")
+	}
+
+	if title == "" {
+		title = fmt.Sprintf("Маршрут %.1f км", route.DistanceKm)
+	}
+
+	track := osrm.DecodePolyline(route.Geometry)
+	points := routePoints(route, pois)
+
+	switch format {
+	case FormatGPX:
+		return writeGPX(w, title, track, points)
+	case FormatKML:
+		return writeKML(w, title, track, points)
+	case FormatGeoJSON:
+		return writeGeoJSON(w, title, route, track, points)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// routePoints lists the route waypoints in visiting order followed by any
+// POIs of the response that are not waypoints themselves.
+func routePoints(route *domain.Route, pois []domain.POI) []point {
+	points := make([]point, 0, len(route.Waypoints)+len(pois))
+	seen := make(map[string]bool)
+
+	for i, wp := range route.Waypoints {
+		if wp.POI != nil {
+			seen[wp.POI.ID.String()] = true
+			points = append(points, poiPoint(wp.POI))
+			continue
+		}
+
+		name := wp.Name
+		if name == "" {
+			name = fmt.Sprintf("Точка %d", i+1)
+		}
+		points = append(points, point{Location: wp.Location, Name: name})
+	}
+
+	for i := range pois {
+		if seen[pois[i].ID.String()] {
+			continue
+		}
+		points = append(points, poiPoint(&pois[i]))
+	}
+
+	return points
+}
+
+func poiPoint(poi *domain.POI) point {
+	description := poi.Description
+	if description == "" {
+		description = poi.ShortDescription
+	}
+	if description == "" {
+		description = poi.Address
+	}
+
+	return point{
+		Location:    domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng},
+		Name:        poi.Name,
+		Description: description,
+		Category:    poi.Category,
+	}
+}
+
+type gpxDoc struct {
+	XMLName   xml.Name    `xml:"gpx"`
+	Version   string      `xml:"version,attr"`
+	Creator   string      `xml:"creator,attr"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	Metadata  gpxMetadata `xml:"metadata"`
+	Waypoints []gpxPoint  `xml:"wpt"`
+	Track     gpxTrack    `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+}
+
+type gpxPoint struct {
+	Lat         float64 `xml:"lat,attr"`
+	Lon         float64 `xml:"lon,attr"`
+	Name        string  `xml:"name,omitempty"`
+	Description string  `xml:"desc,omitempty"`
+	Type        string  `xml:"type,omitempty"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+func writeGPX(w io.Writer, title string, track []domain.Coordinate, points []point) error {
+	doc := gpxDoc{
+		Version:  "1.1",
+		Creator:  creator,
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		Metadata: gpxMetadata{Name: title},
+		Track:    gpxTrack{Name: title},
+	}
+
+	for _, p := range points {
+		doc.Waypoints = append(doc.Waypoints, gpxPoint{
+			Lat:         p.Location.Lat,
+			Lon:         p.Location.Lng,
+			Name:        p.Name,
+			Description: p.Description,
+			Type:        p.Category,
+		})
+	}
+
+	for _, c := range track {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxPoint{Lat: c.Lat, Lon: c.Lng})
+	}
+
+	return writeXML(w, doc)
+}
+
+type kmlDoc struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name"`
+	Description string         `xml:"description,omitempty"`
+	Point       *kmlPoint      `xml:"Point,omitempty"`
+	LineString  *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+func writeKML(w io.Writer, title string, track []domain.Coordinate, points []point) error {
+	doc := kmlDoc{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{Name: title},
+	}
+
+	coords := make([]string, len(track))
+	for i, c := range track {
+		coords[i] = kmlCoordinate(c)
+	}
+
+	doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+		Name:       title,
+		LineString: &kmlLineString{Tessellate: 1, Coordinates: strings.Join(coords, " ")},
+	})
+
+	for _, p := range points {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:        p.Name,
+			Description: p.Description,
+			Point:       &kmlPoint{Coordinates: kmlCoordinate(p.Location)},
+		})
+	}
+
+	return writeXML(w, doc)
+}
+
+func kmlCoordinate(c domain.Coordinate) string {
+	return fmt.Sprintf("%.6f,%.6f,0", c.Lng, c.Lat)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode xml: %w", err)
+	}
+	return enc.Close()
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func writeGeoJSON(w io.Writer, title string, route *domain.Route, track []domain.Coordinate, points []point) error {
+	line := make([][2]float64, len(track))
+	for i, c := range track {
+		line[i] = [2]float64{c.Lng, c.Lat}
+	}
+
+	collection := geoJSONCollection{
+		Type: "FeatureCollection",
+		Features: []geoJSONFeature{{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "LineString", Coordinates: line},
+			Properties: map[string]interface{}{
+				"name":         title,
+				"distance_km":  route.DistanceKm,
+				"duration_min": route.DurationMin,
+				"mode":         route.Mode,
+			},
+		}},
+	}
+
+	for _, p := range points {
+		props := map[string]interface{}{"name": p.Name}
+		if p.Description != "" {
+			props["description"] = p.Description
+		}
+		if p.Category != "" {
+			props["category"] = p.Category
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: [2]float64{p.Location.Lng, p.Location.Lat}},
+			Properties: props,
+		})
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
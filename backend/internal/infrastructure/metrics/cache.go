@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cache results.
+const (
+	CacheLocalHit = "local_hit"
+	CacheHit      = "hit"
+	CacheStale    = "stale"
+	CacheMiss     = "miss"
+	CacheShared   = "shared"
+	CacheBypass   = "bypass"
+)
+
+// CacheRequestsTotal counts cache lookups by cache name and result.
+// "local_hit" is served from process memory, "hit" from Redis, "stale" is
+// served while a refresh runs in the background, "shared" is a miss served
+// by a concurrent identical request and "bypass" means the cache is not
+// configured.
+var CacheRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Total number of cache lookups by cache and result",
+	},
+	[]string{"cache", "result"},
+)
+
+// RegisterCacheMetrics registers the application cache metrics.
+func RegisterCacheMetrics() {
+	prometheus.MustRegister(CacheRequestsTotal)
+}
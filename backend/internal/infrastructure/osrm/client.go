@@ -65,6 +65,18 @@ type Waypoint struct {
 }
 
 func (c *Client) Route(ctx context.Context, waypoints []domain.Coordinate, mode domain.TransportMode) (*domain.Route, error) {
+	routes, err := c.RouteAlternatives(ctx, waypoints, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	return routes[0], nil
+}
+
+// RouteAlternatives builds the fastest route and asks OSRM for up to
+// alternatives additional routes. The first element is always the main route.
+// OSRM only computes alternatives between two waypoints, so fewer routes than
+// requested may be returned.
+func (c *Client) RouteAlternatives(ctx context.Context, waypoints []domain.Coordinate, mode domain.TransportMode, alternatives int) ([]*domain.Route, error) {
 	if len(waypoints) < 2 {
 		return nil, fmt.Errorf("at least 2 waypoints required")
 	}
@@ -73,6 +85,9 @@ func (c *Client) Route(ctx context.Context, waypoints []domain.Coordinate, mode
 	coords := formatCoordinates(waypoints)
 
 	url := fmt.Sprintf("%s/route/v1/%s/%s?overview=full&geometries=polyline", c.baseURL, profile, coords)
+	if alternatives > 0 {
+		url += fmt.Sprintf("&alternatives=%d", alternatives)
+	}
 	log.Printf("OSRM Route request: %s", url)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -103,27 +118,46 @@ func (c *Client) Route(ctx context.Context, waypoints []domain.Coordinate, mode
 		return nil, fmt.Errorf("routing failed: %s", routeResp.Code)
 	}
 
-	route := routeResp.Routes[0]
-	log.Printf("OSRM Route success: %.1f km, %.0f min", route.Distance/1000, route.Duration/60)
+	log.Printf("OSRM Route success: %.1f km, %.0f min, %d alternatives",
+		routeResp.Routes[0].Distance/1000, routeResp.Routes[0].Duration/60, len(routeResp.Routes)-1)
 
-	result := &domain.Route{
-		DistanceKm:  route.Distance / 1000,
-		DurationMin: route.Duration / 60,
-		Geometry:    route.Geometry,
-		Mode:        mode,
-		Waypoints:   make([]domain.Waypoint, len(waypoints)),
-	}
+	results := make([]*domain.Route, 0, len(routeResp.Routes))
+	for i, route := range routeResp.Routes {
+		if i > alternatives {
+			break
+		}
+
+		result := &domain.Route{
+			DistanceKm:  route.Distance / 1000,
+			DurationMin: route.Duration / 60,
+			Geometry:    route.Geometry,
+			Mode:        mode,
+			Waypoints:   make([]domain.Waypoint, len(waypoints)),
+			Sequence:    make([]int, len(waypoints)),
+			LegsMin:     legDurations(route.Legs),
+		}
 
-	for i, coord := range waypoints {
-		result.Waypoints[i] = domain.Waypoint{
-			Location: coord,
-			Order:    i,
+		for j, coord := range waypoints {
+			result.Waypoints[j] = domain.Waypoint{
+				Location: coord,
+				Order:    j,
+			}
+			result.Sequence[j] = j
 		}
+
+		results = append(results, result)
 	}
 
-	return result, nil
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no routes kept for %d alternatives", alternatives)
+	}
+
+	return results, nil
 }
 
+// Trip orders the waypoints into the shortest trip starting at the first
+// one. A trip that is not a round trip ends at the last waypoint: OSRM
+// supports no other end for it.
 func (c *Client) Trip(ctx context.Context, waypoints []domain.Coordinate, mode domain.TransportMode, roundtrip bool) (*domain.Route, error) {
 	if len(waypoints) < 2 {
 		return nil, fmt.Errorf("at least 2 waypoints required")
@@ -178,10 +212,18 @@ func (c *Client) Trip(ctx context.Context, waypoints []domain.Coordinate, mode d
 		Geometry:    trip.Geometry,
 		Mode:        mode,
 		Waypoints:   make([]domain.Waypoint, len(tripResp.Waypoints)),
+		Sequence:    make([]int, len(tripResp.Waypoints)),
+		LegsMin:     legDurations(trip.Legs),
 	}
 
+	// OSRM returns waypoints in input order; waypoint_index is the position
+	// of the waypoint within the optimized trip.
 	for i, wp := range tripResp.Waypoints {
-		result.Waypoints[i] = domain.Waypoint{
+		if wp.WaypointIndex < 0 || wp.WaypointIndex >= len(tripResp.Waypoints) || len(wp.Location) < 2 {
+			return nil, fmt.Errorf("trip planning failed: invalid waypoint %d", i)
+		}
+
+		result.Waypoints[wp.WaypointIndex] = domain.Waypoint{
 			Location: domain.Coordinate{
 				Lat: wp.Location[1],
 				Lng: wp.Location[0],
@@ -189,14 +231,112 @@ func (c *Client) Trip(ctx context.Context, waypoints []domain.Coordinate, mode d
 			Name:  wp.Name,
 			Order: wp.WaypointIndex,
 		}
+		result.Sequence[wp.WaypointIndex] = i
 	}
 
 	return result, nil
 }
 
+type TableResponse struct {
+	Code      string       `json:"code"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+// maxTableDestinations keeps table requests under the default OSRM
+// max-table-size of 100 coordinates including the source.
+const maxTableDestinations = 99
+
+// TableFrom returns travel durations (seconds) and distances (meters) from the
+// source to every destination. Unreachable destinations get -1.
+func (c *Client) TableFrom(ctx context.Context, source domain.Coordinate, destinations []domain.Coordinate, mode domain.TransportMode) ([]float64, []float64, error) {
+	durations := make([]float64, 0, len(destinations))
+	distances := make([]float64, 0, len(destinations))
+
+	for i := 0; i < len(destinations); i += maxTableDestinations {
+		end := i + maxTableDestinations
+		if end > len(destinations) {
+			end = len(destinations)
+		}
+
+		chunkDurations, chunkDistances, err := c.tableChunk(ctx, source, destinations[i:end], mode)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		durations = append(durations, chunkDurations...)
+		distances = append(distances, chunkDistances...)
+	}
+
+	return durations, distances, nil
+}
+
+func (c *Client) tableChunk(ctx context.Context, source domain.Coordinate, destinations []domain.Coordinate, mode domain.TransportMode) ([]float64, []float64, error) {
+	profile := modeToProfile(mode)
+	coords := formatCoordinates(append([]domain.Coordinate{source}, destinations...))
+
+	url := fmt.Sprintf("%s/table/v1/%s/%s?sources=0&annotations=duration,distance", c.baseURL, profile, coords)
+	log.Printf("OSRM Table request: %d destinations", len(destinations))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("OSRM Table error: %v", err)
+		return nil, nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var tableResp TableResponse
+	if err := json.Unmarshal(body, &tableResp); err != nil {
+		log.Printf("OSRM Table parse error: %v, body: %s", err, string(body))
+		return nil, nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	if tableResp.Code != "Ok" || len(tableResp.Durations) == 0 {
+		log.Printf("OSRM Table failed: code=%s", tableResp.Code)
+		return nil, nil, fmt.Errorf("table failed: %s", tableResp.Code)
+	}
+
+	// The first column is the source itself.
+	durations := make([]float64, len(destinations))
+	distances := make([]float64, len(destinations))
+	for i := range destinations {
+		durations[i] = tableValue(tableResp.Durations, i+1)
+		distances[i] = tableValue(tableResp.Distances, i+1)
+	}
+
+	return durations, distances, nil
+}
+
+func tableValue(matrix [][]*float64, col int) float64 {
+	if len(matrix) == 0 || col >= len(matrix[0]) || matrix[0][col] == nil {
+		return -1
+	}
+	return *matrix[0][col]
+}
+
+// legDurations converts leg durations to minutes.
+func legDurations(legs []Leg) []float64 {
+	result := make([]float64, len(legs))
+	for i, leg := range legs {
+		result[i] = leg.Duration / 60
+	}
+	return result
+}
+
 func modeToProfile(mode domain.TransportMode) string {
 	switch mode {
-	case domain.TransportWalking:
+	case domain.TransportWalking, domain.TransportTransit:
+		// Transit routes use OSRM only for their walking legs.
 		return "foot"
 	case domain.TransportCycling:
 		return "bike"
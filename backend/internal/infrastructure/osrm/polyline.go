@@ -0,0 +1,101 @@
+package osrm
+
+import (
+	"math"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// DecodePolyline decodes an OSRM geometry encoded with the Google polyline
+// algorithm (precision 5).
+func DecodePolyline(encoded string) []domain.Coordinate {
+	points := make([]domain.Coordinate, 0, len(encoded)/4)
+	index := 0
+	lat := 0
+	lng := 0
+
+	for index < len(encoded) {
+		dlat, next, ok := decodeValue(encoded, index)
+		if !ok {
+			break
+		}
+		index = next
+
+		dlng, next, ok := decodeValue(encoded, index)
+		if !ok {
+			break
+		}
+		index = next
+
+		lat += dlat
+		lng += dlng
+
+		points = append(points, domain.Coordinate{
+			Lat: float64(lat) / 1e5,
+			Lng: float64(lng) / 1e5,
+		})
+	}
+
+	return points
+}
+
+// EncodePolyline encodes coordinates with the Google polyline algorithm
+// (precision 5), the format OSRM returns geometries in.
+func EncodePolyline(points []domain.Coordinate) string {
+	buf := make([]byte, 0, len(points)*8)
+	prevLat := 0
+	prevLng := 0
+
+	for _, p := range points {
+		lat := int(math.Round(p.Lat * 1e5))
+		lng := int(math.Round(p.Lng * 1e5))
+
+		buf = encodeValue(buf, lat-prevLat)
+		buf = encodeValue(buf, lng-prevLng)
+
+		prevLat = lat
+		prevLng = lng
+	}
+
+	return string(buf)
+}
+
+func encodeValue(buf []byte, value int) []byte {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+
+	for v >= 0x20 {
+		buf = append(buf, byte((0x20|(v&0x1f))+63))
+		v >>= 5
+	}
+
+	return append(buf, byte(v+63))
+}
+
+func decodeValue(encoded string, index int) (int, int, bool) {
+	result := 0
+	shift := 0
+
+	for {
+		if index >= len(encoded) {
+			return 0, index, false
+		}
+
+		b := int(encoded[index]) - 63
+		index++
+
+		result |= (b & 0x1f) << shift
+		shift += 5
+
+		if b < 0x20 {
+			break
+		}
+	}
+
+	if result&1 != 0 {
+		return ^(result >> 1), index, true
+	}
+	return result >> 1, index, true
+}
@@ -81,13 +81,7 @@ func (c *Client) UpsertPOI(ctx context.Context, poi *domain.POI, vector []float3
 				Vector: &pb.Vector{Data: vector},
 			},
 		},
-		Payload: map[string]*pb.Value{
-			"name":       {Kind: &pb.Value_StringValue{StringValue: poi.Name}},
-			"category":   {Kind: &pb.Value_StringValue{StringValue: poi.Category}},
-			"lat":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lat}},
-			"lng":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lng}},
-			"popularity": {Kind: &pb.Value_DoubleValue{DoubleValue: poi.PopularityScore}},
-		},
+		Payload: poiPayload(poi),
 	}
 
 	_, err := c.pointsClient.Upsert(ctx, &pb.UpsertPoints{
@@ -114,13 +108,65 @@ func (c *Client) UpsertBatch(ctx context.Context, pois []domain.POI, vectors [][
 					Vector: &pb.Vector{Data: vectors[i]},
 				},
 			},
-			Payload: map[string]*pb.Value{
-				"name":       {Kind: &pb.Value_StringValue{StringValue: poi.Name}},
-				"category":   {Kind: &pb.Value_StringValue{StringValue: poi.Category}},
-				"lat":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lat}},
-				"lng":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lng}},
-				"popularity": {Kind: &pb.Value_DoubleValue{DoubleValue: poi.PopularityScore}},
+			Payload: poiPayload(&poi),
+		}
+	}
+
+	_, err := c.pointsClient.Upsert(ctx, &pb.UpsertPoints{
+		CollectionName: CollectionName,
+		Points:         points,
+	})
+
+	return err
+}
+
+func poiPayload(poi *domain.POI) map[string]*pb.Value {
+	payload := map[string]*pb.Value{
+		"name":       {Kind: &pb.Value_StringValue{StringValue: poi.Name}},
+		"category":   {Kind: &pb.Value_StringValue{StringValue: poi.Category}},
+		"lat":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lat}},
+		"lng":        {Kind: &pb.Value_DoubleValue{DoubleValue: poi.Lng}},
+		"popularity": {Kind: &pb.Value_DoubleValue{DoubleValue: poi.PopularityScore}},
+		"region":     {Kind: &pb.Value_StringValue{StringValue: poi.Region}},
+	}
+	if poi.YearBuilt != nil {
+		payload["year_built"] = yearValue(*poi.YearBuilt)
+	}
+	return payload
+}
+
+// FactPoint is a historical fact to index with its POI.
+type FactPoint struct {
+	Fact   domain.HistoricalFact
+	POI    *domain.POI
+	Vector []float32
+}
+
+// UpsertFacts indexes facts as separate points. Their payload is that of
+// the POI, so search filters apply to them, plus kind "fact" and the
+// poi_id search results are reported with.
+func (c *Client) UpsertFacts(ctx context.Context, facts []FactPoint) error {
+	if len(facts) == 0 {
+		return nil
+	}
+
+	points := make([]*pb.PointStruct, len(facts))
+	for i, f := range facts {
+		payload := poiPayload(f.POI)
+		payload["kind"] = &pb.Value{Kind: &pb.Value_StringValue{StringValue: factKind}}
+		payload["poi_id"] = &pb.Value{Kind: &pb.Value_StringValue{StringValue: f.POI.ID.String()}}
+		payload["title"] = &pb.Value{Kind: &pb.Value_StringValue{StringValue: f.Fact.Title}}
+
+		points[i] = &pb.PointStruct{
+			Id: &pb.PointId{
+				PointIdOptions: &pb.PointId_Uuid{Uuid: f.Fact.ID.String()},
+			},
+			Vectors: &pb.Vectors{
+				VectorsOptions: &pb.Vectors_Vector{
+					Vector: &pb.Vector{Data: f.Vector},
+				},
 			},
+			Payload: payload,
 		}
 	}
 
@@ -132,6 +178,67 @@ func (c *Client) UpsertBatch(ctx context.Context, pois []domain.POI, vectors [][
 	return err
 }
 
+// DeleteFactPoints removes the fact points of the given POIs.
+func (c *Client) DeleteFactPoints(ctx context.Context, poiIDs []uuid.UUID) error {
+	ids := make([]string, len(poiIDs))
+	for i, id := range poiIDs {
+		ids[i] = id.String()
+	}
+
+	_, err := c.pointsClient.Delete(ctx, &pb.DeletePoints{
+		CollectionName: CollectionName,
+		Points: &pb.PointsSelector{
+			PointsSelectorOneOf: &pb.PointsSelector_Filter{
+				Filter: &pb.Filter{
+					Must: []*pb.Condition{
+						keywordCondition("kind", factKind),
+						{
+							ConditionOneOf: &pb.Condition_Field{
+								Field: &pb.FieldCondition{
+									Key: "poi_id",
+									Match: &pb.Match{
+										MatchValue: &pb.Match_Keywords{
+											Keywords: &pb.RepeatedStrings{Strings: ids},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+// DeletePoints removes the points of the given POIs.
+func (c *Client) DeletePoints(ctx context.Context, ids []uuid.UUID) error {
+	pointIDs := make([]*pb.PointId, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = &pb.PointId{
+			PointIdOptions: &pb.PointId_Uuid{Uuid: id.String()},
+		}
+	}
+
+	_, err := c.pointsClient.Delete(ctx, &pb.DeletePoints{
+		CollectionName: CollectionName,
+		Points: &pb.PointsSelector{
+			PointsSelectorOneOf: &pb.PointsSelector_Points{
+				Points: &pb.PointsIdsList{Ids: pointIDs},
+			},
+		},
+	})
+
+	return err
+}
+
+// factKind marks the points of historical facts; POI points have no kind.
+const factKind = "fact"
+
+// SearchResult is a matching POI. For fact points ID is the ID of the POI
+// the fact belongs to.
 type SearchResult struct {
 	ID         uuid.UUID
 	Score      float32
@@ -142,7 +249,7 @@ type SearchResult struct {
 	Popularity float64
 }
 
-func (c *Client) Search(ctx context.Context, vector []float32, limit uint64, categories []string) ([]SearchResult, error) {
+func (c *Client) Search(ctx context.Context, vector []float32, limit uint64, categories []string, region string, period *domain.Period) ([]SearchResult, error) {
 	var filter *pb.Filter
 	if len(categories) > 0 {
 		values := make([]*pb.Value, len(categories))
@@ -167,6 +274,8 @@ func (c *Client) Search(ctx context.Context, vector []float32, limit uint64, cat
 			},
 		}
 	}
+	filter = withRegion(filter, region)
+	filter = withPeriod(filter, period)
 
 	resp, err := c.pointsClient.Search(ctx, &pb.SearchPoints{
 		CollectionName: CollectionName,
@@ -182,36 +291,13 @@ func (c *Client) Search(ctx context.Context, vector []float32, limit uint64, cat
 
 	results := make([]SearchResult, len(resp.Result))
 	for i, point := range resp.Result {
-		id, _ := uuid.Parse(point.Id.GetUuid())
-
-		result := SearchResult{
-			ID:    id,
-			Score: point.Score,
-		}
-
-		if name, ok := point.Payload["name"]; ok {
-			result.Name = name.GetStringValue()
-		}
-		if cat, ok := point.Payload["category"]; ok {
-			result.Category = cat.GetStringValue()
-		}
-		if lat, ok := point.Payload["lat"]; ok {
-			result.Lat = lat.GetDoubleValue()
-		}
-		if lng, ok := point.Payload["lng"]; ok {
-			result.Lng = lng.GetDoubleValue()
-		}
-		if pop, ok := point.Payload["popularity"]; ok {
-			result.Popularity = pop.GetDoubleValue()
-		}
-
-		results[i] = result
+		results[i] = searchResult(point)
 	}
 
 	return results, nil
 }
 
-func (c *Client) SearchWithGeo(ctx context.Context, vector []float32, limit uint64, lat, lng, radiusKm float64) ([]SearchResult, error) {
+func (c *Client) SearchWithGeo(ctx context.Context, vector []float32, limit uint64, lat, lng, radiusKm float64, region string, period *domain.Period) ([]SearchResult, error) {
 	filter := &pb.Filter{
 		Must: []*pb.Condition{
 			{
@@ -238,6 +324,8 @@ func (c *Client) SearchWithGeo(ctx context.Context, vector []float32, limit uint
 			},
 		},
 	}
+	filter = withRegion(filter, region)
+	filter = withPeriod(filter, period)
 
 	resp, err := c.pointsClient.Search(ctx, &pb.SearchPoints{
 		CollectionName: CollectionName,
@@ -253,33 +341,95 @@ func (c *Client) SearchWithGeo(ctx context.Context, vector []float32, limit uint
 
 	results := make([]SearchResult, len(resp.Result))
 	for i, point := range resp.Result {
-		id, _ := uuid.Parse(point.Id.GetUuid())
+		results[i] = searchResult(point)
+	}
 
-		result := SearchResult{
-			ID:    id,
-			Score: point.Score,
-		}
+	return results, nil
+}
 
-		if name, ok := point.Payload["name"]; ok {
-			result.Name = name.GetStringValue()
-		}
-		if cat, ok := point.Payload["category"]; ok {
-			result.Category = cat.GetStringValue()
-		}
-		if lat, ok := point.Payload["lat"]; ok {
-			result.Lat = lat.GetDoubleValue()
-		}
-		if lng, ok := point.Payload["lng"]; ok {
-			result.Lng = lng.GetDoubleValue()
-		}
-		if pop, ok := point.Payload["popularity"]; ok {
-			result.Popularity = pop.GetDoubleValue()
-		}
+func searchResult(point *pb.ScoredPoint) SearchResult {
+	id, _ := uuid.Parse(point.Id.GetUuid())
 
-		results[i] = result
+	result := SearchResult{
+		ID:    id,
+		Score: point.Score,
 	}
 
-	return results, nil
+	if kind, ok := point.Payload["kind"]; ok && kind.GetStringValue() == factKind {
+		result.ID, _ = uuid.Parse(point.Payload["poi_id"].GetStringValue())
+	}
+	if name, ok := point.Payload["name"]; ok {
+		result.Name = name.GetStringValue()
+	}
+	if cat, ok := point.Payload["category"]; ok {
+		result.Category = cat.GetStringValue()
+	}
+	if lat, ok := point.Payload["lat"]; ok {
+		result.Lat = lat.GetDoubleValue()
+	}
+	if lng, ok := point.Payload["lng"]; ok {
+		result.Lng = lng.GetDoubleValue()
+	}
+	if pop, ok := point.Payload["popularity"]; ok {
+		result.Popularity = pop.GetDoubleValue()
+	}
+
+	return result
+}
+
+// withRegion adds a region condition to the filter. Points indexed before
+// regions existed have no region and are excluded.
+func withRegion(filter *pb.Filter, region string) *pb.Filter {
+	if region == "" {
+		return filter
+	}
+	if filter == nil {
+		filter = &pb.Filter{}
+	}
+
+	filter.Must = append(filter.Must, keywordCondition("region", region))
+	return filter
+}
+
+func keywordCondition(key, value string) *pb.Condition {
+	return &pb.Condition{
+		ConditionOneOf: &pb.Condition_Field{
+			Field: &pb.FieldCondition{
+				Key: key,
+				Match: &pb.Match{
+					MatchValue: &pb.Match_Keyword{Keyword: value},
+				},
+			},
+		},
+	}
+}
+
+// withPeriod keeps the points of POIs built within the period; points
+// without a year of construction are excluded.
+func withPeriod(filter *pb.Filter, period *domain.Period) *pb.Filter {
+	if period == nil {
+		return filter
+	}
+	if filter == nil {
+		filter = &pb.Filter{}
+	}
+
+	filter.Must = append(filter.Must, &pb.Condition{
+		ConditionOneOf: &pb.Condition_Field{
+			Field: &pb.FieldCondition{
+				Key: "year_built",
+				Range: &pb.Range{
+					Gte: float64Ptr(float64(period.From)),
+					Lte: float64Ptr(float64(period.To)),
+				},
+			},
+		},
+	})
+	return filter
+}
+
+func yearValue(year int) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_IntegerValue{IntegerValue: int64(year)}}
 }
 
 func float64Ptr(v float64) *float64 {
@@ -0,0 +1,83 @@
+package osm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+var (
+	osmDate    = regexp.MustCompile(`^(\d{1,4})(?:-(\d{2})(?:-(\d{2}))?)?$`)
+	osmDecade  = regexp.MustCompile(`^(\d{2,3}0)s$`)
+	osmCentury = regexp.MustCompile(`^(?:(early|mid|late)\s+)?c(\d{1,2})$`)
+)
+
+// centuryParts shifts the year of "early C18", "mid C18" and "late C18"
+// into the named third of the century.
+var centuryParts = map[string]int{"": 0, "early": 0, "mid": 33, "late": 66}
+
+// ParseDate parses an OSM date value (start_date, end_date and similar):
+// "1765", "1812-05", "1812-05-24", "~1700", "1890s", "C18", "late C18"
+// and ranges "1890..1895". It returns the year and how exactly it is known;
+// for ranges, decades and centuries the year is the first one. Other
+// values ("before 1800", free text) are not supported.
+func ParseDate(raw string) (int, domain.DatePrecision, bool) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if v == "" {
+		return 0, "", false
+	}
+
+	if from, _, ok := strings.Cut(v, ".."); ok {
+		year, _, ok := ParseDate(from)
+		if !ok {
+			return 0, "", false
+		}
+		return year, domain.DatePrecisionRange, true
+	}
+
+	circa := strings.HasPrefix(v, "~")
+	v = strings.TrimSpace(strings.TrimPrefix(v, "~"))
+
+	var year int
+	var precision domain.DatePrecision
+
+	switch {
+	case osmDate.MatchString(v):
+		m := osmDate.FindStringSubmatch(v)
+		year, _ = strconv.Atoi(m[1])
+		switch {
+		case m[3] != "":
+			precision = domain.DatePrecisionDay
+		case m[2] != "":
+			precision = domain.DatePrecisionMonth
+		default:
+			precision = domain.DatePrecisionYear
+		}
+	case osmDecade.MatchString(v):
+		year, _ = strconv.Atoi(osmDecade.FindStringSubmatch(v)[1])
+		precision = domain.DatePrecisionDecade
+	case osmCentury.MatchString(v):
+		m := osmCentury.FindStringSubmatch(v)
+		century, _ := strconv.Atoi(m[2])
+		if century < 1 {
+			return 0, "", false
+		}
+		year = (century-1)*100 + 1 + centuryParts[m[1]]
+		precision = domain.DatePrecisionCentury
+	default:
+		return 0, "", false
+	}
+
+	if year < 1 || year > time.Now().Year()+1 {
+		return 0, "", false
+	}
+
+	// "~C18" is no less exact than "C18"
+	if circa && (precision == domain.DatePrecisionYear || precision == domain.DatePrecisionMonth || precision == domain.DatePrecisionDay) {
+		precision = domain.DatePrecisionCirca
+	}
+	return year, precision, true
+}
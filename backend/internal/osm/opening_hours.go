@@ -0,0 +1,197 @@
+package osm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// osmWeekdays maps OSM day abbreviations to time.Weekday indexes.
+var osmWeekdays = map[string]int{
+	"Su": 0, "Mo": 1, "Tu": 2, "We": 3, "Th": 4, "Fr": 5, "Sa": 6,
+}
+
+// ParseOpeningHours parses the common subset of the OSM opening_hours
+// syntax: "24/7", weekday ranges and lists ("Mo-Fr", "Sa,Su"), one or more
+// time ranges per rule and "off"/"closed". Rules are separated by ";" and
+// later rules override earlier ones for the days they name. A fallback rule
+// after "||" applies only to the days no earlier rule names. Public holiday
+// rules ("PH ...") are ignored. Month, week and date selectors are not
+// supported and return an error.
+func ParseOpeningHours(raw string) (*domain.OpeningHours, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty opening_hours")
+	}
+
+	hours := &domain.OpeningHours{Raw: raw}
+
+	var matched [7]bool
+	for _, group := range strings.Split(raw, "||") {
+		week, named, err := parseRules(group)
+		if err != nil {
+			return nil, err
+		}
+
+		for day := range week {
+			if named[day] && !matched[day] {
+				hours.Week[day] = week[day]
+				matched[day] = true
+			}
+		}
+	}
+
+	return hours, nil
+}
+
+// parseRules parses ";"-separated rules and reports the days they name.
+func parseRules(group string) ([7][]domain.TimeRange, [7]bool, error) {
+	var week [7][]domain.TimeRange
+	var named [7]bool
+
+	for _, rule := range strings.Split(group, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		if rule == "24/7" {
+			for day := range week {
+				week[day] = []domain.TimeRange{{From: 0, To: 24 * 60}}
+				named[day] = true
+			}
+			continue
+		}
+
+		days, rest, skip, err := parseDaySelector(rule)
+		if err != nil {
+			return week, named, err
+		}
+		if skip {
+			continue
+		}
+
+		ranges, err := parseTimeRanges(rest)
+		if err != nil {
+			return week, named, fmt.Errorf("rule %q: %w", rule, err)
+		}
+
+		for _, day := range days {
+			week[day] = ranges
+			named[day] = true
+		}
+	}
+
+	return week, named, nil
+}
+
+// parseDaySelector splits a rule into the days it applies to and the time
+// part. Rules without a day selector apply to every day.
+func parseDaySelector(rule string) ([]int, string, bool, error) {
+	fields := strings.SplitN(rule, " ", 2)
+	selector := fields[0]
+
+	if !startsWithLetter(selector) {
+		return []int{0, 1, 2, 3, 4, 5, 6}, rule, false, nil
+	}
+
+	rest := ""
+	if len(fields) == 2 {
+		rest = strings.TrimSpace(fields[1])
+	}
+
+	if rest == "" && (selector == "off" || selector == "closed") {
+		return []int{0, 1, 2, 3, 4, 5, 6}, selector, false, nil
+	}
+
+	seen := make(map[int]bool)
+	var days []int
+	holidayOnly := true
+
+	for _, part := range strings.Split(selector, ",") {
+		if part == "PH" || part == "SH" {
+			continue
+		}
+		holidayOnly = false
+
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return nil, "", false, fmt.Errorf("unsupported day selector %q", part)
+		}
+
+		first, ok := osmWeekdays[bounds[0]]
+		if !ok {
+			return nil, "", false, fmt.Errorf("unsupported day selector %q", part)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = osmWeekdays[bounds[1]]; !ok {
+				return nil, "", false, fmt.Errorf("unsupported day selector %q", part)
+			}
+		}
+
+		// Ranges follow the Monday-first OSM week and may wrap, e.g. Fr-Mo.
+		for day := first; ; day = (day + 1) % 7 {
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return days, rest, holidayOnly, nil
+}
+
+func parseTimeRanges(s string) ([]domain.TimeRange, error) {
+	if s == "off" || s == "closed" {
+		return nil, nil
+	}
+
+	var ranges []domain.TimeRange
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.Split(strings.TrimSpace(part), "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("unsupported time range %q", part)
+		}
+
+		from, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if to <= from {
+			to += 24 * 60
+		}
+
+		ranges = append(ranges, domain.TimeRange{From: from, To: to})
+	}
+
+	return ranges, nil
+}
+
+func parseClock(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("unsupported time %q", s)
+	}
+
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 48 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("unsupported time %q", s)
+	}
+
+	return h*60 + m, nil
+}
+
+func startsWithLetter(s string) bool {
+	return s != "" && (s[0] >= 'A' && s[0] <= 'Z' || s[0] >= 'a' && s[0] <= 'z')
+}
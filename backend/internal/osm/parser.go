@@ -1,14 +1,24 @@
 package osm
 
 import (
+	"regexp"
+	"strings"
+
+	"github.com/dremotha/mapbot/internal/categories"
 	"github.com/dremotha/mapbot/internal/domain"
 	pkgosm "github.com/dremotha/mapbot/pkg/osm"
 )
 
-type Parser struct{}
+var wikidataID = regexp.MustCompile(`^Q[1-9][0-9]*$`)
+
+type Parser struct {
+	categories *categories.Mapping
+}
 
-func NewParser() *Parser {
-	return &Parser{}
+// NewParser creates a parser that assigns categories by the rules of
+// configs/categories.yaml.
+func NewParser(mapping *categories.Mapping) *Parser {
+	return &Parser{categories: mapping}
 }
 
 func (p *Parser) ParseElements(elements []pkgosm.Element) []domain.POI {
@@ -48,9 +58,15 @@ func (p *Parser) parseElement(el pkgosm.Element) *domain.POI {
 		Subcategory:      subcategory,
 		Tags:             p.getTags(el),
 		HistoricalPeriod: p.getHistoricalPeriod(el),
+		OpeningHours:     p.getOpeningHours(el),
 		Source:           "osm",
 		OsmID:            &el.ID,
+		OsmType:          el.Type,
+		Wikidata:         p.getWikidata(el),
+		Wikipedia:        el.Tags["wikipedia"],
 	}
+	poi.YearBuilt, poi.YearBuiltPrecision = p.getYear(el, "start_date", "year_of_construction")
+	poi.YearDestroyed, poi.YearDestroyedPrecision = p.getYear(el, "end_date", "demolished:date")
 
 	return poi
 }
@@ -120,64 +136,16 @@ func (p *Parser) getAddress(el pkgosm.Element) string {
 }
 
 func (p *Parser) getCategory(el pkgosm.Element) (string, string) {
-	if historic, ok := el.Tags["historic"]; ok {
-		switch historic {
-		case "church", "cathedral", "chapel":
-			return "religious", historic
-		case "monastery":
-			return "religious", "monastery"
-		case "memorial", "monument":
-			return "military", "memorial"
-		case "battlefield":
-			return "military", "battlefield"
-		case "fort", "castle", "fortress":
-			return "military", "fortress"
-		case "manor":
-			return "architecture", "manor"
-		case "palace":
-			return "architecture", "palace"
-		case "ruins":
-			return "architecture", "ruins"
-		case "tower":
-			return "architecture", "tower"
-		default:
-			return "architecture", ""
-		}
-	}
-
-	if building, ok := el.Tags["building"]; ok {
-		switch building {
-		case "church":
-			return "religious", "church"
-		case "cathedral":
-			return "religious", "cathedral"
-		case "chapel":
-			return "religious", "chapel"
-		}
-	}
-
-	if amenity, ok := el.Tags["amenity"]; ok {
-		if amenity == "place_of_worship" {
-			if el.Tags["building"] == "cathedral" {
-				return "religious", "cathedral"
-			}
-			return "religious", "church"
-		}
-		if amenity == "monastery" {
-			return "religious", "monastery"
-		}
-	}
+	return p.categories.Match(el.Tags)
+}
 
-	if military, ok := el.Tags["military"]; ok {
-		switch military {
-		case "bunker":
-			return "military", "bunker"
-		default:
-			return "military", ""
-		}
+// getWikidata returns the Wikidata item ID, ignoring malformed values.
+func (p *Parser) getWikidata(el pkgosm.Element) string {
+	id := strings.TrimSpace(el.Tags["wikidata"])
+	if !wikidataID.MatchString(id) {
+		return ""
 	}
-
-	return "architecture", ""
+	return id
 }
 
 func (p *Parser) getTags(el pkgosm.Element) []string {
@@ -197,6 +165,21 @@ func (p *Parser) getTags(el pkgosm.Element) []string {
 	return tags
 }
 
+// getOpeningHours keeps the raw tag even when it cannot be parsed, so the
+// schedule is shown to users and reported as unknown to routing.
+func (p *Parser) getOpeningHours(el pkgosm.Element) *domain.OpeningHours {
+	raw, ok := el.Tags["opening_hours"]
+	if !ok || raw == "" {
+		return nil
+	}
+
+	hours, err := ParseOpeningHours(raw)
+	if err != nil {
+		return &domain.OpeningHours{Raw: raw, Unparsed: true}
+	}
+	return hours
+}
+
 func (p *Parser) getHistoricalPeriod(el pkgosm.Element) string {
 	if period, ok := el.Tags["start_date"]; ok {
 		return period
@@ -206,3 +189,13 @@ func (p *Parser) getHistoricalPeriod(el pkgosm.Element) string {
 	}
 	return ""
 }
+
+// getYear parses the first of the date tags that holds a supported value.
+func (p *Parser) getYear(el pkgosm.Element, keys ...string) (*int, domain.DatePrecision) {
+	for _, key := range keys {
+		if year, precision, ok := ParseDate(el.Tags[key]); ok {
+			return &year, precision
+		}
+	}
+	return nil, ""
+}
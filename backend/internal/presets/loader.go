@@ -0,0 +1,123 @@
+package presets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// File is the editor-facing format of configs/preset_routes.yaml.
+type File struct {
+	PresetRoutes []Definition `yaml:"preset_routes" json:"preset_routes"`
+}
+
+type Definition struct {
+	ID            string   `yaml:"id" json:"id"`
+	Name          string   `yaml:"name" json:"name"`
+	Description   string   `yaml:"description" json:"description"`
+	Category      string   `yaml:"category" json:"category"`
+	Mode          string   `yaml:"mode" json:"mode"`
+	Difficulty    string   `yaml:"difficulty" json:"difficulty"`
+	DurationHours float64  `yaml:"duration_hours" json:"duration_hours"`
+	Image         string   `yaml:"image" json:"image"`
+	Tags          []string `yaml:"tags" json:"tags"`
+	Stops         []Stop   `yaml:"stops" json:"stops"`
+}
+
+// Stop references a POI by poi_id or osm_id, or by name near lat/lng.
+// Coordinates are also used as a fallback when no POI matches.
+type Stop struct {
+	POIID string  `yaml:"poi_id" json:"poi_id"`
+	OsmID int64   `yaml:"osm_id" json:"osm_id"`
+	Name  string  `yaml:"name" json:"name"`
+	Note  string  `yaml:"note" json:"note"`
+	Lat   float64 `yaml:"lat" json:"lat"`
+	Lng   float64 `yaml:"lng" json:"lng"`
+}
+
+func (s Stop) HasLocation() bool {
+	return s.Lat != 0 || s.Lng != 0
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+var difficulties = map[string]bool{"easy": true, "medium": true, "hard": true}
+
+var modes = map[string]bool{"walking": true, "driving": true, "cycling": true}
+
+// Load reads preset route definitions from a YAML or JSON file.
+func Load(path string) ([]Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read presets: %w", err)
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &file)
+	default:
+		return nil, fmt.Errorf("unsupported presets file: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse presets: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.PresetRoutes {
+		def := &file.PresetRoutes[i]
+		applyDefaults(def)
+
+		if err := validate(def); err != nil {
+			return nil, fmt.Errorf("preset %d (%s): %w", i, def.ID, err)
+		}
+		if seen[def.ID] {
+			return nil, fmt.Errorf("preset %s: duplicate id", def.ID)
+		}
+		seen[def.ID] = true
+	}
+
+	return file.PresetRoutes, nil
+}
+
+func applyDefaults(def *Definition) {
+	if def.Mode == "" {
+		def.Mode = "walking"
+	}
+	if def.Difficulty == "" {
+		def.Difficulty = "easy"
+	}
+}
+
+func validate(def *Definition) error {
+	if !idPattern.MatchString(def.ID) {
+		return fmt.Errorf("id must be a lowercase slug")
+	}
+	if def.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if !modes[def.Mode] {
+		return fmt.Errorf("unknown mode %q", def.Mode)
+	}
+	if !difficulties[def.Difficulty] {
+		return fmt.Errorf("unknown difficulty %q", def.Difficulty)
+	}
+	if len(def.Stops) < 2 {
+		return fmt.Errorf("at least 2 stops required")
+	}
+
+	for i, stop := range def.Stops {
+		if stop.POIID == "" && stop.OsmID == 0 && (stop.Name == "" || !stop.HasLocation()) {
+			return fmt.Errorf("stop %d: poi_id, osm_id or name with lat/lng required", i+1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package regions loads the import regions from configs/regions.yaml.
+package regions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go.yaml.in/yaml/v2"
+
+	pkgosm "github.com/dremotha/mapbot/pkg/osm"
+)
+
+// File is the format of configs/regions.yaml.
+type File struct {
+	Regions []Definition `yaml:"regions"`
+}
+
+// Definition describes a region by bbox (south, west, north, east) or by
+// a GeoJSON file with a Polygon or MultiPolygon, relative to the YAML file.
+type Definition struct {
+	ID      string    `yaml:"id"`
+	Name    string    `yaml:"name"`
+	BBox    []float64 `yaml:"bbox"`
+	GeoJSON string    `yaml:"geojson"`
+}
+
+// Region is an import area. POIs imported for it are tagged with its ID.
+type Region struct {
+	ID   string
+	Name string
+	// BBox bounds the region; Polygon, if set, refines it.
+	BBox    pkgosm.BBox
+	Polygon pkgosm.Polygon
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Load reads region definitions from a YAML file.
+func Load(path string) ([]Region, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read regions: %w", err)
+	}
+
+	var file File
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parse regions: %w", err)
+	}
+
+	regions := make([]Region, 0, len(file.Regions))
+	seen := make(map[string]bool)
+
+	for i, def := range file.Regions {
+		if !idPattern.MatchString(def.ID) {
+			return nil, fmt.Errorf("region %d: id must be a lowercase slug", i+1)
+		}
+		if seen[def.ID] {
+			return nil, fmt.Errorf("region %s: duplicate id", def.ID)
+		}
+		seen[def.ID] = true
+
+		region := Region{ID: def.ID, Name: def.Name}
+
+		switch {
+		case def.GeoJSON != "" && len(def.BBox) > 0:
+			return nil, fmt.Errorf("region %s: bbox and geojson are mutually exclusive", def.ID)
+		case def.GeoJSON != "":
+			geoPath := def.GeoJSON
+			if !filepath.IsAbs(geoPath) {
+				geoPath = filepath.Join(filepath.Dir(path), geoPath)
+			}
+			region.Polygon, err = LoadGeoJSON(geoPath)
+			if err != nil {
+				return nil, fmt.Errorf("region %s: %w", def.ID, err)
+			}
+			region.BBox = region.Polygon.Bounds()
+		case len(def.BBox) == 4:
+			region.BBox = pkgosm.BBox{South: def.BBox[0], West: def.BBox[1], North: def.BBox[2], East: def.BBox[3]}
+		default:
+			return nil, fmt.Errorf("region %s: bbox [south, west, north, east] or geojson required", def.ID)
+		}
+
+		if err := region.BBox.Validate(); err != nil {
+			return nil, fmt.Errorf("region %s: %w", def.ID, err)
+		}
+
+		regions = append(regions, region)
+	}
+
+	return regions, nil
+}
+
+// Find returns the region with the given ID.
+func Find(regions []Region, id string) (Region, bool) {
+	for _, r := range regions {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+func (r Region) Contains(lat, lon float64) bool {
+	if !r.BBox.Contains(lat, lon) {
+		return false
+	}
+	return r.Polygon == nil || r.Polygon.Contains(lat, lon)
+}
+
+// WKT returns the region area as EWKT for PostGIS.
+func (r Region) WKT() string {
+	if r.Polygon != nil {
+		return r.Polygon.WKT()
+	}
+	return r.BBox.WKT()
+}
+
+// Tiles splits the region into boxes at most size degrees on each side,
+// dropping those outside the polygon.
+func (r Region) Tiles(size float64) []pkgosm.BBox {
+	tiles := r.BBox.Split(size)
+	if r.Polygon == nil {
+		return tiles
+	}
+
+	kept := tiles[:0]
+	for _, t := range tiles {
+		if r.Polygon.Intersects(t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// LoadGeoJSON reads a Polygon or MultiPolygon geometry, Feature or
+// FeatureCollection; all polygons found are merged.
+func LoadGeoJSON(path string) (pkgosm.Polygon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read geojson: %w", err)
+	}
+
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+
+	var polygon pkgosm.Polygon
+	if err := collectPolygons(&doc, &polygon); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	if len(polygon) == 0 {
+		return nil, fmt.Errorf("geojson %s has no polygons", path)
+	}
+
+	return polygon, nil
+}
+
+func collectPolygons(g *geoJSON, out *pkgosm.Polygon) error {
+	switch g.Type {
+	case "FeatureCollection":
+		for i := range g.Features {
+			if err := collectPolygons(&g.Features[i], out); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g.Geometry != nil {
+			return collectPolygons(g.Geometry, out)
+		}
+	case "Polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return err
+		}
+		*out = append(*out, toRings(rings))
+	case "MultiPolygon":
+		var polys [][][][2]float64
+		if err := json.Unmarshal(g.Coordinates, &polys); err != nil {
+			return err
+		}
+		for _, rings := range polys {
+			*out = append(*out, toRings(rings))
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+	return nil
+}
+
+// toRings converts GeoJSON [lon, lat] positions.
+func toRings(rings [][][2]float64) [][]pkgosm.Point {
+	result := make([][]pkgosm.Point, len(rings))
+	for i, ring := range rings {
+		points := make([]pkgosm.Point, len(ring))
+		for j, pos := range ring {
+			points[j] = pkgosm.Point{Lat: pos[1], Lon: pos[0]}
+		}
+		result[i] = points
+	}
+	return result
+}
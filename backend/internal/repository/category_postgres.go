@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// CategoryRepository keeps the categories table in sync with
+// configs/categories.yaml.
+type CategoryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCategoryRepository(pool *pgxpool.Pool) *CategoryRepository {
+	return &CategoryRepository{pool: pool}
+}
+
+// Sync makes the table equal to categories, which must list every parent
+// before its children, and returns the IDs of removed categories.
+func (r *CategoryRepository) Sync(ctx context.Context, categories []domain.Category) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]string, len(categories))
+	for i, c := range categories {
+		ids[i] = c.ID
+
+		osmTags, err := json.Marshal(c.OsmTags)
+		if err != nil {
+			return nil, fmt.Errorf("marshal osm tags: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO categories (id, name_ru, name_en, parent_id, icon, osm_tags)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6)
+			ON CONFLICT (id) DO UPDATE SET
+				name_ru = EXCLUDED.name_ru,
+				name_en = EXCLUDED.name_en,
+				parent_id = EXCLUDED.parent_id,
+				icon = EXCLUDED.icon,
+				osm_tags = EXCLUDED.osm_tags`,
+			c.ID, c.NameRu, c.NameEn, c.ParentID, c.Icon, osmTags,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("upsert category %s: %w", c.ID, err)
+		}
+	}
+
+	// Children first: parent_id references categories
+	var removed []string
+	for _, query := range []string{
+		`DELETE FROM categories WHERE NOT (id = ANY($1)) AND parent_id IS NOT NULL RETURNING id`,
+		`DELETE FROM categories WHERE NOT (id = ANY($1)) RETURNING id`,
+	} {
+		rows, err := tx.Query(ctx, query, ids)
+		if err != nil {
+			return nil, fmt.Errorf("delete categories: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan category id: %w", err)
+			}
+			removed = append(removed, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("delete categories: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit categories: %w", err)
+	}
+	return removed, nil
+}
+
+// Missing returns the IDs that are not in the table.
+func (r *CategoryRepository) Missing(ctx context.Context, ids []string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id FROM unnest($1::text[]) AS t(id)
+		WHERE NOT EXISTS (SELECT 1 FROM categories c WHERE c.id = t.id)
+		ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan category id: %w", err)
+		}
+		missing = append(missing, id)
+	}
+	return missing, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+var ErrFactNotFound = errors.New("historical fact not found")
+
+type HistoricalFactRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewHistoricalFactRepository(pool *pgxpool.Pool) *HistoricalFactRepository {
+	return &HistoricalFactRepository{pool: pool}
+}
+
+const factSelectColumns = `
+			id, poi_id, COALESCE(fact_type, ''), COALESCE(title, ''), COALESCE(content, ''),
+			year_from, year_to, COALESCE(source_url, ''), COALESCE(source, '')`
+
+// factOrder puts dated facts first, in chronological order.
+const factOrder = ` ORDER BY year_from NULLS LAST, year_to NULLS LAST, created_at, id`
+
+func (r *HistoricalFactRepository) Create(ctx context.Context, fact *domain.HistoricalFact) error {
+	if fact.ID == uuid.Nil {
+		fact.ID = uuid.New()
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO historical_facts (id, poi_id, fact_type, title, content, year_from, year_to, source_url, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))`,
+		fact.ID, fact.POIID, fact.FactType, fact.Title, fact.Content,
+		fact.YearFrom, fact.YearTo, fact.SourceURL, fact.Source,
+	)
+	if err != nil {
+		return fmt.Errorf("insert historical fact: %w", err)
+	}
+	return nil
+}
+
+func (r *HistoricalFactRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.HistoricalFact, error) {
+	query := `SELECT` + factSelectColumns + `
+		FROM historical_facts
+		WHERE id = $1`
+
+	fact, err := scanFact(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFactNotFound
+	}
+	return fact, err
+}
+
+// ListByPOI returns the facts of a POI in chronological order; the ID of a
+// merged duplicate resolves to the POI it was merged into, which holds its
+// facts.
+func (r *HistoricalFactRepository) ListByPOI(ctx context.Context, poiID uuid.UUID) ([]domain.HistoricalFact, error) {
+	query := `SELECT` + factSelectColumns + `
+		FROM historical_facts
+		WHERE poi_id = COALESCE((SELECT merged_into FROM poi WHERE id = $1), $1)` + factOrder
+
+	return r.list(ctx, query, poiID)
+}
+
+// ListByPOIs returns the facts of several POIs.
+func (r *HistoricalFactRepository) ListByPOIs(ctx context.Context, poiIDs []uuid.UUID) ([]domain.HistoricalFact, error) {
+	query := `SELECT` + factSelectColumns + `
+		FROM historical_facts
+		WHERE poi_id = ANY($1)` + factOrder
+
+	return r.list(ctx, query, poiIDs)
+}
+
+func (r *HistoricalFactRepository) Update(ctx context.Context, fact *domain.HistoricalFact) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE historical_facts SET
+			fact_type = $2, title = $3, content = $4,
+			year_from = $5, year_to = $6, source_url = NULLIF($7, ''), source = NULLIF($8, '')
+		WHERE id = $1`,
+		fact.ID, fact.FactType, fact.Title, fact.Content,
+		fact.YearFrom, fact.YearTo, fact.SourceURL, fact.Source,
+	)
+	if err != nil {
+		return fmt.Errorf("update historical fact: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFactNotFound
+	}
+
+	return nil
+}
+
+func (r *HistoricalFactRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM historical_facts WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete historical fact: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFactNotFound
+	}
+
+	return nil
+}
+
+func (r *HistoricalFactRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.HistoricalFact, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query historical facts: %w", err)
+	}
+	defer rows.Close()
+
+	facts := make([]domain.HistoricalFact, 0)
+	for rows.Next() {
+		fact, err := scanFact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan historical fact: %w", err)
+		}
+		facts = append(facts, *fact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return facts, nil
+}
+
+func scanFact(row pgx.Row) (*domain.HistoricalFact, error) {
+	var fact domain.HistoricalFact
+	err := row.Scan(
+		&fact.ID, &fact.POIID, &fact.FactType, &fact.Title, &fact.Content,
+		&fact.YearFrom, &fact.YearTo, &fact.SourceURL, &fact.Source,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &fact, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TileCheckpoint is a tile completed by an earlier, interrupted run of the
+// same import.
+type TileCheckpoint struct {
+	Tile        int
+	BBox        string
+	Seen        []OSMRef
+	CompletedAt time.Time
+}
+
+// ImportCheckpointRepository stores per-tile progress of Overpass imports.
+type ImportCheckpointRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImportCheckpointRepository(pool *pgxpool.Pool) *ImportCheckpointRepository {
+	return &ImportCheckpointRepository{pool: pool}
+}
+
+// Completed returns the tiles already imported for runKey.
+func (r *ImportCheckpointRepository) Completed(ctx context.Context, runKey string) ([]TileCheckpoint, error) {
+	query := `
+		SELECT tile, bbox, osm_types, osm_ids, completed_at
+		FROM osm_import_tiles
+		WHERE run_key = $1
+		ORDER BY tile`
+
+	rows, err := r.pool.Query(ctx, query, runKey)
+	if err != nil {
+		return nil, fmt.Errorf("query import checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []TileCheckpoint
+	for rows.Next() {
+		var cp TileCheckpoint
+		var types []string
+		var osmIDs []int64
+
+		if err := rows.Scan(&cp.Tile, &cp.BBox, &types, &osmIDs, &cp.CompletedAt); err != nil {
+			return nil, fmt.Errorf("scan import checkpoint: %w", err)
+		}
+		if len(types) != len(osmIDs) {
+			return nil, fmt.Errorf("import checkpoint %d: osm_types and osm_ids differ in length", cp.Tile)
+		}
+
+		cp.Seen = make([]OSMRef, len(types))
+		for i := range types {
+			cp.Seen[i] = OSMRef{Type: types[i], ID: osmIDs[i]}
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+// Complete records that the tile was imported with the given OSM objects.
+func (r *ImportCheckpointRepository) Complete(ctx context.Context, runKey string, tile int, bbox string, seen []OSMRef) error {
+	types, osmIDs := splitOSMRefs(seen)
+
+	query := `
+		INSERT INTO osm_import_tiles (run_key, tile, bbox, osm_types, osm_ids, completed_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (run_key, tile) DO UPDATE SET
+			bbox = EXCLUDED.bbox,
+			osm_types = EXCLUDED.osm_types,
+			osm_ids = EXCLUDED.osm_ids,
+			completed_at = NOW()`
+
+	if _, err := r.pool.Exec(ctx, query, runKey, tile, bbox, types, osmIDs); err != nil {
+		return fmt.Errorf("save import checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the progress of runKey once the import has finished.
+func (r *ImportCheckpointRepository) Clear(ctx context.Context, runKey string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM osm_import_tiles WHERE run_key = $1`, runKey); err != nil {
+		return fmt.Errorf("clear import checkpoints: %w", err)
+	}
+	return nil
+}
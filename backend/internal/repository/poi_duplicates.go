@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// DuplicateCandidate is a pair of visible POIs of the same category close
+// to each other. Whether they are the same object is decided by the caller.
+type DuplicateCandidate struct {
+	A, B      uuid.UUID
+	DistanceM float64
+}
+
+// DuplicateCandidates returns pairs of POIs of the region at most
+// maxDistanceM meters apart.
+func (r *POIRepository) DuplicateCandidates(ctx context.Context, region string, maxDistanceM float64) ([]DuplicateCandidate, error) {
+	query := `
+		SELECT a.id, b.id, ST_Distance(a.location, b.location)
+		FROM poi a
+		JOIN poi b ON a.id < b.id
+			AND a.category = b.category
+			AND ST_DWithin(a.location, b.location, $2)
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND a.region IS NOT DISTINCT FROM NULLIF($1, '')
+			AND b.region IS NOT DISTINCT FROM NULLIF($1, '')`
+
+	rows, err := r.pool.Query(ctx, query, region, maxDistanceM)
+	if err != nil {
+		return nil, fmt.Errorf("query duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []DuplicateCandidate
+	for rows.Next() {
+		var p DuplicateCandidate
+		if err := rows.Scan(&p.A, &p.B, &p.DistanceM); err != nil {
+			return nil, fmt.Errorf("scan duplicate candidate: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// GetByIDs returns the visible POIs among ids, in no particular order.
+func (r *POIRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.POI, error) {
+	query := `SELECT` + poiSelectColumns + `
+		FROM poi
+		WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query pois: %w", err)
+	}
+	defer rows.Close()
+
+	pois := make([]domain.POI, 0, len(ids))
+	for rows.Next() {
+		poi, err := scanPOI(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan poi: %w", err)
+		}
+		pois = append(pois, *poi)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return pois, nil
+}
+
+// MergeDuplicates hides the duplicates behind the canonical POI, which
+// gets the given aliases and their tags, and moves facts and preset stops
+// over to it. The duplicates keep their OSM identity, so imports still
+// match them.
+func (r *POIRepository) MergeDuplicates(ctx context.Context, canonical uuid.UUID, aliases []string, duplicates []uuid.UUID) error {
+	aliasesJSON, _ := json.Marshal(aliases)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	steps := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"set aliases", `
+			UPDATE poi SET aliases = $2, updated_at = NOW()
+			WHERE id = $1`, []interface{}{canonical, aliasesJSON}},
+		// Earlier duplicates of a POI that is now merged itself
+		{"repoint merged pois", `
+			UPDATE poi SET merged_into = $1
+			WHERE merged_into = ANY($2)`, []interface{}{canonical, duplicates}},
+		{"merge pois", `
+			UPDATE poi SET merged_into = $1, deleted_at = NOW(), updated_at = NOW()
+			WHERE id = ANY($2) AND deleted_at IS NULL`, []interface{}{canonical, duplicates}},
+		{"merge tags", `
+			UPDATE poi SET tags = poi_merged_tags(id, tags)
+			WHERE id = $1`, []interface{}{canonical}},
+		{"move historical facts", `
+			UPDATE historical_facts SET poi_id = $1
+			WHERE poi_id = ANY($2)`, []interface{}{canonical, duplicates}},
+		{"move preset stops", `
+			UPDATE preset_route_stops SET poi_id = $1
+			WHERE poi_id = ANY($2)`, []interface{}{canonical, duplicates}},
+	}
+
+	for _, step := range steps {
+		if _, err := tx.Exec(ctx, step.query, step.args...); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit merge: %w", err)
+	}
+
+	r.notifyChanged(ctx, append([]uuid.UUID{canonical}, duplicates...))
+	return nil
+}
+
+// ReleaseOrphanDuplicates makes duplicates visible again when the POI they
+// were merged into has been removed, and returns their IDs.
+func (r *POIRepository) ReleaseOrphanDuplicates(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+		UPDATE poi d SET merged_into = NULL, deleted_at = NULL, updated_at = NOW()
+		FROM poi c
+		WHERE d.merged_into = c.id AND c.deleted_at IS NOT NULL AND c.merged_into IS NULL
+		RETURNING d.id`
+
+	return r.updateIDs(ctx, query)
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// OSMRef identifies an OSM object. Node, way and relation IDs overlap, so
+// the element type is part of the key.
+type OSMRef struct {
+	Type string
+	ID   int64
+}
+
+// UpsertStats counts the outcome of an OSM import.
+type UpsertStats struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Removed   int
+}
+
+func (s *UpsertStats) Add(other UpsertStats) {
+	s.Inserted += other.Inserted
+	s.Updated += other.Updated
+	s.Unchanged += other.Unchanged
+	s.Removed += other.Removed
+}
+
+// claimLegacyQuery assigns osm_type to rows imported before the column
+// existed, so that the upsert matches them instead of inserting copies.
+const claimLegacyQuery = `
+	UPDATE poi p SET osm_type = c.osm_type
+	FROM (
+		SELECT DISTINCT ON (l.source, l.osm_id) l.id, r.osm_type
+		FROM poi l
+		JOIN unnest($1::text[], $2::text[], $3::bigint[]) AS r(source, osm_type, osm_id)
+			ON r.source = l.source AND r.osm_id = l.osm_id
+		WHERE l.osm_type IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM poi t
+				WHERE t.source = r.source AND t.osm_type = r.osm_type AND t.osm_id = r.osm_id
+			)
+		ORDER BY l.source, l.osm_id, l.created_at
+	) c
+	WHERE p.id = c.id`
+
+// upsertOSMQuery inserts a POI or updates the OSM-derived fields of the
+// existing row, keeping its ID. Rows whose fields did not change are left
+// alone and return nothing; xmax = 0 distinguishes inserts from updates.
+// Merged duplicates are kept up to date but stay hidden, and the tags of a
+// POI with merged duplicates include theirs. The Wikidata and Wikipedia
+// enrichment is kept and returned with the aliases, so changed POIs are
+// indexed with it. A POI in the overlap of two regions keeps the region
+// it was first imported with, so region-scoped deletes see the same set on
+// every run.
+const upsertOSMQuery = `
+	INSERT INTO poi (
+		id, name, description, short_description,
+		location, address, category, subcategory, tags,
+		historical_period, year_built, year_destroyed,
+		source, osm_id, osm_type, popularity_score, opening_hours, region,
+		year_built_precision, year_destroyed_precision, wikidata, wikipedia
+	) VALUES (
+		$1, $2, $3, $4,
+		ST_SetSRID(ST_MakePoint($5, $6), 4326)::geography,
+		$7, $8, $9, $10,
+		$11, $12, $13,
+		$14, $15, $16, $17, $18, NULLIF($19, ''),
+		NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, '')
+	)
+	ON CONFLICT (source, osm_type, osm_id) DO UPDATE SET
+		name = EXCLUDED.name,
+		description = EXCLUDED.description,
+		short_description = EXCLUDED.short_description,
+		location = EXCLUDED.location,
+		address = EXCLUDED.address,
+		category = EXCLUDED.category,
+		subcategory = EXCLUDED.subcategory,
+		tags = poi_merged_tags(poi.id, EXCLUDED.tags),
+		historical_period = EXCLUDED.historical_period,
+		year_built = EXCLUDED.year_built,
+		year_destroyed = EXCLUDED.year_destroyed,
+		year_built_precision = EXCLUDED.year_built_precision,
+		year_destroyed_precision = EXCLUDED.year_destroyed_precision,
+		wikidata = EXCLUDED.wikidata,
+		wikipedia = EXCLUDED.wikipedia,
+		opening_hours = EXCLUDED.opening_hours,
+		region = COALESCE(poi.region, EXCLUDED.region),
+		deleted_at = CASE WHEN poi.merged_into IS NULL THEN NULL ELSE poi.deleted_at END,
+		updated_at = NOW()
+	WHERE (poi.deleted_at IS NOT NULL AND poi.merged_into IS NULL)
+		OR NOT ST_Equals(poi.location::geometry, EXCLUDED.location::geometry)
+		OR (poi.name, poi.description, poi.short_description, poi.address,
+			poi.category, poi.subcategory, poi.tags, poi.historical_period,
+			poi.year_built, poi.year_destroyed, poi.opening_hours, poi.region,
+			poi.year_built_precision, poi.year_destroyed_precision, poi.wikidata, poi.wikipedia)
+		IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.short_description, EXCLUDED.address,
+			EXCLUDED.category, EXCLUDED.subcategory, poi_merged_tags(poi.id, EXCLUDED.tags), EXCLUDED.historical_period,
+			EXCLUDED.year_built, EXCLUDED.year_destroyed, EXCLUDED.opening_hours, COALESCE(poi.region, EXCLUDED.region),
+			EXCLUDED.year_built_precision, EXCLUDED.year_destroyed_precision, EXCLUDED.wikidata, EXCLUDED.wikipedia)
+	RETURNING id, (xmax = 0) AS inserted, (merged_into IS NOT NULL) AS merged, COALESCE(aliases, '[]'),
+		COALESCE(NULLIF(description, ''), wiki_description, ''), COALESCE(year_built, wiki_year_built),
+		CASE WHEN year_built IS NULL THEN COALESCE(wiki_year_built_precision, '') ELSE COALESCE(year_built_precision, '') END,
+		COALESCE(heritage, ''), COALESCE(image, '')`
+
+// UpsertOSM writes POIs parsed from OSM, matching existing rows by
+// (source, osm_type, osm_id). It returns the inserted and updated POIs with
+// their stored IDs; unchanged POIs are only counted.
+func (r *POIRepository) UpsertOSM(ctx context.Context, pois []domain.POI) ([]domain.POI, UpsertStats, error) {
+	var stats UpsertStats
+	if len(pois) == 0 {
+		return nil, stats, nil
+	}
+
+	sources := make([]string, len(pois))
+	types := make([]string, len(pois))
+	osmIDs := make([]int64, len(pois))
+	for i, poi := range pois {
+		if poi.OsmID == nil || poi.OsmType == "" {
+			return nil, stats, fmt.Errorf("poi %q has no OSM reference", poi.Name)
+		}
+		sources[i], types[i], osmIDs[i] = poi.Source, poi.OsmType, *poi.OsmID
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(claimLegacyQuery, sources, types, osmIDs)
+
+	for i := range pois {
+		poi := &pois[i]
+		tags, _ := json.Marshal(poi.Tags)
+
+		batch.Queue(upsertOSMQuery,
+			uuid.New(), poi.Name, poi.Description, poi.ShortDescription,
+			poi.Lng, poi.Lat,
+			poi.Address, poi.Category, poi.Subcategory, tags,
+			poi.HistoricalPeriod, poi.YearBuilt, poi.YearDestroyed,
+			poi.Source, poi.OsmID, poi.OsmType, poi.PopularityScore, marshalOpeningHours(poi.OpeningHours), poi.Region,
+			poi.YearBuiltPrecision, poi.YearDestroyedPrecision, poi.Wikidata, poi.Wikipedia,
+		)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	if _, err := br.Exec(); err != nil {
+		return nil, stats, fmt.Errorf("claim legacy pois: %w", err)
+	}
+
+	changed := make([]domain.POI, 0, len(pois))
+	ids := make([]uuid.UUID, 0, len(pois))
+
+	for i := range pois {
+		var id uuid.UUID
+		var inserted, merged bool
+		var aliases []byte
+		poi := pois[i]
+
+		err := br.QueryRow().Scan(&id, &inserted, &merged, &aliases,
+			&poi.Description, &poi.YearBuilt, &poi.YearBuiltPrecision, &poi.Heritage, &poi.Image)
+		if errors.Is(err, pgx.ErrNoRows) {
+			stats.Unchanged++
+			continue
+		}
+		if err != nil {
+			return nil, stats, fmt.Errorf("upsert poi: %w", err)
+		}
+
+		if inserted {
+			stats.Inserted++
+		} else {
+			stats.Updated++
+		}
+		if merged {
+			continue
+		}
+
+		poi.ID = id
+		json.Unmarshal(aliases, &poi.Aliases)
+		changed = append(changed, poi)
+		ids = append(ids, id)
+	}
+
+	if len(ids) > 0 {
+		r.notifyChanged(ctx, ids)
+	}
+
+	return changed, stats, nil
+}
+
+// DeleteOSM soft-deletes the POIs of the given OSM objects in region and
+// returns their IDs. Objects without a POI are ignored. Merged duplicates
+// are detached, so that they are not released when their canonical POI
+// is removed.
+func (r *POIRepository) DeleteOSM(ctx context.Context, region string, refs []OSMRef) ([]uuid.UUID, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	types, osmIDs := splitOSMRefs(refs)
+
+	query := `
+		UPDATE poi p SET deleted_at = COALESCE(p.deleted_at, NOW()), merged_into = NULL, updated_at = NOW()
+		FROM unnest($1::text[], $2::bigint[]) AS d(osm_type, osm_id)
+		WHERE p.source = 'osm'
+			AND (p.deleted_at IS NULL OR p.merged_into IS NOT NULL)
+			AND p.osm_type = d.osm_type AND p.osm_id = d.osm_id
+			AND p.region IS NOT DISTINCT FROM NULLIF($3, '')
+		RETURNING p.id`
+
+	return r.updateIDs(ctx, query, types, osmIDs, region)
+}
+
+// LocationsByOSMRefs returns the stored locations of the given OSM objects,
+// for diffs that change the tags of a way without its nodes.
+func (r *POIRepository) LocationsByOSMRefs(ctx context.Context, refs []OSMRef) (map[OSMRef]domain.Coordinate, error) {
+	locations := make(map[OSMRef]domain.Coordinate)
+	if len(refs) == 0 {
+		return locations, nil
+	}
+
+	types, osmIDs := splitOSMRefs(refs)
+
+	query := `
+		SELECT p.osm_type, p.osm_id, ST_Y(p.location::geometry), ST_X(p.location::geometry)
+		FROM poi p
+		JOIN unnest($1::text[], $2::bigint[]) AS l(osm_type, osm_id)
+			ON p.osm_type = l.osm_type AND p.osm_id = l.osm_id
+		WHERE p.source = 'osm' AND (p.deleted_at IS NULL OR p.merged_into IS NOT NULL)`
+
+	rows, err := r.pool.Query(ctx, query, types, osmIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query poi locations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref OSMRef
+		var c domain.Coordinate
+		if err := rows.Scan(&ref.Type, &ref.ID, &c.Lat, &c.Lng); err != nil {
+			return nil, fmt.Errorf("scan poi location: %w", err)
+		}
+		locations[ref] = c
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return locations, nil
+}
+
+// DeleteMissingOSM soft-deletes POIs of source osm inside the area (WKT,
+// EPSG:4326) that are not among seen, i.e. have disappeared from OSM. It
+// returns the IDs of the deleted POIs. Like DeleteOSM, it detaches merged
+// duplicates.
+func (r *POIRepository) DeleteMissingOSM(ctx context.Context, areaWKT string, seen []OSMRef) ([]uuid.UUID, error) {
+	types, osmIDs := splitOSMRefs(seen)
+
+	query := `
+		UPDATE poi p SET deleted_at = COALESCE(p.deleted_at, NOW()), merged_into = NULL, updated_at = NOW()
+		WHERE p.source = 'osm'
+			AND (p.deleted_at IS NULL OR p.merged_into IS NOT NULL)
+			AND ST_Intersects(p.location, ST_GeogFromText($3))
+			AND NOT EXISTS (
+				SELECT 1 FROM unnest($1::text[], $2::bigint[]) AS s(osm_type, osm_id)
+				WHERE s.osm_type = p.osm_type AND s.osm_id = p.osm_id
+			)
+		RETURNING p.id`
+
+	return r.updateIDs(ctx, query, types, osmIDs, areaWKT)
+}
+
+// updateIDs runs an UPDATE ... RETURNING id and notifies listeners.
+func (r *POIRepository) updateIDs(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("delete pois: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan poi id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(ids) > 0 {
+		r.notifyChanged(ctx, ids)
+	}
+
+	return ids, nil
+}
+
+func splitOSMRefs(refs []OSMRef) ([]string, []int64) {
+	types := make([]string, len(refs))
+	osmIDs := make([]int64, len(refs))
+	for i, ref := range refs {
+		types[i], osmIDs[i] = ref.Type, ref.ID
+	}
+	return types, osmIDs
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -12,14 +13,30 @@ import (
 	"github.com/dremotha/mapbot/internal/domain"
 )
 
+// POIChangeFunc is called after POIs are written, e.g. to invalidate caches.
+type POIChangeFunc func(ctx context.Context, ids []uuid.UUID)
+
 type POIRepository struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	listeners []POIChangeFunc
 }
 
 func NewPOIRepository(pool *pgxpool.Pool) *POIRepository {
 	return &POIRepository{pool: pool}
 }
 
+// OnChange registers fn to be called after successful writes. It must be
+// called before the repository is used concurrently.
+func (r *POIRepository) OnChange(fn POIChangeFunc) {
+	r.listeners = append(r.listeners, fn)
+}
+
+func (r *POIRepository) notifyChanged(ctx context.Context, ids []uuid.UUID) {
+	for _, fn := range r.listeners {
+		fn(ctx, ids)
+	}
+}
+
 func (r *POIRepository) Create(ctx context.Context, poi *domain.POI) error {
 	tags, _ := json.Marshal(poi.Tags)
 
@@ -28,13 +45,15 @@ func (r *POIRepository) Create(ctx context.Context, poi *domain.POI) error {
 			id, name, description, short_description,
 			location, address, category, subcategory, tags,
 			historical_period, year_built, year_destroyed,
-			source, osm_id, popularity_score
+			source, osm_id, osm_type, popularity_score, opening_hours, region,
+			year_built_precision, year_destroyed_precision, wikidata, wikipedia
 		) VALUES (
 			$1, $2, $3, $4,
 			ST_SetSRID(ST_MakePoint($5, $6), 4326)::geography,
 			$7, $8, $9, $10,
 			$11, $12, $13,
-			$14, $15, $16
+			$14, $15, NULLIF($16, ''), $17, $18, NULLIF($19, ''),
+			NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, '')
 		)`
 
 	if poi.ID == uuid.Nil {
@@ -46,10 +65,15 @@ func (r *POIRepository) Create(ctx context.Context, poi *domain.POI) error {
 		poi.Lng, poi.Lat,
 		poi.Address, poi.Category, poi.Subcategory, tags,
 		poi.HistoricalPeriod, poi.YearBuilt, poi.YearDestroyed,
-		poi.Source, poi.OsmID, poi.PopularityScore,
+		poi.Source, poi.OsmID, poi.OsmType, poi.PopularityScore, marshalOpeningHours(poi.OpeningHours), poi.Region,
+		poi.YearBuiltPrecision, poi.YearDestroyedPrecision, poi.Wikidata, poi.Wikipedia,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	r.notifyChanged(ctx, []uuid.UUID{poi.ID})
+	return nil
 }
 
 func (r *POIRepository) CreateBatch(ctx context.Context, pois []domain.POI) error {
@@ -60,13 +84,15 @@ func (r *POIRepository) CreateBatch(ctx context.Context, pois []domain.POI) erro
 			id, name, description, short_description,
 			location, address, category, subcategory, tags,
 			historical_period, year_built, year_destroyed,
-			source, osm_id, popularity_score
+			source, osm_id, osm_type, popularity_score, opening_hours, region,
+			year_built_precision, year_destroyed_precision, wikidata, wikipedia
 		) VALUES (
 			$1, $2, $3, $4,
 			ST_SetSRID(ST_MakePoint($5, $6), 4326)::geography,
 			$7, $8, $9, $10,
 			$11, $12, $13,
-			$14, $15, $16
+			$14, $15, NULLIF($16, ''), $17, $18, NULLIF($19, ''),
+			NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, '')
 		) ON CONFLICT (id) DO NOTHING`
 
 	for i := range pois {
@@ -81,7 +107,8 @@ func (r *POIRepository) CreateBatch(ctx context.Context, pois []domain.POI) erro
 			poi.Lng, poi.Lat,
 			poi.Address, poi.Category, poi.Subcategory, tags,
 			poi.HistoricalPeriod, poi.YearBuilt, poi.YearDestroyed,
-			poi.Source, poi.OsmID, poi.PopularityScore,
+			poi.Source, poi.OsmID, poi.OsmType, poi.PopularityScore, marshalOpeningHours(poi.OpeningHours), poi.Region,
+			poi.YearBuiltPrecision, poi.YearDestroyedPrecision, poi.Wikidata, poi.Wikipedia,
 		)
 	}
 
@@ -94,38 +121,25 @@ func (r *POIRepository) CreateBatch(ctx context.Context, pois []domain.POI) erro
 		}
 	}
 
+	ids := make([]uuid.UUID, len(pois))
+	for i := range pois {
+		ids[i] = pois[i].ID
+	}
+	r.notifyChanged(ctx, ids)
+
 	return nil
 }
 
+// GetByID returns the POI; the ID of a merged duplicate resolves to the
+// POI it was merged into.
 func (r *POIRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.POI, error) {
 	query := `
-		SELECT 
-			id, name, description, short_description,
-			ST_Y(location::geometry) as lat, ST_X(location::geometry) as lng,
-			address, category, subcategory, tags,
-			historical_period, year_built, year_destroyed,
-			source, osm_id, popularity_score,
-			created_at, updated_at
+		SELECT` + poiSelectColumns + `
 		FROM poi
-		WHERE id = $1`
+		WHERE id = COALESCE((SELECT merged_into FROM poi WHERE id = $1), $1)
+			AND deleted_at IS NULL`
 
-	var poi domain.POI
-	var tags []byte
-
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&poi.ID, &poi.Name, &poi.Description, &poi.ShortDescription,
-		&poi.Lat, &poi.Lng,
-		&poi.Address, &poi.Category, &poi.Subcategory, &tags,
-		&poi.HistoricalPeriod, &poi.YearBuilt, &poi.YearDestroyed,
-		&poi.Source, &poi.OsmID, &poi.PopularityScore,
-		&poi.CreatedAt, &poi.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	json.Unmarshal(tags, &poi.Tags)
-	return &poi, nil
+	return scanPOI(r.pool.QueryRow(ctx, query, id))
 }
 
 func (r *POIRepository) Search(ctx context.Context, filters domain.SearchFilters) (*domain.SearchResult, error) {
@@ -134,13 +148,7 @@ func (r *POIRepository) Search(ctx context.Context, filters domain.SearchFilters
 	var centerLngIdx, centerLatIdx int
 
 	query := `
-		SELECT 
-			id, name, description, short_description,
-			ST_Y(location::geometry) as lat, ST_X(location::geometry) as lng,
-			address, category, subcategory, tags,
-			historical_period, year_built, year_destroyed,
-			source, osm_id, popularity_score,
-			created_at, updated_at`
+		SELECT` + poiSelectColumns + ``
 
 	if filters.Center != nil {
 		centerLngIdx = argIdx
@@ -151,7 +159,7 @@ func (r *POIRepository) Search(ctx context.Context, filters domain.SearchFilters
 		argIdx += 2
 	}
 
-	query += ` FROM poi WHERE 1=1`
+	query += ` FROM poi WHERE deleted_at IS NULL`
 
 	if filters.Center != nil && filters.RadiusKm > 0 {
 		query += fmt.Sprintf(` AND ST_DWithin(location, ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography, $%d)`,
@@ -166,6 +174,22 @@ func (r *POIRepository) Search(ctx context.Context, filters domain.SearchFilters
 		argIdx++
 	}
 
+	if filters.Region != "" {
+		query += fmt.Sprintf(` AND region = $%d`, argIdx)
+		args = append(args, filters.Region)
+		argIdx++
+	}
+
+	if filters.Period != "" {
+		period, err := domain.ParsePeriod(filters.Period)
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(` AND COALESCE(year_built, wiki_year_built) BETWEEN $%d AND $%d`, argIdx, argIdx+1)
+		args = append(args, period.From, period.To)
+		argIdx += 2
+	}
+
 	if filters.Center != nil {
 		query += ` ORDER BY distance`
 	} else {
@@ -190,29 +214,19 @@ func (r *POIRepository) Search(ctx context.Context, filters domain.SearchFilters
 	}
 
 	for rows.Next() {
-		var poi domain.POI
-		var tags []byte
 		var distance *float64
-
-		scanArgs := []interface{}{
-			&poi.ID, &poi.Name, &poi.Description, &poi.ShortDescription,
-			&poi.Lat, &poi.Lng,
-			&poi.Address, &poi.Category, &poi.Subcategory, &tags,
-			&poi.HistoricalPeriod, &poi.YearBuilt, &poi.YearDestroyed,
-			&poi.Source, &poi.OsmID, &poi.PopularityScore,
-			&poi.CreatedAt, &poi.UpdatedAt,
-		}
+		var extra []interface{}
 
 		if filters.Center != nil {
-			scanArgs = append(scanArgs, &distance)
+			extra = append(extra, &distance)
 		}
 
-		if err := rows.Scan(scanArgs...); err != nil {
+		poi, err := scanPOI(rows, extra...)
+		if err != nil {
 			return nil, err
 		}
 
-		json.Unmarshal(tags, &poi.Tags)
-		result.POIs = append(result.POIs, poi)
+		result.POIs = append(result.POIs, *poi)
 	}
 
 	result.Total = len(result.POIs)
@@ -224,15 +238,10 @@ func (r *POIRepository) SearchByText(ctx context.Context, text string, filters d
 	argIdx := 1
 
 	query := `
-		SELECT 
-			id, name, description, short_description,
-			ST_Y(location::geometry) as lat, ST_X(location::geometry) as lng,
-			address, category, subcategory, tags,
-			historical_period, year_built, year_destroyed,
-			source, osm_id, popularity_score,
-			created_at, updated_at
+		SELECT` + poiSelectColumns + `
 		FROM poi
-		WHERE (name ILIKE $1 OR description ILIKE $1 OR address ILIKE $1)`
+		WHERE deleted_at IS NULL
+			AND (name ILIKE $1 OR aliases::text ILIKE $1 OR description ILIKE $1 OR wiki_description ILIKE $1 OR address ILIKE $1)`
 
 	args = append(args, "%"+text+"%")
 	argIdx++
@@ -243,6 +252,22 @@ func (r *POIRepository) SearchByText(ctx context.Context, text string, filters d
 		argIdx++
 	}
 
+	if filters.Region != "" {
+		query += fmt.Sprintf(` AND region = $%d`, argIdx)
+		args = append(args, filters.Region)
+		argIdx++
+	}
+
+	if filters.Period != "" {
+		period, err := domain.ParsePeriod(filters.Period)
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(` AND COALESCE(year_built, wiki_year_built) BETWEEN $%d AND $%d`, argIdx, argIdx+1)
+		args = append(args, period.From, period.To)
+		argIdx += 2
+	}
+
 	if filters.Center != nil && filters.RadiusKm > 0 {
 		query += fmt.Sprintf(` AND ST_DWithin(location, ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography, $%d)`,
 			argIdx, argIdx+1, argIdx+2)
@@ -271,23 +296,12 @@ func (r *POIRepository) SearchByText(ctx context.Context, text string, filters d
 	}
 
 	for rows.Next() {
-		var poi domain.POI
-		var tags []byte
-
-		err := rows.Scan(
-			&poi.ID, &poi.Name, &poi.Description, &poi.ShortDescription,
-			&poi.Lat, &poi.Lng,
-			&poi.Address, &poi.Category, &poi.Subcategory, &tags,
-			&poi.HistoricalPeriod, &poi.YearBuilt, &poi.YearDestroyed,
-			&poi.Source, &poi.OsmID, &poi.PopularityScore,
-			&poi.CreatedAt, &poi.UpdatedAt,
-		)
+		poi, err := scanPOI(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		json.Unmarshal(tags, &poi.Tags)
-		result.POIs = append(result.POIs, poi)
+		result.POIs = append(result.POIs, *poi)
 	}
 
 	result.Total = len(result.POIs)
@@ -348,3 +362,148 @@ func (r *POIRepository) GetCategories(ctx context.Context) ([]domain.Category, e
 
 	return rootCategories, nil
 }
+
+// SearchAlongRoute returns POIs within bufferM meters of the route line,
+// ordered by their position along the route.
+func (r *POIRepository) SearchAlongRoute(ctx context.Context, line []domain.Coordinate, bufferM float64, categories []string, limit int) ([]domain.CorridorPOI, error) {
+	if len(line) < 2 {
+		return nil, fmt.Errorf("route line requires at least 2 points")
+	}
+
+	args := []interface{}{lineStringWKT(line), bufferM}
+	argIdx := 3
+
+	query := `
+		SELECT` + poiSelectColumns + `,
+			ST_Distance(location, route.line) as distance,
+			ST_LineLocatePoint(route.line::geometry, location::geometry) as position
+		FROM poi, (SELECT ST_GeogFromText($1) AS line) route
+		WHERE deleted_at IS NULL AND ST_DWithin(location, route.line, $2)`
+
+	if len(categories) > 0 {
+		query += fmt.Sprintf(` AND (category = ANY($%d) OR subcategory = ANY($%d))`, argIdx, argIdx)
+		args = append(args, categories)
+		argIdx++
+	}
+
+	if limit == 0 {
+		limit = 50
+	}
+	query += fmt.Sprintf(` ORDER BY position LIMIT $%d`, argIdx)
+	args = append(args, limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query route corridor: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]domain.CorridorPOI, 0)
+
+	for rows.Next() {
+		var item domain.CorridorPOI
+
+		poi, err := scanPOI(rows, &item.DistanceM, &item.Position)
+		if err != nil {
+			return nil, err
+		}
+
+		item.POI = *poi
+		result = append(result, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+func lineStringWKT(line []domain.Coordinate) string {
+	var b strings.Builder
+	b.WriteString("SRID=4326;LINESTRING(")
+	for i, c := range line {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%.6f %.6f", c.Lng, c.Lat)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+const poiSelectColumns = `
+			id, name, COALESCE(NULLIF(description, ''), wiki_description, ''), short_description,
+			ST_Y(location::geometry) as lat, ST_X(location::geometry) as lng,
+			address, category, subcategory, tags, COALESCE(aliases, '[]'),
+			historical_period, COALESCE(year_built, wiki_year_built),
+			CASE WHEN year_built IS NULL THEN COALESCE(wiki_year_built_precision, '') ELSE COALESCE(year_built_precision, '') END,
+			year_destroyed, COALESCE(year_destroyed_precision, ''),
+			source, osm_id, COALESCE(osm_type, ''), popularity_score, opening_hours,
+			COALESCE(region, ''), COALESCE(wikidata, ''), COALESCE(wikipedia, ''),
+			COALESCE(heritage, ''), COALESCE(image, ''), created_at, updated_at`
+
+// scanPOI scans a row selected with poiSelectColumns followed by the given
+// extra columns.
+func scanPOI(row pgx.Row, extra ...interface{}) (*domain.POI, error) {
+	var poi domain.POI
+	var tags, aliases, openingHours []byte
+
+	dest := []interface{}{
+		&poi.ID, &poi.Name, &poi.Description, &poi.ShortDescription,
+		&poi.Lat, &poi.Lng,
+		&poi.Address, &poi.Category, &poi.Subcategory, &tags, &aliases,
+		&poi.HistoricalPeriod, &poi.YearBuilt, &poi.YearBuiltPrecision,
+		&poi.YearDestroyed, &poi.YearDestroyedPrecision,
+		&poi.Source, &poi.OsmID, &poi.OsmType, &poi.PopularityScore, &openingHours,
+		&poi.Region, &poi.Wikidata, &poi.Wikipedia,
+		&poi.Heritage, &poi.Image, &poi.CreatedAt, &poi.UpdatedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(tags, &poi.Tags)
+	json.Unmarshal(aliases, &poi.Aliases)
+	if len(openingHours) > 0 {
+		json.Unmarshal(openingHours, &poi.OpeningHours)
+	}
+	return &poi, nil
+}
+
+// marshalOpeningHours returns nil for POIs without a schedule so the column
+// stays NULL.
+func marshalOpeningHours(hours *domain.OpeningHours) []byte {
+	if hours == nil {
+		return nil
+	}
+	data, _ := json.Marshal(hours)
+	return data
+}
+
+func (r *POIRepository) GetByOsmID(ctx context.Context, osmID int64) (*domain.POI, error) {
+	query := `SELECT` + poiSelectColumns + `
+		FROM poi
+		WHERE id IN (
+				SELECT COALESCE(merged_into, id) FROM poi
+				WHERE source = 'osm' AND osm_id = $1
+			)
+			AND deleted_at IS NULL
+		LIMIT 1`
+
+	return scanPOI(r.pool.QueryRow(ctx, query, osmID))
+}
+
+// FindNearestByName returns the closest POI whose name or alias contains
+// the given text within radiusM meters of the point.
+func (r *POIRepository) FindNearestByName(ctx context.Context, name string, near domain.Coordinate, radiusM float64) (*domain.POI, error) {
+	query := `SELECT` + poiSelectColumns + `
+		FROM poi
+		WHERE deleted_at IS NULL AND (name ILIKE $1 OR aliases::text ILIKE $1)
+			AND ST_DWithin(location, ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, $4)
+		ORDER BY location <-> ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography
+		LIMIT 1`
+
+	return scanPOI(r.pool.QueryRow(ctx, query, "%"+name+"%", near.Lng, near.Lat, radiusM))
+}
@@ -8,15 +8,21 @@ import (
 
 	"github.com/dremotha/mapbot/internal/domain"
 	"github.com/dremotha/mapbot/internal/infrastructure/qdrant"
-	"github.com/dremotha/mapbot/pkg/embedding"
 )
 
+// Embedder turns text into vectors. It is implemented by the embedding
+// service client and by caching wrappers around it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
 type QdrantPOIRepository struct {
 	qdrant          *qdrant.Client
-	embeddingClient *embedding.Client
+	embeddingClient Embedder
 }
 
-func NewQdrantPOIRepository(qdrantClient *qdrant.Client, embeddingClient *embedding.Client) *QdrantPOIRepository {
+func NewQdrantPOIRepository(qdrantClient *qdrant.Client, embeddingClient Embedder) *QdrantPOIRepository {
 	return &QdrantPOIRepository{
 		qdrant:          qdrantClient,
 		embeddingClient: embeddingClient,
@@ -24,10 +30,7 @@ func NewQdrantPOIRepository(qdrantClient *qdrant.Client, embeddingClient *embedd
 }
 
 func (r *QdrantPOIRepository) Index(ctx context.Context, poi *domain.POI) error {
-	text := poi.Name
-	if poi.Description != "" {
-		text += " " + poi.Description
-	}
+	text := embeddingText(poi)
 
 	vector, err := r.embeddingClient.Embed(ctx, text)
 	if err != nil {
@@ -43,13 +46,10 @@ func (r *QdrantPOIRepository) IndexBatch(ctx context.Context, pois []domain.POI)
 	texts := make([]string, 0, len(pois))
 	
 	for _, poi := range pois {
-		text := poi.Name
-		if text == "" {
+		if poi.Name == "" {
 			continue // Skip POIs without names
 		}
-		if poi.Description != "" {
-			text += " " + poi.Description
-		}
+		text := embeddingText(&poi)
 		validPOIs = append(validPOIs, poi)
 		texts = append(texts, text)
 	}
@@ -70,6 +70,86 @@ func (r *QdrantPOIRepository) IndexBatch(ctx context.Context, pois []domain.POI)
 	return r.qdrant.UpsertBatch(ctx, validPOIs, vectors)
 }
 
+// embeddingText is what a POI is found by: its names, including those of
+// merged duplicates, and the description.
+func embeddingText(poi *domain.POI) string {
+	text := poi.Name
+	for _, alias := range poi.Aliases {
+		text += " " + alias
+	}
+	if poi.Description != "" {
+		text += " " + poi.Description
+	}
+	return text
+}
+
+// Delete removes POIs and their facts from the vector index.
+func (r *QdrantPOIRepository) Delete(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.qdrant.DeletePoints(ctx, ids); err != nil {
+		return err
+	}
+	return r.qdrant.DeleteFactPoints(ctx, ids)
+}
+
+// IndexFacts replaces the indexed facts of the POIs with facts, so that
+// questions about events ("где венчался Пушкин") find the POI. Facts of
+// POIs missing from pois are skipped.
+func (r *QdrantPOIRepository) IndexFacts(ctx context.Context, pois []domain.POI, facts []domain.HistoricalFact) error {
+	if len(pois) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(pois))
+	byID := make(map[uuid.UUID]*domain.POI, len(pois))
+	for i := range pois {
+		ids[i] = pois[i].ID
+		byID[pois[i].ID] = &pois[i]
+	}
+	if err := r.qdrant.DeleteFactPoints(ctx, ids); err != nil {
+		return err
+	}
+
+	points := make([]qdrant.FactPoint, 0, len(facts))
+	texts := make([]string, 0, len(facts))
+	for _, fact := range facts {
+		poi := byID[fact.POIID]
+		if poi == nil || fact.Content == "" {
+			continue
+		}
+		points = append(points, qdrant.FactPoint{Fact: fact, POI: poi})
+		texts = append(texts, factEmbeddingText(poi, &fact))
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	vectors, err := r.embeddingClient.EmbedBatch(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(points) {
+		return fmt.Errorf("facts and vectors length mismatch: %d facts vs %d vectors", len(points), len(vectors))
+	}
+	for i := range points {
+		points[i].Vector = vectors[i]
+	}
+
+	return r.qdrant.UpsertFacts(ctx, points)
+}
+
+// factEmbeddingText names the POI so that the fact also matches questions
+// mentioning the place.
+func factEmbeddingText(poi *domain.POI, fact *domain.HistoricalFact) string {
+	text := poi.Name + ". "
+	if fact.Title != "" {
+		text += fact.Title + ". "
+	}
+	return text + fact.Content
+}
+
 func (r *QdrantPOIRepository) SemanticSearch(ctx context.Context, query string, filters domain.SearchFilters) ([]uuid.UUID, []float32, error) {
 	vector, err := r.embeddingClient.Embed(ctx, query)
 	if err != nil {
@@ -81,24 +161,39 @@ func (r *QdrantPOIRepository) SemanticSearch(ctx context.Context, query string,
 		limit = 50
 	}
 
+	var period *domain.Period
+	if filters.Period != "" {
+		period, err = domain.ParsePeriod(filters.Period)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var results []qdrant.SearchResult
 	var searchErr error
 
 	if filters.Center != nil && filters.RadiusKm > 0 {
-		results, searchErr = r.qdrant.SearchWithGeo(ctx, vector, limit, filters.Center.Lat, filters.Center.Lng, filters.RadiusKm)
+		results, searchErr = r.qdrant.SearchWithGeo(ctx, vector, limit, filters.Center.Lat, filters.Center.Lng, filters.RadiusKm, filters.Region, period)
 	} else {
-		results, searchErr = r.qdrant.Search(ctx, vector, limit, filters.Categories)
+		results, searchErr = r.qdrant.Search(ctx, vector, limit, filters.Categories, filters.Region, period)
 	}
 
 	if searchErr != nil {
 		return nil, nil, searchErr
 	}
 
-	ids := make([]uuid.UUID, len(results))
-	scores := make([]float32, len(results))
-	for i, r := range results {
-		ids[i] = r.ID
-		scores[i] = r.Score
+	// A POI may match by itself and by its facts; results are ordered by
+	// score, so the first match is the best
+	ids := make([]uuid.UUID, 0, len(results))
+	scores := make([]float32, 0, len(results))
+	seen := make(map[uuid.UUID]bool, len(results))
+	for _, r := range results {
+		if r.ID == uuid.Nil || seen[r.ID] {
+			continue
+		}
+		seen[r.ID] = true
+		ids = append(ids, r.ID)
+		scores = append(scores, r.Score)
 	}
 
 	return ids, scores, nil
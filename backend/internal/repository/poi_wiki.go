@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// FactSourceWikidata marks the facts written by the enrichment.
+const FactSourceWikidata = "wikidata"
+
+// WikiLink is the Wikidata item and the Wikipedia article of a POI.
+type WikiLink struct {
+	POIID     uuid.UUID
+	Wikidata  string
+	Wikipedia string
+}
+
+// Enrichment is the data of a POI taken from Wikidata and Wikipedia.
+// Description and YearBuilt are used when OSM has none.
+type Enrichment struct {
+	POIID              uuid.UUID
+	Description        string
+	YearBuilt          *int
+	YearBuiltPrecision domain.DatePrecision
+	Heritage           string
+	Image              string
+	Facts              []domain.HistoricalFact
+}
+
+// WikiLinks returns the links of the visible POIs of a region (all regions
+// if empty). Links of merged duplicates are returned for their canonical
+// POI after its own ones.
+func (r *POIRepository) WikiLinks(ctx context.Context, region string) ([]WikiLink, error) {
+	query := `
+		SELECT COALESCE(d.merged_into, d.id), COALESCE(d.wikidata, ''), COALESCE(d.wikipedia, '')
+		FROM poi d
+		LEFT JOIN poi c ON c.id = d.merged_into
+		WHERE (d.wikidata IS NOT NULL OR d.wikipedia IS NOT NULL)
+			AND COALESCE(c.deleted_at, d.deleted_at) IS NULL
+			AND ($1 = '' OR COALESCE(c.region, d.region) = $1)
+		ORDER BY COALESCE(d.merged_into, d.id), d.merged_into IS NOT NULL`
+
+	rows, err := r.pool.Query(ctx, query, region)
+	if err != nil {
+		return nil, fmt.Errorf("query wiki links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []WikiLink
+	for rows.Next() {
+		var l WikiLink
+		if err := rows.Scan(&l.POIID, &l.Wikidata, &l.Wikipedia); err != nil {
+			return nil, fmt.Errorf("scan wiki link: %w", err)
+		}
+		links = append(links, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return links, nil
+}
+
+// SaveEnrichment stores the enrichment of POIs and replaces their facts
+// from Wikidata; facts added by hand are kept. It returns
+// the IDs of POIs whose enrichment changed.
+func (r *POIRepository) SaveEnrichment(ctx context.Context, items []Enrichment) ([]uuid.UUID, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.POIID
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(`
+			UPDATE poi p SET
+				wiki_description = NULLIF($2, ''),
+				wiki_year_built = $3,
+				wiki_year_built_precision = NULLIF($4, ''),
+				heritage = NULLIF($5, ''),
+				image = NULLIF($6, ''),
+				enriched_at = NOW()
+			FROM (
+				SELECT id, wiki_description, wiki_year_built, wiki_year_built_precision, heritage, image
+				FROM poi WHERE id = $1
+			) old
+			WHERE p.id = old.id
+			RETURNING (p.wiki_description, p.wiki_year_built, p.wiki_year_built_precision, p.heritage, p.image)
+				IS DISTINCT FROM (old.wiki_description, old.wiki_year_built, old.wiki_year_built_precision, old.heritage, old.image)`,
+			item.POIID, item.Description, item.YearBuilt, item.YearBuiltPrecision, item.Heritage, item.Image,
+		)
+	}
+	batch.Queue(`
+		DELETE FROM historical_facts
+		WHERE poi_id = ANY($1) AND source = $2`,
+		ids, FactSourceWikidata,
+	)
+	for _, item := range items {
+		for _, f := range item.Facts {
+			batch.Queue(`
+				INSERT INTO historical_facts (poi_id, fact_type, title, content, year_from, year_to, source_url, source)
+				VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)`,
+				item.POIID, f.FactType, f.Title, f.Content, f.YearFrom, f.YearTo, f.SourceURL, f.Source,
+			)
+		}
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	var changed []uuid.UUID
+	for _, item := range items {
+		var differs bool
+		err := br.QueryRow().Scan(&differs)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			br.Close()
+			return nil, fmt.Errorf("update poi %s: %w", item.POIID, err)
+		}
+		if differs {
+			changed = append(changed, item.POIID)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return nil, fmt.Errorf("save facts: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit enrichment: %w", err)
+	}
+
+	if len(changed) > 0 {
+		r.notifyChanged(ctx, changed)
+	}
+	return changed, nil
+}
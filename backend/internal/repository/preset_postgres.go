@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+var ErrPresetNotFound = errors.New("preset route not found")
+
+type PresetRouteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPresetRouteRepository(pool *pgxpool.Pool) *PresetRouteRepository {
+	return &PresetRouteRepository{pool: pool}
+}
+
+// Upsert creates or replaces a preset route together with all its stops.
+func (r *PresetRouteRepository) Upsert(ctx context.Context, preset *domain.PresetRoute) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tags, _ := json.Marshal(preset.Tags)
+
+	var category *string
+	if preset.Category != "" {
+		category = &preset.Category
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO preset_routes (
+			id, name, description, category,
+			mode, difficulty, duration_hours, distance_km, image, tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			category = EXCLUDED.category,
+			mode = EXCLUDED.mode,
+			difficulty = EXCLUDED.difficulty,
+			duration_hours = EXCLUDED.duration_hours,
+			image = EXCLUDED.image,
+			tags = EXCLUDED.tags,
+			distance_km = EXCLUDED.distance_km,
+			updated_at = NOW()`,
+		preset.ID, preset.Name, preset.Description, category,
+		preset.Mode, preset.Difficulty, preset.DurationHours, preset.DistanceKm, preset.Image, tags,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert preset route: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM preset_route_stops WHERE preset_id = $1`, preset.ID); err != nil {
+		return fmt.Errorf("delete preset stops: %w", err)
+	}
+
+	for _, stop := range preset.Stops {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO preset_route_stops (preset_id, position, poi_id, name, note, location)
+			VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6, $7), 4326)::geography)`,
+			preset.ID, stop.Position, stop.POIID, stop.Name, stop.Note,
+			stop.Location.Lng, stop.Location.Lat,
+		)
+		if err != nil {
+			return fmt.Errorf("insert preset stop %d: %w", stop.Position, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PresetRouteRepository) List(ctx context.Context, filters domain.PresetRouteFilters) ([]domain.PresetRoute, error) {
+	var args []interface{}
+	argIdx := 1
+
+	query := `
+		SELECT
+			p.id, p.name, COALESCE(p.description, ''), COALESCE(p.category, ''),
+			p.mode, p.difficulty, COALESCE(p.duration_hours, 0), COALESCE(p.distance_km, 0),
+			COALESCE(p.image, ''), COALESCE(p.tags::text, '[]'), p.updated_at,
+			COALESCE(
+				(SELECT json_agg(s.poi_id ORDER BY s.position)::text
+				 FROM preset_route_stops s
+				 WHERE s.preset_id = p.id AND s.poi_id IS NOT NULL),
+				'[]')
+		FROM preset_routes p
+		WHERE 1=1`
+
+	if filters.Category != "" {
+		query += fmt.Sprintf(` AND p.category = $%d`, argIdx)
+		args = append(args, filters.Category)
+		argIdx++
+	}
+
+	if filters.Mode != "" {
+		query += fmt.Sprintf(` AND p.mode = $%d`, argIdx)
+		args = append(args, filters.Mode)
+		argIdx++
+	}
+
+	if filters.Difficulty != "" {
+		query += fmt.Sprintf(` AND p.difficulty = $%d`, argIdx)
+		args = append(args, filters.Difficulty)
+		argIdx++
+	}
+
+	if filters.Tag != "" {
+		query += fmt.Sprintf(` AND p.tags ? $%d`, argIdx)
+		args = append(args, filters.Tag)
+		argIdx++
+	}
+
+	query += ` ORDER BY p.name`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query preset routes: %w", err)
+	}
+	defer rows.Close()
+
+	presets := make([]domain.PresetRoute, 0)
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *preset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return presets, nil
+}
+
+// GetByID returns a preset route with its stops and the POIs they refer to.
+func (r *PresetRouteRepository) GetByID(ctx context.Context, id string) (*domain.PresetRoute, error) {
+	query := `
+		SELECT
+			p.id, p.name, COALESCE(p.description, ''), COALESCE(p.category, ''),
+			p.mode, p.difficulty, COALESCE(p.duration_hours, 0), COALESCE(p.distance_km, 0),
+			COALESCE(p.image, ''), COALESCE(p.tags::text, '[]'), p.updated_at,
+			COALESCE(
+				(SELECT json_agg(s.poi_id ORDER BY s.position)::text
+				 FROM preset_route_stops s
+				 WHERE s.preset_id = p.id AND s.poi_id IS NOT NULL),
+				'[]')
+		FROM preset_routes p
+		WHERE p.id = $1`
+
+	preset, err := scanPreset(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPresetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			s.position, s.poi_id, s.name, COALESCE(s.note, ''),
+			ST_Y(s.location::geometry), ST_X(s.location::geometry)
+		FROM preset_route_stops s
+		WHERE s.preset_id = $1
+		ORDER BY s.position`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query preset stops: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stop domain.PresetStop
+		if err := rows.Scan(
+			&stop.Position, &stop.POIID, &stop.Name, &stop.Note,
+			&stop.Location.Lat, &stop.Location.Lng,
+		); err != nil {
+			return nil, fmt.Errorf("scan preset stop: %w", err)
+		}
+		preset.Stops = append(preset.Stops, stop)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return preset, nil
+}
+
+func scanPreset(row pgx.Row) (*domain.PresetRoute, error) {
+	var preset domain.PresetRoute
+	var tags, poiIDs string
+
+	err := row.Scan(
+		&preset.ID, &preset.Name, &preset.Description, &preset.Category,
+		&preset.Mode, &preset.Difficulty, &preset.DurationHours, &preset.DistanceKm,
+		&preset.Image, &tags, &preset.UpdatedAt,
+		&poiIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(tags), &preset.Tags)
+	json.Unmarshal([]byte(poiIDs), &preset.POIIDs)
+
+	if preset.Tags == nil {
+		preset.Tags = []string{}
+	}
+	if preset.POIIDs == nil {
+		preset.POIIDs = []string{}
+	}
+
+	return &preset, nil
+}
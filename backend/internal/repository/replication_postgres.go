@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNoReplicationState = errors.New("replication state not found")
+
+// ReplicationRepository stores the last OSM diff applied from each
+// replication source.
+type ReplicationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReplicationRepository(pool *pgxpool.Pool) *ReplicationRepository {
+	return &ReplicationRepository{pool: pool}
+}
+
+// GetSequence returns the last applied sequence number for source.
+func (r *ReplicationRepository) GetSequence(ctx context.Context, source string) (int64, error) {
+	var seq int64
+	err := r.pool.QueryRow(ctx,
+		`SELECT sequence_number FROM osm_replication_state WHERE source = $1`, source,
+	).Scan(&seq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNoReplicationState
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get replication state: %w", err)
+	}
+	return seq, nil
+}
+
+func (r *ReplicationRepository) SaveSequence(ctx context.Context, source string, seq int64, timestamp time.Time) error {
+	query := `
+		INSERT INTO osm_replication_state (source, sequence_number, timestamp, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (source) DO UPDATE SET
+			sequence_number = EXCLUDED.sequence_number,
+			timestamp = EXCLUDED.timestamp,
+			updated_at = NOW()`
+
+	var ts *time.Time
+	if !timestamp.IsZero() {
+		ts = &timestamp
+	}
+
+	if _, err := r.pool.Exec(ctx, query, source, seq, ts); err != nil {
+		return fmt.Errorf("save replication state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+var (
+	ErrRouteNotFound     = errors.New("saved route not found")
+	ErrShareCodeConflict = errors.New("share code already exists")
+)
+
+type RouteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRouteRepository(pool *pgxpool.Pool) *RouteRepository {
+	return &RouteRepository{pool: pool}
+}
+
+// Create stores the route with the hash of its delete token.
+func (r *RouteRepository) Create(ctx context.Context, route *domain.SavedRoute, deleteTokenHash string) error {
+	waypoints, _ := json.Marshal(route.Waypoints)
+	pois, _ := json.Marshal(route.POIs)
+	metadata, _ := json.Marshal(route.Metadata)
+
+	query := `
+		INSERT INTO saved_routes (
+			id, share_code, title, mode,
+			distance_km, duration_min, geometry,
+			waypoints, pois, metadata, delete_token_hash
+		) VALUES (
+			$1, $2, $3, $4,
+			$5, $6, $7,
+			$8, $9, $10, $11
+		)
+		RETURNING created_at`
+
+	if route.ID == uuid.Nil {
+		route.ID = uuid.New()
+	}
+
+	err := r.pool.QueryRow(ctx, query,
+		route.ID, route.ShareCode, route.Title, route.Mode,
+		route.DistanceKm, route.DurationMin, route.Geometry,
+		waypoints, pois, metadata, deleteTokenHash,
+	).Scan(&route.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrShareCodeConflict
+	}
+
+	return err
+}
+
+func (r *RouteRepository) GetByShareCode(ctx context.Context, code string) (*domain.SavedRoute, error) {
+	query := `
+		SELECT
+			id, share_code, title, mode,
+			distance_km, duration_min, geometry,
+			waypoints, pois, metadata, created_at
+		FROM saved_routes
+		WHERE share_code = $1`
+
+	return r.scanRoute(r.pool.QueryRow(ctx, query, code))
+}
+
+func (r *RouteRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.SavedRoute, error) {
+	query := `
+		SELECT
+			id, share_code, title, mode,
+			distance_km, duration_min, geometry,
+			waypoints, pois, metadata, created_at
+		FROM saved_routes
+		WHERE id = $1`
+
+	return r.scanRoute(r.pool.QueryRow(ctx, query, id))
+}
+
+// ListByShareCodes returns the routes with the given share codes, newest
+// first. Unknown codes are skipped.
+func (r *RouteRepository) ListByShareCodes(ctx context.Context, codes []string) ([]domain.SavedRoute, error) {
+	query := `
+		SELECT
+			id, share_code, title, mode,
+			distance_km, duration_min, geometry,
+			waypoints, pois, metadata, created_at
+		FROM saved_routes
+		WHERE share_code = ANY($1)
+		ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, codes)
+	if err != nil {
+		return nil, fmt.Errorf("query saved routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := make([]domain.SavedRoute, 0, len(codes))
+	for rows.Next() {
+		route, err := r.scanRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan saved route: %w", err)
+		}
+		routes = append(routes, *route)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return routes, nil
+}
+
+// Delete removes the route if deleteTokenHash matches the stored one. A
+// wrong token is reported as ErrRouteNotFound so that it does not reveal
+// whether the route exists.
+func (r *RouteRepository) Delete(ctx context.Context, id uuid.UUID, deleteTokenHash string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM saved_routes WHERE id = $1 AND delete_token_hash = $2`, id, deleteTokenHash)
+	if err != nil {
+		return fmt.Errorf("delete saved route: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrRouteNotFound
+	}
+
+	return nil
+}
+
+func (r *RouteRepository) scanRoute(row pgx.Row) (*domain.SavedRoute, error) {
+	var route domain.SavedRoute
+	var waypoints, pois, metadata []byte
+
+	err := row.Scan(
+		&route.ID, &route.ShareCode, &route.Title, &route.Mode,
+		&route.DistanceKm, &route.DurationMin, &route.Geometry,
+		&waypoints, &pois, &metadata, &route.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRouteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(waypoints, &route.Waypoints)
+	json.Unmarshal(pois, &route.POIs)
+	json.Unmarshal(metadata, &route.Metadata)
+
+	return &route, nil
+}
@@ -3,20 +3,57 @@ package service
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dremotha/mapbot/internal/config"
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/metrics"
 )
 
+// ErrCacheMiss is returned by Get when the key is not cached.
+var ErrCacheMiss = errors.New("cache miss")
+
+// CacheManager is a two-tier cache: a size-limited in-process LRU in front
+// of Redis. Values are fresh for their TTL and may be served stale for
+// another half TTL while GetOrCompute refreshes them in the background.
+// Without Redis only the local tier is used.
 type CacheManager struct {
 	redis *redis.Client
+	codec Codec
+	local *lruCache[cacheEntry]
+	group singleflight.Group
+
+	mu       sync.RWMutex
+	versions map[string]int64
 }
 
-func NewCacheManager(redisClient *redis.Client) *CacheManager {
-	return &CacheManager{redis: redisClient}
+// NewCacheManager creates the cache. redisClient may be nil.
+func NewCacheManager(redisClient *redis.Client, cfg config.CacheConfig) (*CacheManager, error) {
+	codec, err := NewCodec(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CacheManager{
+		redis: redisClient,
+		codec: codec,
+		local: newSizedLRUCache(cfg.LocalSize, cfg.LocalMaxMB<<20, func(e cacheEntry) int {
+			return len(e.data) + len(e.key)
+		}),
+		versions: make(map[string]int64),
+	}, nil
 }
 
 const (
@@ -27,63 +64,331 @@ const (
 	CategoriesCacheTTL = 24 * time.Hour
 )
 
-func (c *CacheManager) Get(ctx context.Context, key string, dest interface{}) error {
-	data, err := c.redis.Get(ctx, key).Bytes()
+// cacheEntry is a decoded envelope. Values are kept encoded in the local
+// tier so every caller gets its own copy.
+type cacheEntry struct {
+	key        string
+	data       []byte
+	freshUntil time.Time
+	expiresAt  time.Time
+}
+
+func (e cacheEntry) stale(now time.Time) bool {
+	return now.After(e.freshUntil)
+}
+
+// Stored values are prefixed with a header: magic byte, codec ID, fresh
+// until and expires at (Unix milliseconds).
+const (
+	envelopeMagic  = 0xca
+	envelopeHeader = 2 + 8 + 8
+)
+
+// staleWindow is how long an entry may be served after its TTL.
+func staleWindow(ttl time.Duration) time.Duration {
+	return ttl / 2
+}
+
+func (c *CacheManager) encode(key string, value interface{}, ttl time.Duration) (cacheEntry, []byte, error) {
+	data, err := c.codec.Marshal(value)
 	if err != nil {
-		return err
+		return cacheEntry{}, nil, err
+	}
+
+	now := time.Now()
+	entry := cacheEntry{
+		key:        key,
+		data:       data,
+		freshUntil: now.Add(ttl),
+		expiresAt:  now.Add(ttl + staleWindow(ttl)),
+	}
+
+	raw := make([]byte, envelopeHeader, envelopeHeader+len(data))
+	raw[0] = envelopeMagic
+	raw[1] = c.codec.ID()
+	binary.BigEndian.PutUint64(raw[2:], uint64(entry.freshUntil.UnixMilli()))
+	binary.BigEndian.PutUint64(raw[10:], uint64(entry.expiresAt.UnixMilli()))
+	raw = append(raw, data...)
+
+	return entry, raw, nil
+}
+
+func (c *CacheManager) decodeEnvelope(key string, raw []byte) (cacheEntry, bool) {
+	if len(raw) < envelopeHeader || raw[0] != envelopeMagic || raw[1] != c.codec.ID() {
+		return cacheEntry{}, false
 	}
-	return json.Unmarshal(data, dest)
+
+	return cacheEntry{
+		key:        key,
+		data:       raw[envelopeHeader:],
+		freshUntil: time.UnixMilli(int64(binary.BigEndian.Uint64(raw[2:]))),
+		expiresAt:  time.UnixMilli(int64(binary.BigEndian.Uint64(raw[10:]))),
+	}, true
+}
+
+// lookup checks the local tier, then Redis. local reports which tier
+// answered.
+func (c *CacheManager) lookup(ctx context.Context, key string) (entry cacheEntry, local bool, ok bool) {
+	now := time.Now()
+
+	if entry, ok := c.local.Get(key); ok {
+		if now.Before(entry.expiresAt) {
+			return entry, true, true
+		}
+		c.local.Delete(key)
+	}
+
+	if c.redis == nil {
+		return cacheEntry{}, false, false
+	}
+
+	raw, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return cacheEntry{}, false, false
+	}
+
+	entry, ok = c.decodeEnvelope(key, raw)
+	if !ok || !now.Before(entry.expiresAt) {
+		return cacheEntry{}, false, false
+	}
+
+	c.local.Set(key, entry)
+	return entry, false, true
+}
+
+// Get decodes a cached value into dest, including values past their TTL
+// but still within the stale window.
+func (c *CacheManager) Get(ctx context.Context, key string, dest interface{}) error {
+	entry, _, ok := c.lookup(ctx, key)
+	if !ok {
+		return ErrCacheMiss
+	}
+	return c.codec.Unmarshal(entry.data, dest)
+}
+
+// Decode decodes a value returned by GetMany.
+func (c *CacheManager) Decode(data []byte, dest interface{}) error {
+	return c.codec.Unmarshal(data, dest)
 }
 
 func (c *CacheManager) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	data, err := json.Marshal(value)
+	entry, raw, err := c.encode(key, value, ttl)
 	if err != nil {
 		return err
 	}
-	return c.redis.Set(ctx, key, data, ttl).Err()
+
+	c.local.Set(key, entry)
+	if c.redis == nil {
+		return nil
+	}
+	return c.redis.Set(ctx, key, raw, ttl+staleWindow(ttl)).Err()
 }
 
+// SetAsync encodes the value immediately and writes it to Redis in the
+// background.
 func (c *CacheManager) SetAsync(ctx context.Context, key string, value interface{}, ttl time.Duration) {
-	go func() {
-		data, err := json.Marshal(value)
+	entry, raw, err := c.encode(key, value, ttl)
+	if err != nil {
+		log.Printf("Cache encode %s failed: %v", key, err)
+		return
+	}
+
+	c.local.Set(key, entry)
+	c.storeAsync(key, raw, ttl)
+}
+
+// storeAsync writes an encoded value to Redis in the background.
+func (c *CacheManager) storeAsync(key string, raw []byte, ttl time.Duration) {
+	if c.redis == nil {
+		return
+	}
+	go c.redis.Set(context.Background(), key, raw, ttl+staleWindow(ttl))
+}
+
+// GetMany fetches several keys from Redis in one round trip. Missing keys
+// get a nil entry; values are decoded with Decode. Without Redis the
+// local tier is checked instead.
+func (c *CacheManager) GetMany(ctx context.Context, keys []string) ([][]byte, error) {
+	if c.redis == nil {
+		now := time.Now()
+		result := make([][]byte, len(keys))
+		for i, key := range keys {
+			if entry, ok := c.local.Get(key); ok && now.Before(entry.expiresAt) {
+				result[i] = entry.data
+			}
+		}
+		return result, nil
+	}
+
+	values, err := c.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]byte, len(keys))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if entry, ok := c.decodeEnvelope(keys[i], []byte(s)); ok {
+			result[i] = entry.data
+		}
+	}
+	return result, nil
+}
+
+// SetManyAsync stores several values with the same TTL in one pipeline.
+func (c *CacheManager) SetManyAsync(ctx context.Context, values map[string]interface{}, ttl time.Duration) {
+	raws := make(map[string][]byte, len(values))
+	for key, value := range values {
+		entry, raw, err := c.encode(key, value, ttl)
 		if err != nil {
-			return
+			continue
+		}
+		if c.redis == nil {
+			c.local.Set(key, entry)
+			continue
+		}
+		raws[key] = raw
+	}
+
+	if c.redis == nil {
+		return
+	}
+
+	go func() {
+		pipe := c.redis.Pipeline()
+		for key, raw := range raws {
+			pipe.Set(context.Background(), key, raw, ttl+staleWindow(ttl))
 		}
-		c.redis.Set(context.Background(), key, data, ttl)
+		pipe.Exec(context.Background())
 	}()
 }
 
 func (c *CacheManager) Delete(ctx context.Context, key string) error {
+	c.local.Delete(key)
+	if c.redis == nil {
+		return nil
+	}
 	return c.redis.Del(ctx, key).Err()
 }
 
-func (c *CacheManager) GetOrCompute(ctx context.Context, key string, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) error {
-	if err := c.Get(ctx, key, dest); err == nil {
+type computed struct {
+	value interface{}
+	data  []byte
+}
+
+// GetOrCompute returns the cached value or computes it once for all
+// concurrent callers of this replica. Stale values are returned immediately
+// and refreshed in the background. compute runs detached from the caller's
+// cancellation because other callers may be waiting for it. name labels the
+// cache metrics.
+func (c *CacheManager) GetOrCompute(ctx context.Context, name, key string, ttl time.Duration, dest interface{}, compute func(context.Context) (interface{}, error)) error {
+	if entry, local, ok := c.lookup(ctx, key); ok {
+		switch {
+		case entry.stale(time.Now()):
+			metrics.CacheRequestsTotal.WithLabelValues(name, metrics.CacheStale).Inc()
+			c.revalidate(ctx, key, ttl, compute)
+		case local:
+			metrics.CacheRequestsTotal.WithLabelValues(name, metrics.CacheLocalHit).Inc()
+		default:
+			metrics.CacheRequestsTotal.WithLabelValues(name, metrics.CacheHit).Inc()
+		}
+		return c.codec.Unmarshal(entry.data, dest)
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		return c.computeAndStore(context.WithoutCancel(ctx), key, ttl, compute)
+	})
+
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if res.Shared {
+		metrics.CacheRequestsTotal.WithLabelValues(name, metrics.CacheShared).Inc()
+	} else {
+		metrics.CacheRequestsTotal.WithLabelValues(name, metrics.CacheMiss).Inc()
+	}
+
+	if res.Err != nil {
+		return res.Err
+	}
+
+	result := res.Val.(computed)
+	if !res.Shared && assign(dest, result.value) {
 		return nil
 	}
+	return c.codec.Unmarshal(result.data, dest)
+}
 
-	result, err := compute()
+func (c *CacheManager) computeAndStore(ctx context.Context, key string, ttl time.Duration, compute func(context.Context) (interface{}, error)) (interface{}, error) {
+	value, err := compute(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	data, err := json.Marshal(result)
+	entry, raw, err := c.encode(key, value, ttl)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := json.Unmarshal(data, dest); err != nil {
-		return err
-	}
+	c.local.Set(key, entry)
+	c.storeAsync(key, raw, ttl)
 
-	c.SetAsync(ctx, key, result, ttl)
+	return computed{value: value, data: entry.data}, nil
+}
 
-	return nil
+// revalidate refreshes a stale entry at most once at a time per key.
+func (c *CacheManager) revalidate(ctx context.Context, key string, ttl time.Duration, compute func(context.Context) (interface{}, error)) {
+	c.group.DoChan("revalidate:"+key, func() (interface{}, error) {
+		_, err := c.computeAndStore(context.WithoutCancel(ctx), key, ttl, compute)
+		if err != nil {
+			log.Printf("Cache revalidation of %s failed: %v", key, err)
+		}
+		return nil, err
+	})
 }
 
-func SearchCacheKey(query string, categories []string, lat, lng *float64, radiusKm float64) string {
-	data := fmt.Sprintf("search:%s:%v:%v:%v:%f", query, categories, lat, lng, radiusKm)
-	return hashKey(data)
+// assign stores value in dest without a codec round trip when the types
+// allow it: T into *T, or *T into *T (copying the pointed-to value).
+func assign(dest, value interface{}) bool {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() || value == nil {
+		return false
+	}
+	target := d.Elem()
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(target.Type()) {
+		target.Set(v)
+		return true
+	}
+	if v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Type().AssignableTo(target.Type()) {
+		target.Set(v.Elem())
+		return true
+	}
+	return false
+}
+
+// SearchCacheKey expects an already normalized query. Category order does
+// not affect the key.
+func SearchCacheKey(query string, filters domain.SearchFilters) string {
+	categories := append([]string(nil), filters.Categories...)
+	sort.Strings(categories)
+
+	center := "-"
+	if filters.Center != nil {
+		center = fmt.Sprintf("%.4f,%.4f", filters.Center.Lat, filters.Center.Lng)
+	}
+
+	data := fmt.Sprintf("search:%s:%s:%s:%.1f:%s:%s:%d:%d",
+		query, strings.Join(categories, ","), center, filters.RadiusKm, filters.Period, filters.Region, filters.Limit, filters.Offset)
+	return "search:" + hashKey(data)
 }
 
 func POICacheKey(id string) string {
@@ -99,6 +404,10 @@ func RouteCacheKey(waypoints string, mode string) string {
 	return hashKey(data)
 }
 
+func PresetRouteCacheKey(id string, updatedAt time.Time) string {
+	return fmt.Sprintf("preset_route:%s:%d", id, updatedAt.Unix())
+}
+
 func CategoriesCacheKey() string {
 	return "categories"
 }
@@ -107,41 +416,3 @@ func hashKey(data string) string {
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:16])
 }
-
-type CachedSearchService struct {
-	searchService *SearchService
-	cache         *CacheManager
-}
-
-func NewCachedSearchService(searchService *SearchService, cache *CacheManager) *CachedSearchService {
-	return &CachedSearchService{
-		searchService: searchService,
-		cache:         cache,
-	}
-}
-
-type CachedEmbeddingClient struct {
-	cache *CacheManager
-}
-
-func NewCachedEmbeddingClient(cache *CacheManager) *CachedEmbeddingClient {
-	return &CachedEmbeddingClient{cache: cache}
-}
-
-func (c *CachedEmbeddingClient) GetCached(ctx context.Context, text string) ([]float32, bool) {
-	key := EmbeddingCacheKey(text)
-	var vector []float32
-	if err := c.cache.Get(ctx, key, &vector); err == nil {
-		return vector, true
-	}
-	return nil, false
-}
-
-func (c *CachedEmbeddingClient) SetCached(ctx context.Context, text string, vector []float32) {
-	key := EmbeddingCacheKey(text)
-	c.cache.SetAsync(ctx, key, vector, EmbeddingCacheTTL)
-}
-
-
-
-
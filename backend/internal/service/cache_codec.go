@@ -0,0 +1,46 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dremotha/mapbot/pkg/msgpack"
+)
+
+// Codec serializes cached values.
+type Codec interface {
+	// ID is stored with every value so entries written with another codec
+	// are treated as misses instead of failing to decode.
+	ID() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) ID() byte { return 'j' }
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// MsgpackCodec is more compact than JSON and about twice as fast to decode.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ID() byte { return 'm' }
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+// NewCodec returns the codec named "json" or "msgpack".
+func NewCodec(name string) (Codec, error) {
+	switch name {
+	case "json":
+		return JSONCodec{}, nil
+	case "msgpack", "":
+		return MsgpackCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache codec %q", name)
+	}
+}
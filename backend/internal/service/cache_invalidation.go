@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheNamespacePOI covers everything derived from POI data: search
+// results, POIs, categories and routes through POIs.
+const CacheNamespacePOI = "poi"
+
+const (
+	cacheInvalidationChannel = "cache:invalidate"
+	// cacheVersionRefresh re-reads namespace versions in case an
+	// invalidation message was lost while the subscription was down.
+	cacheVersionRefresh = time.Minute
+)
+
+// InvalidationEvent is published when a namespace version is bumped.
+type InvalidationEvent struct {
+	Namespace string `json:"namespace"`
+	Version   int64  `json:"version"`
+}
+
+func cacheVersionKey(namespace string) string {
+	return fmt.Sprintf("cache:version:%s", namespace)
+}
+
+// VersionedKey prefixes key with the current namespace version, so bumping
+// the version makes all older entries unreachable. They expire by TTL.
+func (c *CacheManager) VersionedKey(ctx context.Context, namespace, key string) string {
+	return fmt.Sprintf("v%d:%s", c.version(ctx, namespace), key)
+}
+
+func (c *CacheManager) version(ctx context.Context, namespace string) int64 {
+	c.mu.RLock()
+	v, ok := c.versions[namespace]
+	c.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	if c.redis == nil {
+		c.setVersion(namespace, 0)
+		return c.version(ctx, namespace)
+	}
+
+	v, err := c.redis.Get(ctx, cacheVersionKey(namespace)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0
+	}
+
+	c.setVersion(namespace, v)
+	return c.version(ctx, namespace)
+}
+
+// setVersion never moves a version backwards, so a delayed message or
+// refresh cannot resurrect old entries.
+func (c *CacheManager) setVersion(namespace string, v int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.versions[namespace]; ok && current >= v {
+		return false
+	}
+	c.versions[namespace] = v
+	return true
+}
+
+// Invalidate bumps the namespace version and notifies all replicas.
+// Without Redis only this process is affected.
+func (c *CacheManager) Invalidate(ctx context.Context, namespace string) error {
+	if c.redis == nil {
+		c.apply(InvalidationEvent{Namespace: namespace, Version: c.version(ctx, namespace) + 1})
+		return nil
+	}
+
+	v, err := c.redis.Incr(ctx, cacheVersionKey(namespace)).Result()
+	if err != nil {
+		return fmt.Errorf("bump cache version: %w", err)
+	}
+
+	event := InvalidationEvent{Namespace: namespace, Version: v}
+	c.apply(event)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal invalidation event: %w", err)
+	}
+
+	if err := c.redis.Publish(ctx, cacheInvalidationChannel, data).Err(); err != nil {
+		return fmt.Errorf("publish invalidation event: %w", err)
+	}
+
+	return nil
+}
+
+// InvalidatePOIs matches repository.POIChangeFunc. Search results and
+// routes cannot be traced back to the POIs they contain, so the whole
+// namespace is invalidated.
+func (c *CacheManager) InvalidatePOIs(ctx context.Context, ids []uuid.UUID) {
+	if err := c.Invalidate(ctx, CacheNamespacePOI); err != nil {
+		log.Printf("Cache invalidation failed: %v", err)
+	}
+}
+
+// DeferredInvalidation invalidates the POI namespace once for a run that
+// writes POIs in many batches, such as an import, instead of after every
+// batch.
+type DeferredInvalidation struct {
+	cache   *CacheManager
+	changed atomic.Bool
+}
+
+func (c *CacheManager) Deferred() *DeferredInvalidation {
+	return &DeferredInvalidation{cache: c}
+}
+
+// POIsChanged matches repository.POIChangeFunc.
+func (d *DeferredInvalidation) POIsChanged(ctx context.Context, ids []uuid.UUID) {
+	d.changed.Store(true)
+}
+
+// Flush invalidates the namespace if any POI changed since the last flush.
+func (d *DeferredInvalidation) Flush(ctx context.Context) {
+	if d.changed.Swap(false) {
+		d.cache.InvalidatePOIs(ctx, nil)
+	}
+}
+
+func (c *CacheManager) apply(event InvalidationEvent) {
+	if !c.setVersion(event.Namespace, event.Version) {
+		return
+	}
+
+	// Old entries are unreachable under the new version; free the memory.
+	c.local.Purge()
+}
+
+// Listen applies invalidation events from other replicas until ctx is done.
+// Without Redis there are none and it returns immediately.
+func (c *CacheManager) Listen(ctx context.Context) {
+	if c.redis == nil {
+		return
+	}
+
+	pubsub := c.redis.Subscribe(ctx, cacheInvalidationChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	ticker := time.NewTicker(cacheVersionRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event InvalidationEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Invalid cache invalidation event: %v", err)
+				continue
+			}
+			c.apply(event)
+		case <-ticker.C:
+			c.refreshVersions(ctx)
+		}
+	}
+}
+
+func (c *CacheManager) refreshVersions(ctx context.Context) {
+	c.mu.RLock()
+	namespaces := make([]string, 0, len(c.versions))
+	for ns := range c.versions {
+		namespaces = append(namespaces, ns)
+	}
+	c.mu.RUnlock()
+
+	for _, ns := range namespaces {
+		v, err := c.redis.Get(ctx, cacheVersionKey(ns)).Int64()
+		if err != nil {
+			continue
+		}
+		c.apply(InvalidationEvent{Namespace: ns, Version: v})
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
+)
+
+const (
+	defaultCorridorBufferM = 500
+	maxCorridorBufferM     = 5000
+	defaultCorridorLimit   = 20
+
+	// detourCircuity converts the straight-line distance to the route into a
+	// road network distance.
+	detourCircuity = 1.3
+)
+
+// DiscoverAlongRoute finds POIs within a corridor around a route and
+// estimates the detour needed to visit each of them.
+func (s *RoutingService) DiscoverAlongRoute(ctx context.Context, req domain.CorridorRequest) (*domain.CorridorResponse, error) {
+	resp := &domain.CorridorResponse{}
+	geometry := req.Geometry
+	mode := req.Mode
+
+	if geometry == "" {
+		if req.Route == nil {
+			return nil, invalidRequest("необходимо указать маршрут или его геометрию")
+		}
+
+		routeResp, err := s.BuildRoute(ctx, *req.Route)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Route = routeResp.Route
+		geometry = routeResp.Route.Geometry
+		mode = routeResp.Route.Mode
+	}
+
+	if mode == "" {
+		mode = domain.TransportDriving
+	}
+
+	line := osrm.DecodePolyline(geometry)
+	if len(line) < 2 {
+		return nil, invalidRequest("некорректная геометрия маршрута")
+	}
+
+	bufferM := req.BufferM
+	if bufferM <= 0 {
+		bufferM = defaultCorridorBufferM
+	}
+	if bufferM > maxCorridorBufferM {
+		bufferM = maxCorridorBufferM
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultCorridorLimit
+	}
+
+	log.Printf("Searching POIs along route: %d points, buffer=%.0fm, categories=%v", len(line), bufferM, req.Categories)
+
+	pois, err := s.poiRepo.SearchAlongRoute(ctx, line, bufferM, req.Categories, limit)
+	if err != nil {
+		log.Printf("Corridor search failed: %v", err)
+		return nil, fmt.Errorf("ошибка поиска мест вдоль маршрута: %w", err)
+	}
+
+	lengthKm := lineLengthKm(line)
+	if resp.Route != nil {
+		lengthKm = resp.Route.DistanceKm
+	}
+
+	// On public transport the detour to a POI is walked
+	speed := averageSpeedKmh(mode)
+	if mode == domain.TransportTransit {
+		speed = averageSpeedKmh(domain.TransportWalking)
+	}
+	for i := range pois {
+		detourM := 2 * pois[i].DistanceM * detourCircuity
+		pois[i].DistanceAlongKm = pois[i].Position * lengthKm
+		pois[i].DetourM = detourM
+		pois[i].DetourMin = detourM / 1000 / speed * 60
+	}
+
+	resp.POIs = pois
+	resp.Message = fmt.Sprintf("Вдоль маршрута найдено %d мест в пределах %.0f м", len(pois), bufferM)
+	if len(pois) == 0 {
+		resp.Message = "Вдоль маршрута не найдено подходящих мест"
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+)
+
+const (
+	DefaultDedupDistanceM  = 100
+	DefaultDedupSimilarity = 0.6
+	dedupStemLength        = 5
+)
+
+// genericNameWords name the kind of object rather than the object itself:
+// "Церковь Троицы" and "Храм Троицы" are the same church. Names with
+// different kinds never match, as a chapel often stands next to the church
+// of the same dedication. Words without a kind are just skipped.
+var genericNameWords = map[string]string{
+	"церковь": "church", "храм": "church", "собор": "church", "кирха": "church",
+	"часовня": "chapel", "монастырь": "monastery", "мечеть": "mosque", "синагога": "synagogue",
+	"памятник": "monument", "мемориал": "monument", "усадьба": "manor", "дворец": "palace",
+	"башня": "tower", "крепость": "fortress", "руины": "ruins",
+	"в": "", "на": "", "и": "", "им": "", "имени": "",
+}
+
+// DedupOptions tune duplicate detection.
+type DedupOptions struct {
+	// MaxDistanceM is the largest distance between duplicates.
+	MaxDistanceM float64
+	// MinSimilarity is the least name similarity, from 0 to 1.
+	MinSimilarity float64
+}
+
+// Duplicate is a POI to be merged into the canonical one of its group.
+type Duplicate struct {
+	POI        domain.POI
+	DistanceM  float64
+	Similarity float64
+}
+
+type DuplicateGroup struct {
+	Canonical  domain.POI
+	Duplicates []Duplicate
+	// Aliases are the canonical POI's names after the merge.
+	Aliases []string
+}
+
+// DedupService finds POIs that describe the same object, e.g. a church
+// mapped as a node and as a building, and merges them.
+type DedupService struct {
+	poiRepo    *repository.POIRepository
+	factRepo   *repository.HistoricalFactRepository
+	qdrantRepo *repository.QdrantPOIRepository
+}
+
+// NewDedupService creates the service. qdrantRepo may be nil, in which case
+// the vector index is not updated.
+func NewDedupService(poiRepo *repository.POIRepository, factRepo *repository.HistoricalFactRepository, qdrantRepo *repository.QdrantPOIRepository) *DedupService {
+	return &DedupService{poiRepo: poiRepo, factRepo: factRepo, qdrantRepo: qdrantRepo}
+}
+
+// Run detects the duplicates of a region and, unless dryRun is set, merges
+// them. Duplicates whose canonical POI has been removed from OSM are
+// released first, so they can form new groups.
+func (s *DedupService) Run(ctx context.Context, region string, opts DedupOptions, dryRun bool) ([]DuplicateGroup, error) {
+	if !dryRun {
+		released, err := s.poiRepo.ReleaseOrphanDuplicates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(released) > 0 {
+			log.Printf("Released %d duplicates of removed POIs", len(released))
+			if err := s.reindex(ctx, released); err != nil {
+				log.Printf("Warning: Failed to index released duplicates: %v", err)
+			}
+		}
+	}
+
+	groups, err := s.Find(ctx, region, opts)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return groups, nil
+	}
+
+	for _, g := range groups {
+		if err := s.merge(ctx, g); err != nil {
+			return nil, fmt.Errorf("merge into %s: %w", g.Canonical.ID, err)
+		}
+	}
+	return groups, nil
+}
+
+// Find groups nearby POIs of the same category with similar names.
+func (s *DedupService) Find(ctx context.Context, region string, opts DedupOptions) ([]DuplicateGroup, error) {
+	pairs, err := s.poiRepo.DuplicateCandidates(ctx, region, opts.MaxDistanceM)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, 2*len(pairs))
+	for _, p := range pairs {
+		ids = append(ids, p.A, p.B)
+	}
+	pois, err := s.poiRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*domain.POI, len(pois))
+	for i := range pois {
+		byID[pois[i].ID] = &pois[i]
+	}
+
+	// Union-find over the pairs that pass the name check
+	parent := make(map[uuid.UUID]uuid.UUID)
+	var root func(id uuid.UUID) uuid.UUID
+	root = func(id uuid.UUID) uuid.UUID {
+		p, ok := parent[id]
+		if !ok || p == id {
+			return id
+		}
+		parent[id] = root(p)
+		return parent[id]
+	}
+
+	for _, p := range pairs {
+		a, b := byID[p.A], byID[p.B]
+		if a == nil || b == nil || nameSimilarity(a, b) < opts.MinSimilarity {
+			continue
+		}
+		for _, id := range []uuid.UUID{p.A, p.B} {
+			if _, ok := parent[id]; !ok {
+				parent[id] = id
+			}
+		}
+		parent[root(p.A)] = root(p.B)
+	}
+
+	members := make(map[uuid.UUID][]*domain.POI)
+	for id := range parent {
+		r := root(id)
+		members[r] = append(members[r], byID[id])
+	}
+
+	groups := make([]DuplicateGroup, 0, len(members))
+	for _, group := range members {
+		if g, ok := buildGroup(group, opts); ok {
+			groups = append(groups, g)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Canonical.Name < groups[j].Canonical.Name
+	})
+	return groups, nil
+}
+
+// buildGroup picks the canonical POI and keeps the members that are close
+// and similar to it: chains of pairs may link different objects.
+func buildGroup(group []*domain.POI, opts DedupOptions) (DuplicateGroup, bool) {
+	sort.Slice(group, func(i, j int) bool {
+		return canonicalBefore(group[i], group[j])
+	})
+	canonical := group[0]
+
+	g := DuplicateGroup{Canonical: *canonical}
+	names := map[string]bool{normalizeName(canonical.Name): true}
+	g.Aliases = append(g.Aliases, canonical.Aliases...)
+	for _, alias := range canonical.Aliases {
+		names[normalizeName(alias)] = true
+	}
+
+	for _, poi := range group[1:] {
+		distance := haversineKm(
+			domain.Coordinate{Lat: canonical.Lat, Lng: canonical.Lng},
+			domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng},
+		) * 1000
+		similarity := nameSimilarity(canonical, poi)
+		if distance > opts.MaxDistanceM || similarity < opts.MinSimilarity {
+			continue
+		}
+
+		g.Duplicates = append(g.Duplicates, Duplicate{POI: *poi, DistanceM: distance, Similarity: similarity})
+		for _, name := range append([]string{poi.Name}, poi.Aliases...) {
+			if key := normalizeName(name); !names[key] {
+				names[key] = true
+				g.Aliases = append(g.Aliases, name)
+			}
+		}
+	}
+
+	return g, len(g.Duplicates) > 0
+}
+
+// canonicalBefore prefers the POI that already absorbed duplicates, then
+// the more popular and more complete one, then areas over points, so that
+// repeated runs keep the same canonical POI.
+func canonicalBefore(a, b *domain.POI) bool {
+	if (len(a.Aliases) > 0) != (len(b.Aliases) > 0) {
+		return len(a.Aliases) > 0
+	}
+	if a.PopularityScore != b.PopularityScore {
+		return a.PopularityScore > b.PopularityScore
+	}
+	if ca, cb := completeness(a), completeness(b); ca != cb {
+		return ca > cb
+	}
+	if (a.OsmType == "node") != (b.OsmType == "node") {
+		return b.OsmType == "node"
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func completeness(p *domain.POI) int {
+	n := len(p.Tags)
+	for _, filled := range []bool{
+		p.Description != "", p.ShortDescription != "", p.Address != "",
+		p.HistoricalPeriod != "", p.YearBuilt != nil, p.OpeningHours != nil,
+	} {
+		if filled {
+			n += 2
+		}
+	}
+	return n
+}
+
+// nameSimilarity compares the best matching names of two POIs, aliases
+// included.
+func nameSimilarity(a, b *domain.POI) float64 {
+	best := 0.0
+	for _, x := range append([]string{a.Name}, a.Aliases...) {
+		for _, y := range append([]string{b.Name}, b.Aliases...) {
+			tx, kx := nameTokens(x)
+			ty, ky := nameTokens(y)
+			if kx != "" && ky != "" && kx != ky {
+				continue
+			}
+			if s := tokenSimilarity(tx, ty); s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// tokenSimilarity is the Jaccard index of the token sets, or 1 when one
+// name of at least two words is contained in the other ("Храм Христа
+// Спасителя" and "Кафедральный соборный храм Христа Спасителя").
+func tokenSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+
+	smaller := len(a)
+	if len(b) < smaller {
+		smaller = len(b)
+	}
+	if common == smaller && smaller >= 2 {
+		return 1
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// nameTokens splits a name into word stems without generic words. Stems
+// are word prefixes, which is enough to match Russian case forms
+// ("Троицы", "Троица"). Names of only generic words ("Памятник") have no
+// tokens and never match: nearby objects often share them. The kind is
+// that of the first generic word.
+func nameTokens(name string) (map[string]bool, string) {
+	words := strings.Fields(normalizeName(name))
+
+	tokens := make(map[string]bool, len(words))
+	kind := ""
+	for _, w := range words {
+		k, generic := genericNameWords[w]
+		if !generic {
+			tokens[stem(w)] = true
+		} else if kind == "" {
+			kind = k
+		}
+	}
+	return tokens, kind
+}
+
+func normalizeName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func stem(word string) string {
+	runes := []rune(word)
+	if len(runes) > dedupStemLength {
+		runes = runes[:dedupStemLength]
+	}
+	return string(runes)
+}
+
+func (s *DedupService) merge(ctx context.Context, g DuplicateGroup) error {
+	ids := make([]uuid.UUID, len(g.Duplicates))
+	for i, d := range g.Duplicates {
+		ids[i] = d.POI.ID
+	}
+
+	if err := s.poiRepo.MergeDuplicates(ctx, g.Canonical.ID, g.Aliases, ids); err != nil {
+		return err
+	}
+
+	if s.qdrantRepo == nil {
+		return nil
+	}
+	if err := s.qdrantRepo.Delete(ctx, ids); err != nil {
+		log.Printf("Warning: Failed to remove duplicates of %s from Qdrant: %v", g.Canonical.ID, err)
+	}
+	if err := s.reindex(ctx, []uuid.UUID{g.Canonical.ID}); err != nil {
+		log.Printf("Warning: Failed to index %s in Qdrant: %v", g.Canonical.ID, err)
+	}
+	// The canonical POI now holds the facts of its duplicates
+	if err := indexFacts(ctx, s.factRepo, s.poiRepo, s.qdrantRepo, []uuid.UUID{g.Canonical.ID}); err != nil {
+		log.Printf("Warning: Failed to index facts of %s in Qdrant: %v", g.Canonical.ID, err)
+	}
+	return nil
+}
+
+func (s *DedupService) reindex(ctx context.Context, ids []uuid.UUID) error {
+	if s.qdrantRepo == nil {
+		return nil
+	}
+	pois, err := s.poiRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return s.qdrantRepo.IndexBatch(ctx, pois)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dremotha/mapbot/internal/infrastructure/metrics"
+	"github.com/dremotha/mapbot/internal/repository"
+)
+
+const embeddingCacheName = "embedding"
+
+// CachedEmbeddingClient puts an in-process LRU and Redis in front of the
+// embedding service. Only texts missing from both tiers are sent to the
+// embedding service.
+type CachedEmbeddingClient struct {
+	embedder repository.Embedder
+	cache    *CacheManager
+	local    *lruCache[[]float32]
+}
+
+// NewCachedEmbeddingClient wraps the embedder. cache may be nil, in which
+// case only the in-process tier of size entries is used.
+func NewCachedEmbeddingClient(embedder repository.Embedder, cache *CacheManager, size int) *CachedEmbeddingClient {
+	return &CachedEmbeddingClient{
+		embedder: embedder,
+		cache:    cache,
+		local:    newLRUCache[[]float32](size),
+	}
+}
+
+func (c *CachedEmbeddingClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	key := EmbeddingCacheKey(text)
+
+	if vector, ok := c.local.Get(key); ok {
+		metrics.CacheRequestsTotal.WithLabelValues(embeddingCacheName, metrics.CacheHit).Inc()
+		return vector, nil
+	}
+
+	if c.cache != nil {
+		var vector []float32
+		if err := c.cache.Get(ctx, key, &vector); err == nil {
+			metrics.CacheRequestsTotal.WithLabelValues(embeddingCacheName, metrics.CacheHit).Inc()
+			c.local.Set(key, vector)
+			return vector, nil
+		}
+	}
+	metrics.CacheRequestsTotal.WithLabelValues(embeddingCacheName, metrics.CacheMiss).Inc()
+
+	vector, err := c.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	c.local.Set(key, vector)
+	if c.cache != nil {
+		c.cache.SetAsync(ctx, key, vector, EmbeddingCacheTTL)
+	}
+	return vector, nil
+}
+
+// EmbedBatch returns vectors in the order of texts. Cached vectors are
+// looked up in bulk; the remaining unique texts go to the embedding service
+// in a single batch.
+func (c *CachedEmbeddingClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+
+	var missing []int
+	for i, text := range texts {
+		keys[i] = EmbeddingCacheKey(text)
+		if vector, ok := c.local.Get(keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	if c.cache != nil && len(missing) > 0 {
+		missing = c.fillFromRedis(ctx, keys, missing, vectors)
+	}
+
+	hits := len(texts) - len(missing)
+	metrics.CacheRequestsTotal.WithLabelValues(embeddingCacheName, metrics.CacheHit).Add(float64(hits))
+	metrics.CacheRequestsTotal.WithLabelValues(embeddingCacheName, metrics.CacheMiss).Add(float64(len(missing)))
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	// The same text may appear several times in a batch.
+	positions := make(map[string][]int, len(missing))
+	var uniqueTexts []string
+	for _, i := range missing {
+		if _, ok := positions[keys[i]]; !ok {
+			uniqueTexts = append(uniqueTexts, texts[i])
+		}
+		positions[keys[i]] = append(positions[keys[i]], i)
+	}
+
+	embedded, err := c.embedder.EmbedBatch(ctx, uniqueTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(uniqueTexts) {
+		return nil, fmt.Errorf("embed batch: got %d vectors for %d texts", len(embedded), len(uniqueTexts))
+	}
+
+	toStore := make(map[string]interface{}, len(uniqueTexts))
+	for j, text := range uniqueTexts {
+		key := EmbeddingCacheKey(text)
+		for _, i := range positions[key] {
+			vectors[i] = embedded[j]
+		}
+		c.local.Set(key, embedded[j])
+		toStore[key] = embedded[j]
+	}
+
+	if c.cache != nil {
+		c.cache.SetManyAsync(ctx, toStore, EmbeddingCacheTTL)
+	}
+
+	return vectors, nil
+}
+
+// fillFromRedis fills vectors found in Redis and returns the indexes that
+// are still missing. Redis errors leave all indexes missing.
+func (c *CachedEmbeddingClient) fillFromRedis(ctx context.Context, keys []string, missing []int, vectors [][]float32) []int {
+	lookup := make([]string, len(missing))
+	for j, i := range missing {
+		lookup[j] = keys[i]
+	}
+
+	values, err := c.cache.GetMany(ctx, lookup)
+	if err != nil {
+		return missing
+	}
+
+	var stillMissing []int
+	for j, i := range missing {
+		var vector []float32
+		if values[j] == nil || c.cache.Decode(values[j], &vector) != nil {
+			stillMissing = append(stillMissing, i)
+			continue
+		}
+		vectors[i] = vector
+		c.local.Set(keys[i], vector)
+	}
+	return stillMissing
+}
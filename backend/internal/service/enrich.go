@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/wiki"
+)
+
+const enrichBatchSize = 200
+
+// WikiDumps are the paths of locally downloaded dumps. Wikidata is
+// required; without Wikipedia descriptions come from Wikidata only.
+type WikiDumps struct {
+	Wikidata  string
+	Wikipedia string
+}
+
+type EnrichStats struct {
+	// Linked POIs have a wikidata or wikipedia tag.
+	Linked    int
+	Entities  int
+	Abstracts int
+	Changed   int
+}
+
+// EnrichService fills descriptions, years of construction, heritage status
+// and images of POIs from the Wikidata and Wikipedia pages their OSM tags
+// link to.
+type EnrichService struct {
+	poiRepo    *repository.POIRepository
+	factRepo   *repository.HistoricalFactRepository
+	qdrantRepo *repository.QdrantPOIRepository
+}
+
+// NewEnrichService creates the service. qdrantRepo may be nil, in which
+// case the vector index is not updated.
+func NewEnrichService(poiRepo *repository.POIRepository, factRepo *repository.HistoricalFactRepository, qdrantRepo *repository.QdrantPOIRepository) *EnrichService {
+	return &EnrichService{poiRepo: poiRepo, factRepo: factRepo, qdrantRepo: qdrantRepo}
+}
+
+type poiLinks struct {
+	id       uuid.UUID
+	wikidata string
+	title    string
+}
+
+// Run enriches the POIs of a region and re-indexes the changed ones.
+func (s *EnrichService) Run(ctx context.Context, region string, dumps WikiDumps) (EnrichStats, error) {
+	var stats EnrichStats
+
+	rows, err := s.poiRepo.WikiLinks(ctx, region)
+	if err != nil {
+		return stats, err
+	}
+
+	// A POI keeps its own links; merged duplicates only fill the gaps
+	var pois []*poiLinks
+	byID := make(map[uuid.UUID]*poiLinks)
+	entityIDs := make(map[string]bool)
+	for _, row := range rows {
+		p := byID[row.POIID]
+		if p == nil {
+			p = &poiLinks{id: row.POIID}
+			byID[row.POIID] = p
+			pois = append(pois, p)
+		}
+		if p.wikidata == "" && row.Wikidata != "" {
+			p.wikidata = row.Wikidata
+			entityIDs[row.Wikidata] = true
+		}
+		if p.title == "" {
+			p.title = wiki.RuTitle(row.Wikipedia)
+		}
+	}
+	stats.Linked = len(pois)
+	if len(pois) == 0 {
+		return stats, nil
+	}
+
+	log.Printf("Reading %d Wikidata entities from %s...", len(entityIDs), dumps.Wikidata)
+	entities, err := wiki.ReadEntities(ctx, dumps.Wikidata, entityIDs)
+	if err != nil {
+		return stats, err
+	}
+	stats.Entities = len(entities)
+
+	var abstracts map[string]wiki.Abstract
+	if dumps.Wikipedia != "" {
+		titles := make(map[string]bool)
+		for _, p := range pois {
+			if p.title == "" && entities[p.wikidata] != nil {
+				p.title = wiki.NormalizeTitle(entities[p.wikidata].RuWikiTitle)
+			}
+			if p.title != "" {
+				titles[p.title] = true
+			}
+		}
+
+		log.Printf("Reading %d Wikipedia abstracts from %s...", len(titles), dumps.Wikipedia)
+		abstracts, err = wiki.ReadAbstracts(ctx, dumps.Wikipedia, titles)
+		if err != nil {
+			return stats, err
+		}
+		stats.Abstracts = len(abstracts)
+	}
+
+	items := make([]repository.Enrichment, 0, len(pois))
+	for _, p := range pois {
+		items = append(items, enrichment(p, entities[p.wikidata], abstracts))
+	}
+
+	for i := 0; i < len(items); i += enrichBatchSize {
+		end := i + enrichBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		changed, err := s.poiRepo.SaveEnrichment(ctx, items[i:end])
+		if err != nil {
+			return stats, fmt.Errorf("save enrichment: %w", err)
+		}
+		stats.Changed += len(changed)
+
+		if err := s.reindex(ctx, changed); err != nil {
+			log.Printf("Warning: Failed to index enriched POIs: %v", err)
+		}
+
+		// Facts from Wikidata were replaced for the whole batch
+		batchIDs := make([]uuid.UUID, 0, end-i)
+		for _, item := range items[i:end] {
+			batchIDs = append(batchIDs, item.POIID)
+		}
+		if err := indexFacts(ctx, s.factRepo, s.poiRepo, s.qdrantRepo, batchIDs); err != nil {
+			log.Printf("Warning: Failed to index facts: %v", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// enrichment builds the data of a POI. The Wikipedia abstract is preferred
+// to the short Wikidata description.
+func enrichment(p *poiLinks, e *wiki.Entity, abstracts map[string]wiki.Abstract) repository.Enrichment {
+	item := repository.Enrichment{POIID: p.id}
+
+	if a, ok := abstracts[p.title]; ok {
+		item.Description = a.Text
+	}
+	if e == nil {
+		return item
+	}
+
+	if item.Description == "" {
+		item.Description = e.Description
+	}
+	item.Image = e.Image
+
+	if e.Inception != nil {
+		year := e.Inception.Year
+		item.YearBuilt = &year
+		item.YearBuiltPrecision = e.Inception.Precision
+		item.Facts = append(item.Facts, domain.HistoricalFact{
+			FactType:  "inception",
+			Title:     "Основание",
+			Content:   fmt.Sprintf("Основан %s.", yearPhrase(year, e.Inception.Precision)),
+			YearFrom:  &year,
+			SourceURL: wiki.EntityURL(e.ID),
+			Source:    repository.FactSourceWikidata,
+		})
+	}
+
+	if len(e.HeritageLabels) > 0 {
+		item.Heritage = strings.Join(e.HeritageLabels, "; ")
+		item.Facts = append(item.Facts, domain.HistoricalFact{
+			FactType:  "heritage",
+			Title:     "Охранный статус",
+			Content:   upperFirst(item.Heritage) + ".",
+			SourceURL: wiki.EntityURL(e.ID),
+			Source:    repository.FactSourceWikidata,
+		})
+	}
+
+	return item
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return strings.ToUpper(string(r[:1])) + string(r[1:])
+}
+
+func (s *EnrichService) reindex(ctx context.Context, ids []uuid.UUID) error {
+	if s.qdrantRepo == nil || len(ids) == 0 {
+		return nil
+	}
+	pois, err := s.poiRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return s.qdrantRepo.IndexBatch(ctx, pois)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+)
+
+// HistoricalFactService serves the facts of POIs and keeps them indexed
+// for semantic search.
+type HistoricalFactService struct {
+	factRepo   *repository.HistoricalFactRepository
+	poiRepo    *repository.POIRepository
+	qdrantRepo *repository.QdrantPOIRepository
+}
+
+// NewHistoricalFactService creates the service. qdrantRepo may be nil, in
+// which case facts are not indexed.
+func NewHistoricalFactService(factRepo *repository.HistoricalFactRepository, poiRepo *repository.POIRepository, qdrantRepo *repository.QdrantPOIRepository) *HistoricalFactService {
+	return &HistoricalFactService{factRepo: factRepo, poiRepo: poiRepo, qdrantRepo: qdrantRepo}
+}
+
+// ListByPOI returns the facts of a POI in chronological order.
+func (s *HistoricalFactService) ListByPOI(ctx context.Context, poiID uuid.UUID) ([]domain.HistoricalFact, error) {
+	return s.factRepo.ListByPOI(ctx, poiID)
+}
+
+// Index replaces the indexed facts of the POIs with the stored ones. Fact
+// points carry the name and location of their POI, so they are reindexed
+// whenever the POI changes.
+func (s *HistoricalFactService) Index(ctx context.Context, poiIDs []uuid.UUID) error {
+	return indexFacts(ctx, s.factRepo, s.poiRepo, s.qdrantRepo, poiIDs)
+}
+
+// indexFacts replaces the indexed facts of the POIs. Hidden POIs are
+// skipped: their facts left the index with their points.
+func indexFacts(ctx context.Context, factRepo *repository.HistoricalFactRepository, poiRepo *repository.POIRepository, qdrantRepo *repository.QdrantPOIRepository, poiIDs []uuid.UUID) error {
+	if qdrantRepo == nil || len(poiIDs) == 0 {
+		return nil
+	}
+
+	pois, err := poiRepo.GetByIDs(ctx, poiIDs)
+	if err != nil {
+		return err
+	}
+	facts, err := factRepo.ListByPOIs(ctx, poiIDs)
+	if err != nil {
+		return err
+	}
+	return qdrantRepo.IndexFacts(ctx, pois, facts)
+}
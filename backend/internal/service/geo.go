@@ -0,0 +1,95 @@
+package service
+
+import (
+	"math"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+const earthRadiusKm = 6371.0
+
+// haversineKm returns the great-circle distance between two points.
+func haversineKm(a, b domain.Coordinate) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func lineLengthKm(line []domain.Coordinate) float64 {
+	total := 0.0
+	for i := 1; i < len(line); i++ {
+		total += haversineKm(line[i-1], line[i])
+	}
+	return total
+}
+
+// distanceToLineKm returns the distance from p to the nearest segment of
+// line. Segments are projected onto a plane tangent at p, which is accurate
+// for the short distances it is used for.
+func distanceToLineKm(p domain.Coordinate, line []domain.Coordinate) float64 {
+	if len(line) == 0 {
+		return math.Inf(1)
+	}
+	if len(line) == 1 {
+		return haversineKm(p, line[0])
+	}
+
+	kmPerLat := earthRadiusKm * math.Pi / 180
+	kmPerLng := kmPerLat * math.Cos(p.Lat*math.Pi/180)
+	project := func(c domain.Coordinate) (float64, float64) {
+		return (c.Lng - p.Lng) * kmPerLng, (c.Lat - p.Lat) * kmPerLat
+	}
+
+	best := math.Inf(1)
+	ax, ay := project(line[0])
+	for i := 1; i < len(line); i++ {
+		bx, by := project(line[i])
+		dx, dy := bx-ax, by-ay
+
+		// Closest point of the segment to the origin
+		t := 0.0
+		if l := dx*dx + dy*dy; l > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+		}
+		best = math.Min(best, math.Hypot(ax+t*dx, ay+t*dy))
+
+		ax, ay = bx, by
+	}
+	return best
+}
+
+// averageSpeedKmh is a rough door-to-door speed per mode, used where no
+// routing engine estimate is available.
+func averageSpeedKmh(mode domain.TransportMode) float64 {
+	switch mode {
+	case domain.TransportWalking:
+		return 4.5
+	case domain.TransportCycling:
+		return 14
+	default:
+		return 30
+	}
+}
+
+// destinationPoint returns the point at distanceKm from origin along the
+// given bearing (degrees clockwise from north).
+func destinationPoint(origin domain.Coordinate, bearingDeg, distanceKm float64) domain.Coordinate {
+	lat1 := origin.Lat * math.Pi / 180
+	lng1 := origin.Lng * math.Pi / 180
+	bearing := bearingDeg * math.Pi / 180
+	d := distanceKm / earthRadiusKm
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(bearing))
+	lng2 := lng1 + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+
+	return domain.Coordinate{
+		Lat: lat2 * 180 / math.Pi,
+		Lng: lng2 * 180 / math.Pi,
+	}
+}
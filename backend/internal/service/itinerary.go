@@ -0,0 +1,528 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+const (
+	// MaxItineraryDays caps the length of a planned trip.
+	MaxItineraryDays = 7
+
+	defaultDailyHours = 8.0
+	maxDailyHours     = 14.0
+	// defaultVisitMin is the time spent at a single POI.
+	defaultVisitMin = 45.0
+
+	kmeansIterations = 20
+)
+
+// itineraryParams are the settings shared by all days of an itinerary.
+type itineraryParams struct {
+	base       *domain.Coordinate
+	mode       domain.TransportMode
+	budget     float64
+	skipClosed bool
+}
+
+type dayPlan struct {
+	members   []int
+	departure time.Time
+	route     *domain.Route
+	closed    []int
+	visitMin  float64
+	totalMin  float64
+}
+
+// PlanItinerary splits POIs into daily routes. POIs are clustered by
+// location, one cluster per day, and every day is ordered with the trip
+// solver and scheduled against opening hours. POIs that do not fit the daily
+// budget, or are closed on their day when SkipClosed is set, are moved to
+// other days when possible and reported as unscheduled otherwise.
+func (s *RoutingService) PlanItinerary(ctx context.Context, req domain.ItineraryRequest, pois []domain.POI) (*domain.Itinerary, error) {
+	if len(pois) == 0 && len(req.POIIDs) > 0 {
+		pois = s.loadPOIs(ctx, req.POIIDs)
+	}
+	if len(pois) == 0 {
+		return nil, fmt.Errorf("не указаны точки интереса")
+	}
+
+	days := req.Days
+	if days <= 0 {
+		return nil, fmt.Errorf("укажите количество дней")
+	}
+	if days > MaxItineraryDays {
+		return nil, fmt.Errorf("можно спланировать не более %d дней", MaxItineraryDays)
+	}
+	if days > len(pois) {
+		days = len(pois)
+	}
+
+	hours := req.DailyHours
+	if hours <= 0 {
+		hours = defaultDailyHours
+	}
+	if hours > maxDailyHours {
+		hours = maxDailyHours
+	}
+
+	params := itineraryParams{
+		base:       req.Base,
+		mode:       req.Mode,
+		budget:     hours * 60,
+		skipClosed: req.SkipClosed,
+	}
+	if params.mode == "" {
+		params.mode = domain.TransportDriving
+	}
+
+	startTime := s.defaultItineraryStart()
+	if req.StartTime != nil {
+		startTime = req.StartTime.In(s.location)
+	}
+
+	log.Printf("Planning itinerary: %d POIs, %d days, %.0f min/day, mode=%s", len(pois), days, params.budget, params.mode)
+
+	clusters := orderClusters(pois, clusterPOIs(pois, days), req.Base)
+
+	var plans []*dayPlan
+	var overflow []int
+
+	for i, members := range clusters {
+		plan, dropped, err := s.fitDay(ctx, pois, members, startTime.AddDate(0, 0, i), params)
+		if err != nil {
+			log.Printf("Itinerary day build failed: %v", err)
+			return nil, s.formatRoutingError(err)
+		}
+		plans = append(plans, plan)
+		overflow = append(overflow, dropped...)
+	}
+
+	var unscheduled []domain.POI
+	for _, idx := range overflow {
+		if !s.placeOverflow(ctx, pois, plans, idx, params) {
+			unscheduled = append(unscheduled, pois[idx])
+		}
+	}
+
+	result := &domain.Itinerary{Mode: params.mode, Unscheduled: unscheduled}
+	scheduled := 0
+
+	for i, plan := range plans {
+		if len(plan.members) == 0 {
+			continue
+		}
+
+		day := domain.ItineraryDay{
+			Day:      i + 1,
+			Date:     plan.departure.Format("2006-01-02"),
+			Route:    plan.route,
+			VisitMin: plan.visitMin,
+			TotalMin: plan.totalMin,
+		}
+		for _, wp := range plan.route.Waypoints {
+			if wp.POI != nil {
+				day.POIs = append(day.POIs, *wp.POI)
+			}
+		}
+
+		scheduled += len(day.POIs)
+		result.Days = append(result.Days, day)
+	}
+
+	result.Message = fmt.Sprintf("Маршрут на %s: %s",
+		pluralRu(len(result.Days), "день", "дня", "дней"),
+		pluralRu(scheduled, "место", "места", "мест"))
+	if len(unscheduled) > 0 {
+		result.Message += fmt.Sprintf(", не поместилось в расписание: %d", len(unscheduled))
+	}
+
+	return result, nil
+}
+
+// defaultItineraryStart is 09:00 tomorrow in local time.
+func (s *RoutingService) defaultItineraryStart() time.Time {
+	tomorrow := time.Now().In(s.location).AddDate(0, 0, 1)
+	return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 9, 0, 0, 0, s.location)
+}
+
+// fitDay plans a day and drops POIs until it fits: closed POIs first when
+// skipClosed is set, then the POIs farthest from the day's centre. The last
+// open POI is always kept.
+func (s *RoutingService) fitDay(ctx context.Context, pois []domain.POI, members []int, departure time.Time, params itineraryParams) (*dayPlan, []int, error) {
+	members = append([]int(nil), members...)
+	var dropped []int
+
+	for {
+		if len(members) == 0 {
+			return &dayPlan{departure: departure}, dropped, nil
+		}
+
+		plan, err := s.planDay(ctx, pois, members, departure, params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if params.skipClosed && len(plan.closed) > 0 {
+			closed := make(map[int]bool, len(plan.closed))
+			for _, idx := range plan.closed {
+				closed[idx] = true
+			}
+
+			open := members[:0]
+			for _, idx := range members {
+				if closed[idx] {
+					dropped = append(dropped, idx)
+				} else {
+					open = append(open, idx)
+				}
+			}
+			members = open
+			continue
+		}
+
+		if plan.totalMin <= params.budget || len(members) == 1 {
+			return plan, dropped, nil
+		}
+
+		center := centroid(pois, members)
+		farthest := 0
+		for i, idx := range members {
+			if haversineKm(center, poiLocation(pois[idx])) > haversineKm(center, poiLocation(pois[members[farthest]])) {
+				farthest = i
+			}
+		}
+
+		dropped = append(dropped, members[farthest])
+		members = append(members[:farthest], members[farthest+1:]...)
+	}
+}
+
+// placeOverflow adds a POI to the closest day that still fits the budget
+// with it. Days without POIs are as far as the base, or tried last without
+// one.
+func (s *RoutingService) placeOverflow(ctx context.Context, pois []domain.POI, plans []*dayPlan, idx int, params itineraryParams) bool {
+	location := poiLocation(pois[idx])
+	visit := visitDurationMin(pois[idx])
+
+	candidates := make([]int, 0, len(plans))
+	for d, plan := range plans {
+		if plan.totalMin+visit <= params.budget {
+			candidates = append(candidates, d)
+		}
+	}
+	distance := func(plan *dayPlan) float64 {
+		if len(plan.members) > 0 {
+			return haversineKm(location, centroid(pois, plan.members))
+		}
+		if params.base != nil {
+			return haversineKm(location, *params.base)
+		}
+		return math.Inf(1)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(plans[candidates[i]]) < distance(plans[candidates[j]])
+	})
+
+	for _, d := range candidates {
+		members := append(append([]int(nil), plans[d].members...), idx)
+		plan, err := s.planDay(ctx, pois, members, plans[d].departure, params)
+		if err != nil {
+			log.Printf("Itinerary overflow placement failed: %v", err)
+			continue
+		}
+		if plan.totalMin > params.budget || (params.skipClosed && len(plan.closed) > 0) {
+			continue
+		}
+
+		plans[d] = plan
+		return true
+	}
+
+	return false
+}
+
+// planDay orders and schedules the day's POIs. With a base the day starts
+// and ends there, otherwise it runs between the POI farthest from the
+// day's centre and the POI farthest from that one, so the trip crosses
+// the day's area once.
+func (s *RoutingService) planDay(ctx context.Context, pois []domain.POI, members []int, departure time.Time, params itineraryParams) (*dayPlan, error) {
+	order := append([]int(nil), members...)
+	if params.base == nil {
+		center := centroid(pois, members)
+		start := 0
+		for i, idx := range order {
+			if haversineKm(center, poiLocation(pois[idx])) > haversineKm(center, poiLocation(pois[order[start]])) {
+				start = i
+			}
+		}
+		order[0], order[start] = order[start], order[0]
+
+		end := len(order) - 1
+		for i := 1; i < len(order); i++ {
+			if haversineKm(poiLocation(pois[order[0]]), poiLocation(pois[order[i]])) > haversineKm(poiLocation(pois[order[0]]), poiLocation(pois[order[end]])) {
+				end = i
+			}
+		}
+		order[len(order)-1], order[end] = order[end], order[len(order)-1]
+	}
+
+	dayPOIs := make([]domain.POI, len(order))
+	dwell := make([]float64, 0, len(order)+2)
+	if params.base != nil {
+		dwell = append(dwell, 0)
+	}
+	for i, idx := range order {
+		dayPOIs[i] = pois[idx]
+		dwell = append(dwell, visitDurationMin(pois[idx]))
+	}
+
+	plan := &dayPlan{members: members, departure: departure}
+	for _, poi := range dayPOIs {
+		plan.visitMin += visitDurationMin(poi)
+	}
+
+	if params.base == nil && len(dayPOIs) == 1 {
+		plan.route = &domain.Route{
+			Mode: params.mode,
+			Waypoints: []domain.Waypoint{{
+				POI:      &dayPOIs[0],
+				Location: poiLocation(dayPOIs[0]),
+				Name:     dayPOIs[0].Name,
+			}},
+			Sequence: []int{0},
+		}
+	} else {
+		waypoints := make([]domain.Coordinate, 0, len(dayPOIs)+2)
+		if params.base != nil {
+			waypoints = append(waypoints, *params.base)
+		}
+		for _, poi := range dayPOIs {
+			waypoints = append(waypoints, poiLocation(poi))
+		}
+		if params.base != nil {
+			waypoints = append(waypoints, *params.base)
+		}
+
+		route, err := s.trip(ctx, waypoints, params.mode, departure, dwell)
+		if err != nil {
+			return nil, err
+		}
+		attachPOIs(route, dayPOIs, params.base != nil)
+		plan.route = route
+	}
+
+	closed := s.scheduleTour(plan.route, departure)
+	closedIDs := make(map[uuid.UUID]bool, len(closed))
+	for _, poi := range closed {
+		closedIDs[poi.ID] = true
+	}
+	for _, idx := range members {
+		if closedIDs[pois[idx].ID] {
+			plan.closed = append(plan.closed, idx)
+		}
+	}
+
+	plan.totalMin = tourEnd(plan.route, departure).Sub(departure).Minutes()
+
+	return plan, nil
+}
+
+// loadPOIs fetches POIs by ID, skipping invalid and unknown ones.
+func (s *RoutingService) loadPOIs(ctx context.Context, ids []string) []domain.POI {
+	pois := make([]domain.POI, 0, len(ids))
+
+	for _, idStr := range ids {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			log.Printf("Invalid POI ID: %s", idStr)
+			continue
+		}
+
+		poi, err := s.poiRepo.GetByID(ctx, id)
+		if err != nil {
+			log.Printf("POI not found: %s", idStr)
+			continue
+		}
+
+		pois = append(pois, *poi)
+	}
+
+	return pois
+}
+
+// clusterPOIs groups POIs into k geographic clusters with k-means. Initial
+// centres are picked deterministically as mutually distant POIs.
+func clusterPOIs(pois []domain.POI, k int) [][]int {
+	points := make([]domain.Coordinate, len(pois))
+	for i, poi := range pois {
+		points[i] = poiLocation(poi)
+	}
+
+	all := make([]int, len(pois))
+	for i := range all {
+		all[i] = i
+	}
+	mean := centroid(pois, all)
+
+	centers := make([]domain.Coordinate, 0, k)
+	first := 0
+	for i, p := range points {
+		if haversineKm(mean, p) > haversineKm(mean, points[first]) {
+			first = i
+		}
+	}
+	centers = append(centers, points[first])
+
+	for len(centers) < k {
+		best, bestDist := 0, -1.0
+		for i, p := range points {
+			d := math.Inf(1)
+			for _, c := range centers {
+				d = math.Min(d, haversineKm(c, p))
+			}
+			if d > bestDist {
+				best, bestDist = i, d
+			}
+		}
+		centers = append(centers, points[best])
+	}
+
+	assignment := make([]int, len(points))
+	for iter := 0; iter < kmeansIterations; iter++ {
+		changed := false
+		for i, p := range points {
+			nearest := 0
+			for c := range centers {
+				if haversineKm(centers[c], p) < haversineKm(centers[nearest], p) {
+					nearest = c
+				}
+			}
+			if iter == 0 || assignment[i] != nearest {
+				changed = true
+			}
+			assignment[i] = nearest
+		}
+
+		if !changed {
+			break
+		}
+
+		sums := make([]domain.Coordinate, k)
+		counts := make([]int, k)
+		for i, p := range points {
+			sums[assignment[i]].Lat += p.Lat
+			sums[assignment[i]].Lng += p.Lng
+			counts[assignment[i]]++
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				centers[c] = domain.Coordinate{Lat: sums[c].Lat / float64(counts[c]), Lng: sums[c].Lng / float64(counts[c])}
+			}
+		}
+	}
+
+	clusters := make([][]int, k)
+	for i, c := range assignment {
+		clusters[c] = append(clusters[c], i)
+	}
+
+	result := clusters[:0]
+	for _, c := range clusters {
+		if len(c) > 0 {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// orderClusters arranges days as a nearest-neighbour chain starting from the
+// cluster closest to the base, or from the outermost one without a base.
+func orderClusters(pois []domain.POI, clusters [][]int, base *domain.Coordinate) [][]int {
+	if len(clusters) < 2 {
+		return clusters
+	}
+
+	centers := make([]domain.Coordinate, len(clusters))
+	for i, c := range clusters {
+		centers[i] = centroid(pois, c)
+	}
+
+	var current domain.Coordinate
+	if base != nil {
+		current = *base
+	} else {
+		all := make([]int, len(pois))
+		for i := range all {
+			all[i] = i
+		}
+		mean := centroid(pois, all)
+		outermost := 0
+		for i, c := range centers {
+			if haversineKm(mean, c) > haversineKm(mean, centers[outermost]) {
+				outermost = i
+			}
+		}
+		current = centers[outermost]
+	}
+
+	used := make([]bool, len(clusters))
+	ordered := make([][]int, 0, len(clusters))
+
+	for len(ordered) < len(clusters) {
+		next := -1
+		for i, c := range centers {
+			if used[i] {
+				continue
+			}
+			if next < 0 || haversineKm(current, c) < haversineKm(current, centers[next]) {
+				next = i
+			}
+		}
+		used[next] = true
+		ordered = append(ordered, clusters[next])
+		current = centers[next]
+	}
+
+	return ordered
+}
+
+func centroid(pois []domain.POI, members []int) domain.Coordinate {
+	var c domain.Coordinate
+	if len(members) == 0 {
+		return c
+	}
+	for _, idx := range members {
+		c.Lat += pois[idx].Lat
+		c.Lng += pois[idx].Lng
+	}
+	c.Lat /= float64(len(members))
+	c.Lng /= float64(len(members))
+	return c
+}
+
+func poiLocation(poi domain.POI) domain.Coordinate {
+	return domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng}
+}
+
+// pluralRu formats n with the matching Russian noun form, e.g. 1 день,
+// 3 дня, 5 дней.
+func pluralRu(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return fmt.Sprintf("%d %s", n, one)
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return fmt.Sprintf("%d %s", n, few)
+	default:
+		return fmt.Sprintf("%d %s", n, many)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a size-bounded in-process cache safe for concurrent use.
+// Entries are evicted when either the entry count or the total cost exceeds
+// its limit.
+type lruCache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	maxCost  int
+	cost     func(V) int
+	total    int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+	cost  int
+}
+
+func newLRUCache[V any](capacity int) *lruCache[V] {
+	return newSizedLRUCache[V](capacity, 0, nil)
+}
+
+// newSizedLRUCache also limits the sum of cost(value). A zero maxCost
+// disables the cost limit.
+func newSizedLRUCache[V any](capacity, maxCost int, cost func(V) int) *lruCache[V] {
+	return &lruCache[V]{
+		capacity: capacity,
+		maxCost:  maxCost,
+		cost:     cost,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).value, true
+	}
+
+	var zero V
+	return zero, false
+}
+
+func (c *lruCache[V]) Set(key string, value V) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	cost := 0
+	if c.cost != nil {
+		cost = c.cost(value)
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[V])
+		c.total += cost - entry.cost
+		entry.value = value
+		entry.cost = cost
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, cost: cost})
+		c.total += cost
+	}
+
+	for c.order.Len() > c.capacity || (c.maxCost > 0 && c.total > c.maxCost) {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *lruCache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Purge removes all entries.
+func (c *lruCache[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.total = 0
+}
+
+func (c *lruCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *lruCache[V]) removeElement(el *list.Element) {
+	entry := el.Value.(*lruEntry[V])
+	c.order.Remove(el)
+	delete(c.items, entry.key)
+	c.total -= entry.cost
+}
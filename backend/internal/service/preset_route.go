@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
+	"github.com/dremotha/mapbot/internal/presets"
+	"github.com/dremotha/mapbot/internal/repository"
+)
+
+const (
+	PresetRouteCacheTTL = 24 * time.Hour
+
+	// presetStopMatchRadiusM bounds the name lookup of stops given by
+	// name and coordinates.
+	presetStopMatchRadiusM = 500
+)
+
+type PresetRouteService struct {
+	presetRepo *repository.PresetRouteRepository
+	poiRepo    *repository.POIRepository
+	osrmClient *osrm.Client
+	cache      *CacheManager
+}
+
+// NewPresetRouteService creates the service. cache may be nil, in which case
+// route geometry is built on every request. osrmClient may be nil when the
+// service only imports presets, which then get no distance.
+func NewPresetRouteService(presetRepo *repository.PresetRouteRepository, poiRepo *repository.POIRepository, osrmClient *osrm.Client, cache *CacheManager) *PresetRouteService {
+	return &PresetRouteService{
+		presetRepo: presetRepo,
+		poiRepo:    poiRepo,
+		osrmClient: osrmClient,
+		cache:      cache,
+	}
+}
+
+func (s *PresetRouteService) List(ctx context.Context, filters domain.PresetRouteFilters) ([]domain.PresetRoute, error) {
+	return s.presetRepo.List(ctx, filters)
+}
+
+// Get returns a preset route with the POIs of its stops attached.
+func (s *PresetRouteService) Get(ctx context.Context, id string) (*domain.PresetRoute, error) {
+	preset, err := s.presetRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachPOIs(ctx, preset)
+	return preset, nil
+}
+
+func (s *PresetRouteService) attachPOIs(ctx context.Context, preset *domain.PresetRoute) {
+	for i := range preset.Stops {
+		stop := &preset.Stops[i]
+		if stop.POIID == nil {
+			continue
+		}
+
+		poi, err := s.poiRepo.GetByID(ctx, *stop.POIID)
+		if err != nil {
+			log.Printf("Preset %s: POI %s not found: %v", preset.ID, stop.POIID, err)
+			continue
+		}
+		stop.POI = poi
+	}
+}
+
+// GetRoute builds the geometry of a preset route through its stops in the
+// curated order. Results are cached until the preset is re-imported or
+// POIs change; the POIs of the stops are only loaded to build the route.
+func (s *PresetRouteService) GetRoute(ctx context.Context, id string) (*domain.RouteResponse, error) {
+	preset, err := s.presetRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache == nil {
+		s.attachPOIs(ctx, preset)
+		return s.buildRoute(ctx, preset)
+	}
+
+	// Routes carry POI data, so they are dropped together with POI caches.
+	key := s.cache.VersionedKey(ctx, CacheNamespacePOI, PresetRouteCacheKey(preset.ID, preset.UpdatedAt))
+	var resp domain.RouteResponse
+	err = s.cache.GetOrCompute(ctx, "preset_route", key, PresetRouteCacheTTL, &resp, func(ctx context.Context) (interface{}, error) {
+		s.attachPOIs(ctx, preset)
+		return s.buildRoute(ctx, preset)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (s *PresetRouteService) buildRoute(ctx context.Context, preset *domain.PresetRoute) (*domain.RouteResponse, error) {
+	route, err := s.osrmClient.Route(ctx, stopLocations(preset), preset.Mode)
+	if err != nil {
+		log.Printf("Preset %s route build failed: %v", preset.ID, err)
+		return nil, fmt.Errorf("ошибка построения маршрута: %w", err)
+	}
+
+	pois := make([]domain.POI, 0, len(preset.Stops))
+	for i, stop := range preset.Stops {
+		route.Waypoints[i].Name = stop.Name
+		if stop.POI != nil {
+			route.Waypoints[i].POI = stop.POI
+			pois = append(pois, *stop.POI)
+		}
+	}
+
+	return &domain.RouteResponse{
+		Route:   route,
+		Message: fmt.Sprintf("%s: %.1f км, примерно %.0f минут", preset.Name, route.DistanceKm, route.DurationMin),
+		POIs:    pois,
+	}, nil
+}
+
+func stopLocations(preset *domain.PresetRoute) []domain.Coordinate {
+	locations := make([]domain.Coordinate, len(preset.Stops))
+	for i, stop := range preset.Stops {
+		locations[i] = stop.Location
+	}
+	return locations
+}
+
+// Import stores preset definitions, matching every stop to a POI. Stops
+// that cannot be matched keep their configured coordinates. The distance
+// of every route is measured through OSRM once, here.
+func (s *PresetRouteService) Import(ctx context.Context, defs []presets.Definition) (int, error) {
+	unresolved := 0
+
+	for _, def := range defs {
+		preset := &domain.PresetRoute{
+			ID:            def.ID,
+			Name:          def.Name,
+			Description:   def.Description,
+			Category:      def.Category,
+			Mode:          domain.TransportMode(def.Mode),
+			Difficulty:    def.Difficulty,
+			DurationHours: def.DurationHours,
+			Image:         def.Image,
+			Tags:          def.Tags,
+			Stops:         make([]domain.PresetStop, 0, len(def.Stops)),
+		}
+
+		for i, stopDef := range def.Stops {
+			stop, err := s.resolveStop(ctx, stopDef)
+			if err != nil {
+				return unresolved, fmt.Errorf("preset %s stop %d: %w", def.ID, i+1, err)
+			}
+			if stop.POIID == nil {
+				log.Printf("Preset %s: stop %d (%s) not matched to a POI", def.ID, i+1, stop.Name)
+				unresolved++
+			}
+
+			stop.Position = i
+			preset.Stops = append(preset.Stops, *stop)
+		}
+
+		if s.osrmClient != nil && len(preset.Stops) >= 2 {
+			route, err := s.osrmClient.Route(ctx, stopLocations(preset), preset.Mode)
+			if err != nil {
+				log.Printf("Warning: Preset %s: failed to measure distance: %v", preset.ID, err)
+			} else {
+				preset.DistanceKm = route.DistanceKm
+			}
+		}
+
+		if err := s.presetRepo.Upsert(ctx, preset); err != nil {
+			return unresolved, err
+		}
+
+		log.Printf("Imported preset route %s with %d stops", preset.ID, len(preset.Stops))
+	}
+
+	return unresolved, nil
+}
+
+func (s *PresetRouteService) resolveStop(ctx context.Context, def presets.Stop) (*domain.PresetStop, error) {
+	var poi *domain.POI
+	var err error
+
+	switch {
+	case def.POIID != "":
+		id, parseErr := uuid.Parse(def.POIID)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid poi_id: %w", parseErr)
+		}
+		poi, err = s.poiRepo.GetByID(ctx, id)
+	case def.OsmID != 0:
+		poi, err = s.poiRepo.GetByOsmID(ctx, def.OsmID)
+	default:
+		poi, err = s.poiRepo.FindNearestByName(ctx, def.Name,
+			domain.Coordinate{Lat: def.Lat, Lng: def.Lng}, presetStopMatchRadiusM)
+	}
+
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	stop := &domain.PresetStop{
+		Name:     def.Name,
+		Note:     def.Note,
+		Location: domain.Coordinate{Lat: def.Lat, Lng: def.Lng},
+	}
+
+	if poi != nil {
+		stop.POIID = &poi.ID
+		if stop.Name == "" {
+			stop.Name = poi.Name
+		}
+		stop.Location = domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng}
+		return stop, nil
+	}
+
+	if !def.HasLocation() {
+		return nil, fmt.Errorf("POI not found and no coordinates given")
+	}
+
+	return stop, nil
+}
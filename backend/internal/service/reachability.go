@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+const (
+	defaultReachableLimit  = 30
+	maxReachableCandidates = 200
+
+	isochroneBearings = 16
+	isochroneSteps    = 6
+)
+
+// reachabilityLimits holds the default and maximum travel time (minutes) and
+// an optimistic top speed used to bound the candidate search radius.
+var reachabilityLimits = map[domain.TransportMode]struct {
+	defaultMin float64
+	maxMin     float64
+	topSpeed   float64
+}{
+	domain.TransportWalking: {defaultMin: 20, maxMin: 120, topSpeed: 6},
+	domain.TransportCycling: {defaultMin: 30, maxMin: 120, topSpeed: 20},
+	domain.TransportDriving: {defaultMin: 30, maxMin: 180, topSpeed: 70},
+}
+
+// FindReachable returns POIs reachable from the request location within the
+// travel time budget, annotated with travel times, plus an approximate
+// polygon of the reachable area.
+func (s *RoutingService) FindReachable(ctx context.Context, req domain.ReachabilityRequest) (*domain.ReachabilityResponse, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = domain.TransportWalking
+	}
+
+	limits, ok := reachabilityLimits[mode]
+	if !ok {
+		return nil, invalidRequest(fmt.Sprintf("неподдерживаемый способ передвижения: %s", mode))
+	}
+
+	maxMinutes := req.MaxMinutes
+	if maxMinutes <= 0 {
+		maxMinutes = limits.defaultMin
+	}
+	if maxMinutes > limits.maxMin {
+		maxMinutes = limits.maxMin
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultReachableLimit
+	}
+
+	radiusKm := limits.topSpeed * maxMinutes / 60
+
+	log.Printf("Finding POIs reachable in %.0f min, mode=%s, radius=%.1fkm", maxMinutes, mode, radiusKm)
+
+	candidates, err := s.poiRepo.Search(ctx, domain.SearchFilters{
+		Categories: req.Categories,
+		Center:     &req.Location,
+		RadiusKm:   radiusKm,
+		Limit:      maxReachableCandidates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска мест: %w", err)
+	}
+
+	reachable := make([]domain.ReachablePOI, 0)
+
+	if len(candidates.POIs) > 0 {
+		destinations := make([]domain.Coordinate, len(candidates.POIs))
+		for i, poi := range candidates.POIs {
+			destinations[i] = domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng}
+		}
+
+		durations, distances, err := s.osrmClient.TableFrom(ctx, req.Location, destinations, mode)
+		if err != nil {
+			log.Printf("Table request failed: %v", err)
+			return nil, s.formatRoutingError(err)
+		}
+
+		for i, poi := range candidates.POIs {
+			if durations[i] < 0 || durations[i]/60 > maxMinutes {
+				continue
+			}
+
+			reachable = append(reachable, domain.ReachablePOI{
+				POI:        poi,
+				TravelMin:  durations[i] / 60,
+				DistanceKm: distances[i] / 1000,
+			})
+		}
+	}
+
+	sort.SliceStable(reachable, func(i, j int) bool {
+		return reachable[i].TravelMin < reachable[j].TravelMin
+	})
+
+	if len(reachable) > limit {
+		reachable = reachable[:limit]
+	}
+
+	area, err := s.reachableArea(ctx, req.Location, mode, maxMinutes, radiusKm)
+	if err != nil {
+		// The polygon only decorates the map, the POI list is still useful.
+		log.Printf("Reachable area failed: %v", err)
+	}
+
+	message := fmt.Sprintf("За %.0f минут можно добраться до %d мест", maxMinutes, len(reachable))
+	if len(reachable) == 0 {
+		message = fmt.Sprintf("За %.0f минут не удалось найти интересных мест поблизости", maxMinutes)
+	}
+
+	return &domain.ReachabilityResponse{
+		POIs:       reachable,
+		Area:       area,
+		Mode:       mode,
+		MaxMinutes: maxMinutes,
+		Message:    message,
+	}, nil
+}
+
+// reachableArea approximates the isochrone by probing points along evenly
+// spaced bearings and keeping the farthest reachable probe on each of them.
+func (s *RoutingService) reachableArea(ctx context.Context, origin domain.Coordinate, mode domain.TransportMode, maxMinutes, radiusKm float64) ([]domain.Coordinate, error) {
+	probes := make([]domain.Coordinate, 0, isochroneBearings*isochroneSteps)
+	for b := 0; b < isochroneBearings; b++ {
+		bearing := float64(b) * 360 / isochroneBearings
+		for step := 1; step <= isochroneSteps; step++ {
+			probes = append(probes, destinationPoint(origin, bearing, radiusKm*float64(step)/isochroneSteps))
+		}
+	}
+
+	durations, _, err := s.osrmClient.TableFrom(ctx, origin, probes, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	area := make([]domain.Coordinate, 0, isochroneBearings)
+	for b := 0; b < isochroneBearings; b++ {
+		point := origin
+		for step := 0; step < isochroneSteps; step++ {
+			idx := b*isochroneSteps + step
+			if durations[idx] >= 0 && durations[idx]/60 <= maxMinutes {
+				point = probes[idx]
+			}
+		}
+		area = append(area, point)
+	}
+
+	return area, nil
+}
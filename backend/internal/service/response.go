@@ -50,7 +50,8 @@ func (g *ResponseGenerator) GenerateSearchResponse(result *domain.SearchResult)
 	}
 }
 
-func (g *ResponseGenerator) GenerateRouteResponse(route *domain.Route, pois []domain.POI) domain.ChatResponse {
+func (g *ResponseGenerator) GenerateRouteResponse(resp *domain.RouteResponse) domain.ChatResponse {
+	route := resp.Route
 	message := fmt.Sprintf("Маршрут готов: %.1f км, примерно %.0f минут",
 		route.DistanceKm, route.DurationMin)
 
@@ -62,17 +63,21 @@ func (g *ResponseGenerator) GenerateRouteResponse(route *domain.Route, pois []do
 		message += fmt.Sprintf(". Точки: %s", strings.Join(names, " -> "))
 	}
 
+	for _, alt := range resp.Alternatives {
+		if alt.Summary != "" {
+			message += ". " + alt.Summary
+		}
+	}
+
 	return domain.ChatResponse{
 		Intent:  domain.IntentRoute,
 		Message: message,
-		Data: domain.RouteResponse{
-			Route: route,
-			POIs:  pois,
-		},
+		Data:    resp,
 	}
 }
 
-func (g *ResponseGenerator) GenerateInfoResponse(poi *domain.POI) domain.ChatResponse {
+// GenerateInfoResponse describes a POI and lists its facts as a timeline.
+func (g *ResponseGenerator) GenerateInfoResponse(poi *domain.POI, facts []domain.HistoricalFact) domain.ChatResponse {
 	message := poi.Name
 	if poi.Description != "" {
 		message = fmt.Sprintf("%s - %s", poi.Name, poi.Description)
@@ -81,17 +86,70 @@ func (g *ResponseGenerator) GenerateInfoResponse(poi *domain.POI) domain.ChatRes
 	}
 
 	if poi.YearBuilt != nil {
-		message += fmt.Sprintf(" Построен в %d году.", *poi.YearBuilt)
+		message += fmt.Sprintf(" Построен %s.", yearPhrase(*poi.YearBuilt, poi.YearBuiltPrecision))
+	}
+
+	if poi.YearDestroyed != nil {
+		message += fmt.Sprintf(" Утрачен %s.", yearPhrase(*poi.YearDestroyed, poi.YearDestroyedPrecision))
 	}
 
 	if poi.HistoricalPeriod != "" {
 		message += fmt.Sprintf(" Период: %s.", poi.HistoricalPeriod)
 	}
 
+	if len(facts) > 0 {
+		events := make([]string, 0, len(facts))
+		for _, f := range facts {
+			// The founding year has already been told
+			if f.FactType == "inception" && poi.YearBuilt != nil && f.YearFrom != nil && *f.YearFrom == *poi.YearBuilt {
+				continue
+			}
+			events = append(events, timelineEvent(f))
+		}
+		if len(events) > 0 {
+			message += " История: " + strings.Join(events, "; ") + "."
+		}
+	}
+
 	return domain.ChatResponse{
 		Intent:  domain.IntentInfo,
 		Message: message,
-		Data:    poi,
+		Data:    domain.POIInfo{POI: poi, Facts: facts},
+	}
+}
+
+// timelineEvent formats a fact as "1812–1814 — Title: content".
+func timelineEvent(f domain.HistoricalFact) string {
+	event := strings.TrimRight(strings.TrimSpace(f.Content), ".")
+	if f.Title != "" {
+		event = f.Title + ": " + event
+	}
+
+	switch {
+	case f.YearFrom != nil && f.YearTo != nil && *f.YearTo != *f.YearFrom:
+		return fmt.Sprintf("%d–%d — %s", *f.YearFrom, *f.YearTo, event)
+	case f.YearFrom != nil:
+		return fmt.Sprintf("%d — %s", *f.YearFrom, event)
+	case f.YearTo != nil:
+		return fmt.Sprintf("до %d — %s", *f.YearTo, event)
+	default:
+		return event
+	}
+}
+
+// yearPhrase words a year according to how exactly it is known.
+func yearPhrase(year int, precision domain.DatePrecision) string {
+	switch precision {
+	case domain.DatePrecisionCirca:
+		return fmt.Sprintf("около %d года", year)
+	case domain.DatePrecisionRange:
+		return fmt.Sprintf("не ранее %d года", year)
+	case domain.DatePrecisionDecade:
+		return fmt.Sprintf("в %d-х годах", year)
+	case domain.DatePrecisionCentury:
+		return fmt.Sprintf("в %s веке", domain.IntToRoman(domain.Century(year)))
+	default:
+		return fmt.Sprintf("в %d году", year)
 	}
 }
 
@@ -113,6 +171,97 @@ func (g *ResponseGenerator) GenerateErrorResponse(err error) domain.ChatResponse
 	}
 }
 
+var ordinalsRu = map[int]string{
+	2: "Второй",
+	3: "Третий",
+	4: "Четвёртый",
+}
+
+// poiNamesGenitive holds singular and plural genitive forms used in phrases
+// like "проходит мимо 3 церквей"; in the genitive 3 and 5 take the same form.
+var poiNamesGenitive = map[string][2]string{
+	"church":      {"церкви", "церквей"},
+	"cathedral":   {"собора", "соборов"},
+	"chapel":      {"часовни", "часовен"},
+	"monastery":   {"монастыря", "монастырей"},
+	"memorial":    {"мемориала", "мемориалов"},
+	"battlefield": {"поля сражения", "полей сражений"},
+	"fortress":    {"крепости", "крепостей"},
+	"bunker":      {"бункера", "бункеров"},
+	"manor":       {"усадьбы", "усадеб"},
+	"palace":      {"дворца", "дворцов"},
+	"tower":       {"башни", "башен"},
+	"ruins":       {"руин", "руин"},
+}
+
+// describeAlternative explains how the alternative route differs from the
+// main one, e.g. "Второй вариант на 1.2 км длиннее, но проходит мимо 3 церквей".
+func describeAlternative(number int, main, alt *domain.Route, extra []domain.POI) string {
+	name, ok := ordinalsRu[number]
+	if !ok {
+		name = fmt.Sprintf("Вариант %d", number)
+	} else {
+		name += " вариант"
+	}
+
+	diffKm := alt.DistanceKm - main.DistanceKm
+	diffMin := alt.DurationMin - main.DurationMin
+
+	var message string
+	switch {
+	case diffKm >= 0.05:
+		message = fmt.Sprintf("%s на %.1f км длиннее", name, diffKm)
+	case diffKm <= -0.05:
+		message = fmt.Sprintf("%s на %.1f км короче", name, -diffKm)
+	default:
+		message = fmt.Sprintf("%s примерно той же длины", name)
+	}
+
+	if diffMin >= 1 {
+		message += fmt.Sprintf(" и на %.0f мин дольше", diffMin)
+	} else if diffMin <= -1 {
+		message += fmt.Sprintf(" и на %.0f мин быстрее", -diffMin)
+	}
+
+	if names := describeExtraPOIs(extra); names != "" {
+		if diffKm >= 0.05 || diffMin >= 1 {
+			message += ", но проходит мимо " + names
+		} else {
+			message += " и проходит мимо " + names
+		}
+	}
+
+	return message
+}
+
+// describeExtraPOIs names the most common kind of POI that only the
+// alternative route passes by.
+func describeExtraPOIs(extra []domain.POI) string {
+	counts := make(map[string]int)
+	total := 0
+	for _, poi := range extra {
+		counts[poi.Subcategory]++
+		total++
+	}
+
+	if total == 0 {
+		return ""
+	}
 
+	best := ""
+	for sub, n := range counts {
+		if _, ok := poiNamesGenitive[sub]; !ok {
+			continue
+		}
+		if best == "" || n > counts[best] || (n == counts[best] && sub < best) {
+			best = sub
+		}
+	}
 
+	if best == "" {
+		return pluralRu(total, "исторического места", "исторических мест", "исторических мест")
+	}
 
+	forms := poiNamesGenitive[best]
+	return pluralRu(counts[best], forms[0], forms[1], forms[1])
+}
@@ -5,23 +5,43 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/dremotha/mapbot/internal/domain"
 	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
 	"github.com/dremotha/mapbot/internal/repository"
+	"github.com/dremotha/mapbot/internal/transit"
+)
+
+const (
+	// MaxRouteAlternatives caps the number of alternative routes per request.
+	MaxRouteAlternatives = 3
+
+	nearbyRouteBufferM = 300
+	nearbyRouteLimit   = 30
 )
 
 type RoutingService struct {
 	osrmClient *osrm.Client
 	poiRepo    *repository.POIRepository
+	// transitPlanner is nil when no GTFS feed is configured.
+	transitPlanner *transit.Planner
+	// location is the local time zone for opening hours.
+	location *time.Location
 }
 
-func NewRoutingService(osrmClient *osrm.Client, poiRepo *repository.POIRepository) *RoutingService {
+func NewRoutingService(osrmClient *osrm.Client, poiRepo *repository.POIRepository, transitPlanner *transit.Planner, location *time.Location) *RoutingService {
+	if location == nil {
+		location = time.Local
+	}
+
 	return &RoutingService{
-		osrmClient: osrmClient,
-		poiRepo:    poiRepo,
+		osrmClient:     osrmClient,
+		poiRepo:        poiRepo,
+		transitPlanner: transitPlanner,
+		location:       location,
 	}
 }
 
@@ -33,16 +53,30 @@ func (s *RoutingService) formatRoutingError(err error) error {
 	}
 
 	if strings.Contains(errStr, "NoRoute") || strings.Contains(errStr, "NoSegment") {
-		return fmt.Errorf("невозможно построить маршрут между указанными точками")
+		return invalidRequest("невозможно построить маршрут между указанными точками")
 	}
 
 	if strings.Contains(errStr, "InvalidValue") {
-		return fmt.Errorf("некорректные координаты")
+		return invalidRequest("некорректные координаты")
 	}
 
 	return fmt.Errorf("ошибка построения маршрута: %w", err)
 }
 
+// InvalidRequestError reports a request that cannot be served as given,
+// as opposed to a failure of the routing engine or the database.
+type InvalidRequestError struct {
+	message string
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.message
+}
+
+func invalidRequest(message string) error {
+	return &InvalidRequestError{message: message}
+}
+
 func (s *RoutingService) BuildRoute(ctx context.Context, req domain.RouteRequest) (*domain.RouteResponse, error) {
 	var waypoints []domain.Coordinate
 
@@ -57,7 +91,7 @@ func (s *RoutingService) BuildRoute(ctx context.Context, req domain.RouteRequest
 	}
 
 	if len(waypoints) < 2 {
-		return nil, fmt.Errorf("необходимо минимум 2 точки для построения маршрута")
+		return nil, invalidRequest("необходимо минимум 2 точки для построения маршрута")
 	}
 
 	mode := req.Mode
@@ -67,124 +101,234 @@ func (s *RoutingService) BuildRoute(ctx context.Context, req domain.RouteRequest
 
 	log.Printf("Building route with %d waypoints, mode=%s", len(waypoints), mode)
 
-	route, err := s.osrmClient.Route(ctx, waypoints, mode)
+	if mode == domain.TransportTransit {
+		departure := time.Now()
+		if req.DepartureTime != nil {
+			departure = *req.DepartureTime
+		}
+
+		route, err := s.transitRoute(ctx, waypoints, departure, nil)
+		if err != nil {
+			log.Printf("Transit route build failed: %v", err)
+			return nil, err
+		}
+
+		return &domain.RouteResponse{
+			Route: route,
+			Message: fmt.Sprintf("Маршрут на общественном транспорте готов: %s, примерно %.0f минут",
+				describeTransit(route), route.DurationMin),
+		}, nil
+	}
+
+	alternatives := req.Alternatives
+	if alternatives > MaxRouteAlternatives {
+		alternatives = MaxRouteAlternatives
+	}
+	if alternatives < 0 {
+		alternatives = 0
+	}
+
+	routes, err := s.osrmClient.RouteAlternatives(ctx, waypoints, mode, alternatives)
 	if err != nil {
 		log.Printf("Route build failed: %v", err)
 		return nil, s.formatRoutingError(err)
 	}
 
-	return &domain.RouteResponse{
+	route := routes[0]
+	message := fmt.Sprintf("Маршрут готов: %.1f км, примерно %.0f минут", route.DistanceKm, route.DurationMin)
+
+	resp := &domain.RouteResponse{
 		Route:   route,
-		Message: fmt.Sprintf("Маршрут готов: %.1f км, примерно %.0f минут", route.DistanceKm, route.DurationMin),
-	}, nil
-}
+		Message: message,
+	}
 
-func (s *RoutingService) BuildRouteFromPOIs(ctx context.Context, poiIDs []string, start *domain.Coordinate, mode domain.TransportMode) (*domain.RouteResponse, error) {
-	if len(poiIDs) == 0 {
-		return nil, fmt.Errorf("не указаны точки интереса")
+	if len(routes) > 1 {
+		for _, r := range routes {
+			r.NearbyPOIs = s.nearbyPOIs(ctx, r)
+		}
+
+		mainLine := osrm.DecodePolyline(route.Geometry)
+		for i, alt := range routes[1:] {
+			alt.Summary = describeAlternative(i+2, route, alt, offRoutePOIs(alt.NearbyPOIs, mainLine))
+			resp.Alternatives = append(resp.Alternatives, *alt)
+		}
+		resp.Message = message + ". " + resp.Alternatives[0].Summary
 	}
 
-	pois := make([]domain.POI, 0, len(poiIDs))
-	waypoints := make([]domain.Coordinate, 0, len(poiIDs)+1)
+	return resp, nil
+}
 
-	if start != nil {
-		waypoints = append(waypoints, *start)
+// nearbyPOIs returns POIs the route passes by. Failures are logged and
+// treated as no POIs since they only enrich the response.
+func (s *RoutingService) nearbyPOIs(ctx context.Context, route *domain.Route) []domain.POI {
+	line := osrm.DecodePolyline(route.Geometry)
+	if len(line) < 2 {
+		return nil
 	}
 
-	for _, idStr := range poiIDs {
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			log.Printf("Invalid POI ID: %s", idStr)
-			continue
-		}
+	found, err := s.poiRepo.SearchAlongRoute(ctx, line, nearbyRouteBufferM, nil, nearbyRouteLimit)
+	if err != nil {
+		log.Printf("Nearby POI lookup failed: %v", err)
+		return nil
+	}
 
-		poi, err := s.poiRepo.GetByID(ctx, id)
-		if err != nil {
-			log.Printf("POI not found: %s", idStr)
-			continue
+	pois := make([]domain.POI, len(found))
+	for i, item := range found {
+		pois[i] = item.POI
+	}
+	return pois
+}
+
+// offRoutePOIs returns the POIs farther from line than the nearby POI
+// buffer. The lists of nearby POIs are limited, so a POI missing from the
+// main route's list may still lie along it; the distance decides.
+func offRoutePOIs(pois []domain.POI, line []domain.Coordinate) []domain.POI {
+	var result []domain.POI
+	for _, poi := range pois {
+		if distanceToLineKm(domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng}, line)*1000 > nearbyRouteBufferM {
+			result = append(result, poi)
 		}
+	}
+	return result
+}
 
-		pois = append(pois, *poi)
-		waypoints = append(waypoints, domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng})
+func (s *RoutingService) BuildRouteFromPOIs(ctx context.Context, poiIDs []string, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error) {
+	if len(poiIDs) == 0 {
+		return nil, fmt.Errorf("не указаны точки интереса")
 	}
 
-	if len(waypoints) < 2 {
-		return nil, fmt.Errorf("необходимо минимум 2 точки для построения маршрута")
+	pois := s.loadPOIs(ctx, poiIDs)
+
+	log.Printf("Building trip from %d POIs, mode=%s", len(pois), mode)
+
+	return s.buildTour(ctx, pois, start, mode, opts)
+}
+
+func (s *RoutingService) BuildRouteFromSearch(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error) {
+	if len(pois) == 0 {
+		return nil, fmt.Errorf("не найдены точки интереса")
 	}
 
+	log.Printf("Building trip from search results: %d POIs, mode=%s", len(pois), mode)
+
+	return s.buildTour(ctx, pois, start, mode, opts)
+}
+
+// buildTour orders the POIs, schedules the visits from the departure time
+// and, when requested, rebuilds the route without POIs closed on arrival.
+func (s *RoutingService) buildTour(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error) {
 	if mode == "" {
 		mode = domain.TransportDriving
 	}
 
-	log.Printf("Building trip from %d POIs, mode=%s", len(pois), mode)
+	departure := time.Now()
+	if opts.DepartureTime != nil {
+		departure = *opts.DepartureTime
+	}
 
-	route, err := s.osrmClient.Trip(ctx, waypoints, mode, false)
+	route, err := s.tourRoute(ctx, pois, start, mode, departure)
 	if err != nil {
-		log.Printf("Trip build failed: %v", err)
-		return nil, s.formatRoutingError(err)
+		return nil, err
 	}
 
-	for i := range route.Waypoints {
-		if i < len(pois) {
-			route.Waypoints[i].POI = &pois[i]
-			route.Waypoints[i].Name = pois[i].Name
+	closed := s.scheduleTour(route, departure)
+
+	var skipped []domain.POI
+	if opts.SkipClosed && len(closed) > 0 {
+		skippedIDs := make(map[uuid.UUID]bool, len(closed))
+		for _, poi := range closed {
+			skippedIDs[poi.ID] = true
+		}
+
+		open := make([]domain.POI, 0, len(pois))
+		for _, poi := range pois {
+			if !skippedIDs[poi.ID] {
+				open = append(open, poi)
+			}
 		}
+
+		if len(open) == 0 {
+			return nil, fmt.Errorf("все выбранные места закрыты в это время")
+		}
+
+		route, err = s.tourRoute(ctx, open, start, mode, departure)
+		if err != nil {
+			return nil, err
+		}
+
+		skipped = closed
+		pois = open
+		closed = s.scheduleTour(route, departure)
+	}
+
+	message := fmt.Sprintf("Маршрут через %d точек: %.1f км, примерно %.0f минут", len(pois), route.DistanceKm, route.DurationMin)
+	if total := tourEnd(route, departure).Sub(departure).Minutes(); total > route.DurationMin+1 {
+		message += fmt.Sprintf(", с осмотром — %.0f минут", total)
+	}
+	if len(skipped) > 0 {
+		message += ". Пропущены закрытые места: " + poiNames(skipped)
+	}
+	if len(closed) > 0 {
+		message += ". Будут закрыты к прибытию: " + poiNames(closed)
 	}
 
 	return &domain.RouteResponse{
 		Route:   route,
-		Message: fmt.Sprintf("Маршрут через %d точек: %.1f км, примерно %.0f минут", len(pois), route.DistanceKm, route.DurationMin),
+		Message: message,
 		POIs:    pois,
+		Skipped: skipped,
 	}, nil
 }
 
-func (s *RoutingService) BuildRouteFromSearch(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode) (*domain.RouteResponse, error) {
-	if len(pois) == 0 {
-		return nil, fmt.Errorf("не найдены точки интереса")
-	}
-
+// tourRoute orders the POIs, optionally after a start point, and links the
+// waypoints back to them.
+func (s *RoutingService) tourRoute(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode, departure time.Time) (*domain.Route, error) {
 	waypoints := make([]domain.Coordinate, 0, len(pois)+1)
+	dwell := make([]float64, 0, len(pois)+1)
 
 	if start != nil {
 		waypoints = append(waypoints, *start)
+		dwell = append(dwell, 0)
 	}
 
 	for _, poi := range pois {
-		waypoints = append(waypoints, domain.Coordinate{Lat: poi.Lat, Lng: poi.Lng})
+		waypoints = append(waypoints, poiLocation(poi))
+		dwell = append(dwell, visitDurationMin(poi))
 	}
 
 	if len(waypoints) < 2 {
 		return nil, fmt.Errorf("необходимо минимум 2 точки для построения маршрута")
 	}
 
-	if mode == "" {
-		mode = domain.TransportDriving
-	}
-
-	log.Printf("Building trip from search results: %d POIs, mode=%s", len(pois), mode)
-
-	route, err := s.osrmClient.Trip(ctx, waypoints, mode, false)
+	route, err := s.trip(ctx, waypoints, mode, departure, dwell)
 	if err != nil {
 		log.Printf("Trip build failed: %v", err)
 		return nil, s.formatRoutingError(err)
 	}
 
+	attachPOIs(route, pois, start != nil)
+
+	return route, nil
+}
+
+// attachPOIs links every trip waypoint to the POI it was built from. Waypoints
+// are in visiting order and route.Sequence maps them back to the input
+// coordinates, where the optional start point precedes the POIs.
+func attachPOIs(route *domain.Route, pois []domain.POI, hasStart bool) {
 	startOffset := 0
-	if start != nil {
+	if hasStart {
 		startOffset = 1
 	}
 
 	for i := range route.Waypoints {
-		poiIdx := i - startOffset
+		if i >= len(route.Sequence) {
+			break
+		}
+
+		poiIdx := route.Sequence[i] - startOffset
 		if poiIdx >= 0 && poiIdx < len(pois) {
 			route.Waypoints[i].POI = &pois[poiIdx]
 			route.Waypoints[i].Name = pois[poiIdx].Name
 		}
 	}
-
-	return &domain.RouteResponse{
-		Route:   route,
-		Message: fmt.Sprintf("Маршрут через %d точек: %.1f км, примерно %.0f минут", len(pois), route.DistanceKm, route.DurationMin),
-		POIs:    pois,
-	}, nil
 }
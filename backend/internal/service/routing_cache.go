@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/metrics"
+)
+
+const (
+	// routeCoordinateScale rounds waypoints to 4 decimal places (about 11 m)
+	// so nearby requests share cache entries.
+	routeCoordinateScale = 1e4
+	// departureBucket groups time-dependent requests (transit, scheduled
+	// tours) that depart within the same interval.
+	departureBucket = 5 * time.Minute
+
+	routeCacheName = "route"
+)
+
+// CachedRoutingService caches built routes in Redis under RouteCacheKey and
+// collapses concurrent identical requests. Methods that are not overridden
+// go straight to the wrapped RoutingService.
+type CachedRoutingService struct {
+	*RoutingService
+	cache *CacheManager
+}
+
+// NewCachedRoutingService wraps the routing service. cache may be nil, in
+// which case requests bypass caching.
+func NewCachedRoutingService(routingService *RoutingService, cache *CacheManager) *CachedRoutingService {
+	return &CachedRoutingService{
+		RoutingService: routingService,
+		cache:          cache,
+	}
+}
+
+func (s *CachedRoutingService) BuildRoute(ctx context.Context, req domain.RouteRequest) (*domain.RouteResponse, error) {
+	req.Start = roundCoordinate(req.Start)
+	req.End = roundCoordinate(req.End)
+	waypoints := make([]domain.Coordinate, len(req.Waypoints))
+	for i, c := range req.Waypoints {
+		waypoints[i] = *roundCoordinate(&c)
+	}
+	req.Waypoints = waypoints
+
+	if req.Alternatives > MaxRouteAlternatives {
+		req.Alternatives = MaxRouteAlternatives
+	}
+	if req.Alternatives < 0 {
+		req.Alternatives = 0
+	}
+
+	var points []string
+	if req.Start != nil {
+		points = append(points, formatCacheCoordinate(*req.Start))
+	}
+	for _, c := range req.Waypoints {
+		points = append(points, formatCacheCoordinate(c))
+	}
+	if req.End != nil {
+		points = append(points, formatCacheCoordinate(*req.End))
+	}
+
+	options := fmt.Sprintf("%s:alt=%d", routeMode(req.Mode), req.Alternatives)
+	if routeMode(req.Mode) == domain.TransportTransit {
+		options += fmt.Sprintf(":dep=%d", departureKey(req.DepartureTime))
+	}
+
+	key := RouteCacheKey(strings.Join(points, ";"), options)
+
+	return s.cached(ctx, key, func(ctx context.Context) (*domain.RouteResponse, error) {
+		return s.RoutingService.BuildRoute(ctx, req)
+	})
+}
+
+func (s *CachedRoutingService) BuildRouteFromPOIs(ctx context.Context, poiIDs []string, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error) {
+	start = roundCoordinate(start)
+	key := tourCacheKey(poiIDs, start, mode, opts)
+
+	return s.cached(ctx, key, func(ctx context.Context) (*domain.RouteResponse, error) {
+		return s.RoutingService.BuildRouteFromPOIs(ctx, poiIDs, start, mode, opts)
+	})
+}
+
+func (s *CachedRoutingService) BuildRouteFromSearch(ctx context.Context, pois []domain.POI, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) (*domain.RouteResponse, error) {
+	ids := make([]string, len(pois))
+	for i, poi := range pois {
+		ids[i] = poi.ID.String()
+	}
+
+	start = roundCoordinate(start)
+	key := tourCacheKey(ids, start, mode, opts)
+
+	return s.cached(ctx, key, func(ctx context.Context) (*domain.RouteResponse, error) {
+		return s.RoutingService.BuildRouteFromSearch(ctx, pois, start, mode, opts)
+	})
+}
+
+// cached returns the cached response or builds it once for all concurrent
+// callers with the same key.
+func (s *CachedRoutingService) cached(ctx context.Context, key string, build func(context.Context) (*domain.RouteResponse, error)) (*domain.RouteResponse, error) {
+	if s.cache == nil {
+		metrics.CacheRequestsTotal.WithLabelValues(routeCacheName, metrics.CacheBypass).Inc()
+		return build(ctx)
+	}
+
+	// Routes carry POI data, so they are dropped together with POI caches.
+	key = s.cache.VersionedKey(ctx, CacheNamespacePOI, key)
+
+	var resp domain.RouteResponse
+	err := s.cache.GetOrCompute(ctx, routeCacheName, key, RouteCacheTTL, &resp, func(ctx context.Context) (interface{}, error) {
+		return build(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// tourCacheKey keeps the POI order because it decides the order of the
+// returned POIs and waypoint sequence.
+func tourCacheKey(poiIDs []string, start *domain.Coordinate, mode domain.TransportMode, opts domain.TourOptions) string {
+	points := "tour"
+	if start != nil {
+		points += ";" + formatCacheCoordinate(*start)
+	}
+	points += ";" + strings.Join(poiIDs, ",")
+
+	options := fmt.Sprintf("%s:skip=%t:dep=%d", routeMode(mode), opts.SkipClosed, departureKey(opts.DepartureTime))
+
+	return RouteCacheKey(points, options)
+}
+
+func routeMode(mode domain.TransportMode) domain.TransportMode {
+	if mode == "" {
+		return domain.TransportDriving
+	}
+	return mode
+}
+
+// departureKey is the start of the departure bucket in Unix seconds.
+func departureKey(departure *time.Time) int64 {
+	t := time.Now()
+	if departure != nil {
+		t = *departure
+	}
+	return t.Truncate(departureBucket).Unix()
+}
+
+func roundCoordinate(c *domain.Coordinate) *domain.Coordinate {
+	if c == nil {
+		return nil
+	}
+	return &domain.Coordinate{
+		Lat: math.Round(c.Lat*routeCoordinateScale) / routeCoordinateScale,
+		Lng: math.Round(c.Lng*routeCoordinateScale) / routeCoordinateScale,
+	}
+}
+
+func formatCacheCoordinate(c domain.Coordinate) string {
+	return fmt.Sprintf("%.4f,%.4f", c.Lat, c.Lng)
+}
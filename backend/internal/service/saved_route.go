@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/repository"
+)
+
+const (
+	shareCodeLength   = 8
+	shareCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	shareCodeAttempts = 5
+
+	deleteTokenBytes = 24
+
+	maxSavedRouteTitle = 255
+
+	// maxListedRoutes caps the share codes of one list request.
+	maxListedRoutes = 100
+)
+
+type SavedRouteService struct {
+	routeRepo *repository.RouteRepository
+}
+
+func NewSavedRouteService(routeRepo *repository.RouteRepository) *SavedRouteService {
+	return &SavedRouteService{routeRepo: routeRepo}
+}
+
+// Save persists a built route under a freshly generated share code. The
+// returned route carries the token required to delete it.
+func (s *SavedRouteService) Save(ctx context.Context, title string, route *domain.Route, pois []domain.POI, metadata map[string]string) (*domain.SavedRoute, error) {
+	if route == nil || route.Geometry == "" {
+		return nil, fmt.Errorf("маршрут не указан")
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = fmt.Sprintf("Маршрут %.1f км", route.DistanceKm)
+	}
+	if len([]rune(title)) > maxSavedRouteTitle {
+		title = string([]rune(title)[:maxSavedRouteTitle])
+	}
+
+	saved := &domain.SavedRoute{
+		Title:       title,
+		Mode:        route.Mode,
+		DistanceKm:  route.DistanceKm,
+		DurationMin: route.DurationMin,
+		Geometry:    route.Geometry,
+		Waypoints:   route.Waypoints,
+		POIs:        pois,
+		Metadata:    metadata,
+	}
+
+	token, err := generateDeleteToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate delete token: %w", err)
+	}
+
+	for attempt := 0; attempt < shareCodeAttempts; attempt++ {
+		code, err := generateShareCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate share code: %w", err)
+		}
+
+		saved.ShareCode = code
+		err = s.routeRepo.Create(ctx, saved, hashDeleteToken(token))
+		if errors.Is(err, repository.ErrShareCodeConflict) {
+			saved.ID = uuid.Nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("save route: %w", err)
+		}
+
+		log.Printf("Saved route %s with share code %s", saved.ID, saved.ShareCode)
+		saved.DeleteToken = token
+		return saved, nil
+	}
+
+	return nil, fmt.Errorf("save route: could not allocate share code")
+}
+
+func (s *SavedRouteService) GetByShareCode(ctx context.Context, code string) (*domain.SavedRoute, error) {
+	return s.routeRepo.GetByShareCode(ctx, code)
+}
+
+func (s *SavedRouteService) GetByID(ctx context.Context, id uuid.UUID) (*domain.SavedRoute, error) {
+	return s.routeRepo.GetByID(ctx, id)
+}
+
+// ListByShareCodes returns the routes the client knows the share codes
+// of, typically the ones it saved itself. There is no list of all routes.
+func (s *SavedRouteService) ListByShareCodes(ctx context.Context, codes []string) ([]domain.SavedRoute, error) {
+	seen := make(map[string]bool, len(codes))
+	unique := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		unique = append(unique, code)
+	}
+
+	if len(unique) == 0 {
+		return []domain.SavedRoute{}, nil
+	}
+	if len(unique) > maxListedRoutes {
+		return nil, invalidRequest(fmt.Sprintf("не больше %d кодов маршрутов за запрос", maxListedRoutes))
+	}
+
+	return s.routeRepo.ListByShareCodes(ctx, unique)
+}
+
+// Delete removes a route given the token returned when it was saved.
+func (s *SavedRouteService) Delete(ctx context.Context, id uuid.UUID, deleteToken string) error {
+	if deleteToken == "" {
+		return fmt.Errorf("токен удаления не указан")
+	}
+	return s.routeRepo.Delete(ctx, id, hashDeleteToken(deleteToken))
+}
+
+func generateDeleteToken() (string, error) {
+	b := make([]byte, deleteTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashDeleteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateShareCode() (string, error) {
+	max := big.NewInt(int64(len(shareCodeAlphabet)))
+	code := make([]byte, shareCodeLength)
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = shareCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/metrics"
+)
+
+const (
+	searchCacheName     = "search"
+	poiCacheName        = "poi"
+	categoriesCacheName = "categories"
+)
+
+// POISearcher is implemented by SearchService and SemanticSearchService.
+type POISearcher interface {
+	Search(ctx context.Context, query string, filters domain.SearchFilters) (*domain.SearchResult, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.POI, error)
+	GetCategories(ctx context.Context) ([]domain.Category, error)
+}
+
+// CachedSearchService caches search results, POIs and categories in Redis
+// under the POI cache namespace. Redis errors are treated as misses, so the
+// wrapped service keeps working when Redis is unavailable.
+type CachedSearchService struct {
+	searchService POISearcher
+	cache         *CacheManager
+}
+
+// NewCachedSearchService wraps the search service. cache may be nil, in
+// which case every call goes to the wrapped service.
+func NewCachedSearchService(searchService POISearcher, cache *CacheManager) *CachedSearchService {
+	return &CachedSearchService{
+		searchService: searchService,
+		cache:         cache,
+	}
+}
+
+func (s *CachedSearchService) Search(ctx context.Context, query string, filters domain.SearchFilters) (*domain.SearchResult, error) {
+	if s.cache == nil {
+		metrics.CacheRequestsTotal.WithLabelValues(searchCacheName, metrics.CacheBypass).Inc()
+		return s.searchService.Search(ctx, query, filters)
+	}
+
+	start := time.Now()
+	key := s.cache.VersionedKey(ctx, CacheNamespacePOI, SearchCacheKey(normalizeQuery(query), filters))
+
+	var result domain.SearchResult
+	err := s.cache.GetOrCompute(ctx, searchCacheName, key, SearchCacheTTL, &result, func(ctx context.Context) (interface{}, error) {
+		return s.searchService.Search(ctx, query, filters)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached result may come from a query that differs in case or
+	// spacing.
+	result.Query = query
+	result.TookMs = time.Since(start).Milliseconds()
+	return &result, nil
+}
+
+func (s *CachedSearchService) GetByID(ctx context.Context, id uuid.UUID) (*domain.POI, error) {
+	if s.cache == nil {
+		metrics.CacheRequestsTotal.WithLabelValues(poiCacheName, metrics.CacheBypass).Inc()
+		return s.searchService.GetByID(ctx, id)
+	}
+
+	key := s.cache.VersionedKey(ctx, CacheNamespacePOI, POICacheKey(id.String()))
+
+	var poi domain.POI
+	err := s.cache.GetOrCompute(ctx, poiCacheName, key, POICacheTTL, &poi, func(ctx context.Context) (interface{}, error) {
+		return s.searchService.GetByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &poi, nil
+}
+
+func (s *CachedSearchService) GetCategories(ctx context.Context) ([]domain.Category, error) {
+	if s.cache == nil {
+		metrics.CacheRequestsTotal.WithLabelValues(categoriesCacheName, metrics.CacheBypass).Inc()
+		return s.searchService.GetCategories(ctx)
+	}
+
+	key := s.cache.VersionedKey(ctx, CacheNamespacePOI, CategoriesCacheKey())
+
+	var categories []domain.Category
+	err := s.cache.GetOrCompute(ctx, categoriesCacheName, key, CategoriesCacheTTL, &categories, func(ctx context.Context) (interface{}, error) {
+		return s.searchService.GetCategories(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// normalizeQuery makes queries that differ only in case or whitespace share
+// a cache entry.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+	"github.com/dremotha/mapbot/internal/infrastructure/osrm"
+	"github.com/dremotha/mapbot/internal/transit"
+)
+
+// transitLegNames are used in route messages.
+var transitLegNames = map[string]string{
+	transit.LegSubway:     "метро",
+	transit.LegBus:        "автобус",
+	transit.LegTram:       "трамвай",
+	transit.LegTrolleybus: "троллейбус",
+	transit.LegRail:       "электричка",
+	transit.LegMonorail:   "монорельс",
+	transit.LegFerry:      "речной транспорт",
+}
+
+// trip orders waypoints and builds a route through them. Transit routes are
+// ordered by walking distance and then planned leg by leg on the timetable,
+// leaving each waypoint after dwellMin[i] minutes spent there.
+func (s *RoutingService) trip(ctx context.Context, waypoints []domain.Coordinate, mode domain.TransportMode, departure time.Time, dwellMin []float64) (*domain.Route, error) {
+	if mode != domain.TransportTransit {
+		return s.osrmClient.Trip(ctx, waypoints, mode, false)
+	}
+
+	ordered, err := s.osrmClient.Trip(ctx, waypoints, domain.TransportWalking, false)
+	if err != nil {
+		return nil, err
+	}
+
+	coords := make([]domain.Coordinate, len(ordered.Sequence))
+	dwell := make([]float64, len(ordered.Sequence))
+	for i, idx := range ordered.Sequence {
+		coords[i] = waypoints[idx]
+		if idx < len(dwellMin) {
+			dwell[i] = dwellMin[idx]
+		}
+	}
+
+	route, err := s.transitRoute(ctx, coords, departure, dwell)
+	if err != nil {
+		return nil, err
+	}
+	route.Sequence = ordered.Sequence
+
+	return route, nil
+}
+
+// transitRoute plans a public transport journey through the waypoints in the
+// given order, leaving each one dwellMin[i] minutes after arriving there.
+func (s *RoutingService) transitRoute(ctx context.Context, waypoints []domain.Coordinate, departure time.Time, dwellMin []float64) (*domain.Route, error) {
+	if s.transitPlanner == nil {
+		return nil, fmt.Errorf("маршруты на общественном транспорте недоступны: расписание не загружено")
+	}
+
+	route := &domain.Route{Mode: domain.TransportTransit}
+	var line []domain.Coordinate
+	current := departure
+
+	for i := 0; i+1 < len(waypoints); i++ {
+		if i < len(dwellMin) {
+			current = current.Add(minutes(dwellMin[i]))
+		}
+
+		journey, err := s.transitPlanner.Plan(waypoints[i], waypoints[i+1], current)
+		if errors.Is(err, transit.ErrNoJourney) {
+			return nil, fmt.Errorf("не удалось найти маршрут на общественном транспорте")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, leg := range journey.Legs {
+			routeLeg := s.transitLeg(ctx, leg)
+			route.Legs = append(route.Legs, routeLeg)
+			route.DistanceKm += routeLeg.DistanceKm
+			line = append(line, osrm.DecodePolyline(routeLeg.Geometry)...)
+		}
+		route.LegsMin = append(route.LegsMin, journey.Arrival.Sub(current).Minutes())
+		current = journey.Arrival
+	}
+
+	for i, c := range waypoints {
+		route.Waypoints = append(route.Waypoints, domain.Waypoint{Location: c, Order: i})
+		route.Sequence = append(route.Sequence, i)
+	}
+
+	for _, legMin := range route.LegsMin {
+		route.DurationMin += legMin
+	}
+	route.Geometry = osrm.EncodePolyline(line)
+
+	return route, nil
+}
+
+// transitLeg converts a planner leg. Walking legs follow the street network
+// when OSRM is available and fall back to the planner's estimate otherwise.
+func (s *RoutingService) transitLeg(ctx context.Context, leg transit.Leg) domain.RouteLeg {
+	result := domain.RouteLeg{
+		Kind:        leg.Kind,
+		Line:        leg.Line,
+		From:        leg.FromName,
+		To:          leg.ToName,
+		Departure:   leg.Departure,
+		Arrival:     leg.Arrival,
+		DurationMin: leg.Arrival.Sub(leg.Departure).Minutes(),
+		Start:       leg.From,
+		End:         leg.To,
+	}
+
+	if leg.Kind != transit.LegWalk {
+		result.Geometry = osrm.EncodePolyline(leg.Path)
+		result.DistanceKm = lineLengthKm(leg.Path)
+		return result
+	}
+
+	result.DistanceKm = leg.DistanceM / 1000
+	result.Geometry = osrm.EncodePolyline(leg.Path)
+
+	if haversineKm(leg.From, leg.To) < 0.01 {
+		return result
+	}
+
+	walk, err := s.osrmClient.Route(ctx, []domain.Coordinate{leg.From, leg.To}, domain.TransportWalking)
+	if err != nil {
+		log.Printf("Walking leg geometry failed, using straight line: %v", err)
+		return result
+	}
+
+	result.DistanceKm = walk.DistanceKm
+	result.Geometry = walk.Geometry
+
+	return result
+}
+
+// describeTransit lists the rides of a transit route, e.g.
+// "метро 1 → автобус м27".
+func describeTransit(route *domain.Route) string {
+	var rides []string
+	for _, leg := range route.Legs {
+		if leg.Kind == transit.LegWalk {
+			continue
+		}
+		name := transitLegNames[leg.Kind]
+		if leg.Line != "" {
+			name += " " + leg.Line
+		}
+		rides = append(rides, name)
+	}
+
+	if len(rides) == 0 {
+		return "пешком"
+	}
+
+	return strings.Join(rides, " → ")
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// maxOpeningWaitMin is how long a tour waits at a POI that opens soon
+// before it is reported as closed.
+const maxOpeningWaitMin = 60
+
+// visitDurations is the typical time in minutes spent at a POI of the given
+// subcategory.
+var visitDurations = map[string]float64{
+	"monastery":   120,
+	"cathedral":   45,
+	"church":      30,
+	"chapel":      15,
+	"memorial":    15,
+	"battlefield": 60,
+	"fortress":    90,
+	"manor":       90,
+	"palace":      90,
+	"ruins":       30,
+	"tower":       20,
+	"bunker":      60,
+	"museum":      90,
+}
+
+// categoryVisitDurations is used when the subcategory is unknown.
+var categoryVisitDurations = map[string]float64{
+	"religious":    30,
+	"military":     30,
+	"architecture": 30,
+}
+
+// visitDurationMin is the expected time spent at a POI.
+func visitDurationMin(poi domain.POI) float64 {
+	if d, ok := visitDurations[poi.Subcategory]; ok {
+		return d
+	}
+	if d, ok := categoryVisitDurations[poi.Category]; ok {
+		return d
+	}
+	return defaultVisitMin
+}
+
+// scheduleTour sets arrival and departure times on the route waypoints and
+// checks opening hours on arrival. A POI that opens within
+// maxOpeningWaitMin is waited for. It returns the POIs that are closed.
+func (s *RoutingService) scheduleTour(route *domain.Route, departure time.Time) []domain.POI {
+	var closed []domain.POI
+	t := departure.In(s.location)
+
+	for i := range route.Waypoints {
+		wp := &route.Waypoints[i]
+
+		if i > 0 && i-1 < len(route.LegsMin) {
+			t = t.Add(minutes(route.LegsMin[i-1]))
+		}
+
+		arrival := t
+		wp.Arrival = &arrival
+
+		if wp.POI != nil {
+			visit := minutes(visitDurationMin(*wp.POI))
+			hours := wp.POI.OpeningHours
+
+			if hours.Known() && !hours.IsOpen(t) {
+				next, ok := hours.NextOpening(t)
+				if ok && next.Sub(t) <= maxOpeningWaitMin*time.Minute {
+					wp.HoursNote = fmt.Sprintf("откроется в %s", next.Format("15:04"))
+					t = next
+				} else {
+					wp.Closed = true
+					wp.HoursNote = "закрыто в момент прибытия"
+					closed = append(closed, *wp.POI)
+					visit = 0
+				}
+			}
+
+			if !wp.Closed && hours.Known() {
+				if closes, ok := hours.ClosesAt(t); ok && closes.Before(t.Add(visit)) {
+					wp.HoursNote = fmt.Sprintf("закрывается в %s", closes.Format("15:04"))
+				}
+			}
+
+			t = t.Add(visit)
+		}
+
+		leave := t
+		wp.Departure = &leave
+	}
+
+	return closed
+}
+
+// tourEnd returns the departure from the last waypoint of a scheduled route.
+func tourEnd(route *domain.Route, departure time.Time) time.Time {
+	if n := len(route.Waypoints); n > 0 && route.Waypoints[n-1].Departure != nil {
+		return *route.Waypoints[n-1].Departure
+	}
+	return departure.Add(minutes(route.DurationMin))
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+func poiNames(pois []domain.POI) string {
+	names := ""
+	for i, poi := range pois {
+		if i > 0 {
+			names += ", "
+		}
+		names += poi.Name
+	}
+	return names
+}
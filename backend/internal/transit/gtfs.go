@@ -0,0 +1,490 @@
+package transit
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// Stop is a GTFS stop or platform.
+type Stop struct {
+	ID       string
+	Name     string
+	Location domain.Coordinate
+}
+
+// Line is a GTFS route, named Line to avoid confusion with domain.Route.
+type Line struct {
+	Name string
+	Type int
+}
+
+// Trip is a single vehicle run with its stop times in seconds after
+// midnight of the service day.
+type Trip struct {
+	Line       int32
+	Service    int32
+	Stops      []int32
+	Arrivals   []int32
+	Departures []int32
+}
+
+// Connection is a vehicle moving from Trip.Stops[Seq] to Trip.Stops[Seq+1].
+type Connection struct {
+	Departure int32
+	Arrival   int32
+	Trip      int32
+	Seq       int32
+}
+
+type service struct {
+	weekdays [7]bool
+	start    string
+	end      string
+	added    map[string]bool
+	removed  map[string]bool
+}
+
+type footpath struct {
+	stop     int32
+	duration int32
+}
+
+// Feed is a GTFS feed prepared for journey planning. Connections are sorted
+// by departure time.
+type Feed struct {
+	Stops       []Stop
+	Lines       []Line
+	Trips       []Trip
+	Connections []Connection
+
+	services  []service
+	footpaths [][]footpath
+	grid      map[gridCell][]int32
+}
+
+type gridCell struct {
+	lat int32
+	lng int32
+}
+
+// gridCellDeg is the size of the spatial index cell, about 1 km at
+// Moscow latitude.
+const gridCellDeg = 0.01
+
+// Load reads a GTFS feed from a zip archive or an unpacked directory.
+// stops.txt, trips.txt and stop_times.txt are required; routes.txt,
+// calendar.txt and calendar_dates.txt are used when present.
+func Load(path string) (*Feed, error) {
+	open, closeFn, err := opener(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	feed := &Feed{grid: make(map[gridCell][]int32)}
+
+	stopIdx, err := feed.loadStops(open)
+	if err != nil {
+		return nil, err
+	}
+
+	lineIdx, err := feed.loadLines(open)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceIdx, err := feed.loadServices(open)
+	if err != nil {
+		return nil, err
+	}
+
+	tripIdx, err := feed.loadTrips(open, lineIdx, serviceIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := feed.loadStopTimes(open, stopIdx, tripIdx); err != nil {
+		return nil, err
+	}
+
+	feed.buildConnections()
+	feed.buildFootpaths()
+
+	return feed, nil
+}
+
+type fileOpener func(name string) (io.ReadCloser, error)
+
+var errMissingFile = errors.New("file not found in feed")
+
+func opener(path string) (fileOpener, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open gtfs feed: %w", err)
+	}
+
+	if info.IsDir() {
+		return func(name string) (io.ReadCloser, error) {
+			f, err := os.Open(filepath.Join(path, name))
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, errMissingFile
+			}
+			return f, err
+		}, func() {}, nil
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open gtfs zip: %w", err)
+	}
+
+	return func(name string) (io.ReadCloser, error) {
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) == name {
+				return f.Open()
+			}
+		}
+		return nil, errMissingFile
+	}, func() { zr.Close() }, nil
+}
+
+// readCSV calls fn for every record of a GTFS file with a column lookup.
+func readCSV(open fileOpener, name string, required bool, fn func(get func(string) string) error) error {
+	rc, err := open(name)
+	if errors.Is(err, errMissingFile) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	r := csv.NewReader(rc)
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("read %s header: %w", name, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")] = i
+	}
+
+	var record []string
+	get := func(col string) string {
+		i, ok := columns[col]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	for {
+		record, err = r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		if err := fn(get); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+}
+
+func (f *Feed) loadStops(open fileOpener) (map[string]int32, error) {
+	index := make(map[string]int32)
+
+	err := readCSV(open, "stops.txt", true, func(get func(string) string) error {
+		lat, err1 := strconv.ParseFloat(get("stop_lat"), 64)
+		lng, err2 := strconv.ParseFloat(get("stop_lon"), 64)
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+
+		idx := int32(len(f.Stops))
+		index[get("stop_id")] = idx
+		f.Stops = append(f.Stops, Stop{
+			ID:       get("stop_id"),
+			Name:     get("stop_name"),
+			Location: domain.Coordinate{Lat: lat, Lng: lng},
+		})
+
+		cell := cellOf(domain.Coordinate{Lat: lat, Lng: lng})
+		f.grid[cell] = append(f.grid[cell], idx)
+		return nil
+	})
+
+	return index, err
+}
+
+func (f *Feed) loadLines(open fileOpener) (map[string]int32, error) {
+	index := make(map[string]int32)
+
+	err := readCSV(open, "routes.txt", false, func(get func(string) string) error {
+		name := get("route_short_name")
+		if name == "" {
+			name = get("route_long_name")
+		}
+		routeType, _ := strconv.Atoi(get("route_type"))
+
+		index[get("route_id")] = int32(len(f.Lines))
+		f.Lines = append(f.Lines, Line{Name: name, Type: routeType})
+		return nil
+	})
+
+	return index, err
+}
+
+func (f *Feed) loadServices(open fileOpener) (map[string]int32, error) {
+	index := make(map[string]int32)
+
+	serviceFor := func(id string) *service {
+		idx, ok := index[id]
+		if !ok {
+			idx = int32(len(f.services))
+			index[id] = idx
+			f.services = append(f.services, service{})
+		}
+		return &f.services[idx]
+	}
+
+	err := readCSV(open, "calendar.txt", false, func(get func(string) string) error {
+		svc := serviceFor(get("service_id"))
+		// Go's time.Weekday starts with Sunday.
+		days := []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+		for i, day := range days {
+			svc.weekdays[i] = get(day) == "1"
+		}
+		svc.start = get("start_date")
+		svc.end = get("end_date")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(open, "calendar_dates.txt", false, func(get func(string) string) error {
+		svc := serviceFor(get("service_id"))
+		date := get("date")
+		switch get("exception_type") {
+		case "1":
+			if svc.added == nil {
+				svc.added = make(map[string]bool)
+			}
+			svc.added[date] = true
+		case "2":
+			if svc.removed == nil {
+				svc.removed = make(map[string]bool)
+			}
+			svc.removed[date] = true
+		}
+		return nil
+	})
+
+	return index, err
+}
+
+func (f *Feed) loadTrips(open fileOpener, lineIdx, serviceIdx map[string]int32) (map[string]int32, error) {
+	index := make(map[string]int32)
+
+	err := readCSV(open, "trips.txt", true, func(get func(string) string) error {
+		line, ok := lineIdx[get("route_id")]
+		if !ok {
+			line = int32(len(f.Lines))
+			lineIdx[get("route_id")] = line
+			f.Lines = append(f.Lines, Line{Name: get("route_id"), Type: 3})
+		}
+
+		svc, ok := serviceIdx[get("service_id")]
+		if !ok {
+			// Without calendar information the trip is assumed to run daily.
+			svc = -1
+		}
+
+		index[get("trip_id")] = int32(len(f.Trips))
+		f.Trips = append(f.Trips, Trip{Line: line, Service: svc})
+		return nil
+	})
+
+	return index, err
+}
+
+func (f *Feed) loadStopTimes(open fileOpener, stopIdx, tripIdx map[string]int32) error {
+	type stopTime struct {
+		seq       int
+		stop      int32
+		arrival   int32
+		departure int32
+	}
+
+	byTrip := make(map[int32][]stopTime)
+
+	err := readCSV(open, "stop_times.txt", true, func(get func(string) string) error {
+		trip, ok := tripIdx[get("trip_id")]
+		if !ok {
+			return nil
+		}
+		stop, ok := stopIdx[get("stop_id")]
+		if !ok {
+			return nil
+		}
+
+		arrival, errA := parseGTFSTime(get("arrival_time"))
+		departure, errD := parseGTFSTime(get("departure_time"))
+		if errA != nil && errD != nil {
+			// Untimed stops are skipped rather than interpolated.
+			return nil
+		}
+		if errA != nil {
+			arrival = departure
+		}
+		if errD != nil {
+			departure = arrival
+		}
+
+		seq, _ := strconv.Atoi(get("stop_sequence"))
+		byTrip[trip] = append(byTrip[trip], stopTime{seq: seq, stop: stop, arrival: arrival, departure: departure})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for tripID, times := range byTrip {
+		sort.Slice(times, func(i, j int) bool { return times[i].seq < times[j].seq })
+
+		trip := &f.Trips[tripID]
+		trip.Stops = make([]int32, len(times))
+		trip.Arrivals = make([]int32, len(times))
+		trip.Departures = make([]int32, len(times))
+		for i, st := range times {
+			trip.Stops[i] = st.stop
+			trip.Arrivals[i] = st.arrival
+			trip.Departures[i] = st.departure
+		}
+	}
+
+	return nil
+}
+
+func (f *Feed) buildConnections() {
+	for tripID, trip := range f.Trips {
+		for seq := 0; seq+1 < len(trip.Stops); seq++ {
+			f.Connections = append(f.Connections, Connection{
+				Departure: trip.Departures[seq],
+				Arrival:   trip.Arrivals[seq+1],
+				Trip:      int32(tripID),
+				Seq:       int32(seq),
+			})
+		}
+	}
+
+	sort.Slice(f.Connections, func(i, j int) bool {
+		return f.Connections[i].Departure < f.Connections[j].Departure
+	})
+}
+
+func (f *Feed) buildFootpaths() {
+	f.footpaths = make([][]footpath, len(f.Stops))
+
+	for i, stop := range f.Stops {
+		for _, near := range f.NearbyStops(stop.Location, maxTransferWalkM) {
+			if near.Stop == int32(i) {
+				continue
+			}
+			f.footpaths[i] = append(f.footpaths[i], footpath{stop: near.Stop, duration: walkSeconds(near.DistanceM)})
+		}
+	}
+}
+
+// NearbyStop is a stop found within walking distance of a point.
+type NearbyStop struct {
+	Stop      int32
+	DistanceM float64
+}
+
+// NearbyStops returns stops within radiusM meters of the point.
+func (f *Feed) NearbyStops(point domain.Coordinate, radiusM float64) []NearbyStop {
+	center := cellOf(point)
+	latCells := int32(math.Ceil(radiusM / 111000 / gridCellDeg))
+	lngCells := int32(math.Ceil(radiusM / (111000 * math.Cos(point.Lat*math.Pi/180)) / gridCellDeg))
+
+	var result []NearbyStop
+	for dLat := -latCells; dLat <= latCells; dLat++ {
+		for dLng := -lngCells; dLng <= lngCells; dLng++ {
+			for _, idx := range f.grid[gridCell{lat: center.lat + dLat, lng: center.lng + dLng}] {
+				d := distanceM(point, f.Stops[idx].Location)
+				if d <= radiusM {
+					result = append(result, NearbyStop{Stop: idx, DistanceM: d})
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func (f *Feed) serviceActive(idx int32, date time.Time) bool {
+	if idx < 0 || int(idx) >= len(f.services) {
+		return true
+	}
+
+	svc := &f.services[idx]
+	day := date.Format("20060102")
+
+	if svc.removed[day] {
+		return false
+	}
+	if svc.added[day] {
+		return true
+	}
+	if svc.start == "" {
+		return false
+	}
+
+	return svc.weekdays[date.Weekday()] && day >= svc.start && day <= svc.end
+}
+
+func cellOf(c domain.Coordinate) gridCell {
+	return gridCell{
+		lat: int32(math.Floor(c.Lat / gridCellDeg)),
+		lng: int32(math.Floor(c.Lng / gridCellDeg)),
+	}
+}
+
+// parseGTFSTime parses HH:MM:SS, where hours may exceed 23 for trips running
+// past midnight.
+func parseGTFSTime(s string) (int32, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+
+	var total int
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		total = total*60 + v
+	}
+
+	return int32(total), nil
+}
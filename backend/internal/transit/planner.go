@@ -0,0 +1,363 @@
+package transit
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+const (
+	// walkSpeedMps is the pedestrian speed used for access, egress and
+	// transfer legs.
+	walkSpeedMps = 1.2
+	// walkCircuity converts straight-line distance into street distance.
+	walkCircuity = 1.25
+	// maxAccessWalkM limits how far a passenger walks to the first stop and
+	// from the last one.
+	maxAccessWalkM = 1000
+	// maxTransferWalkM limits walking between stops when changing lines.
+	maxTransferWalkM = 300
+	// minTransferS is the time needed to change from one vehicle to another.
+	minTransferS = 60
+	// maxJourneyS bounds the scan so an unreachable target does not walk
+	// through the whole timetable.
+	maxJourneyS = 4 * 3600
+	// daySeconds is the shift between the times of consecutive service
+	// days: a trip of the previous day at 25:10 runs at 01:10 today.
+	daySeconds = 24 * 3600
+)
+
+// Leg kinds.
+const (
+	LegWalk       = "walk"
+	LegTram       = "tram"
+	LegSubway     = "subway"
+	LegRail       = "rail"
+	LegBus        = "bus"
+	LegFerry      = "ferry"
+	LegTrolleybus = "trolleybus"
+	LegMonorail   = "monorail"
+)
+
+// ErrNoJourney is returned when the destination cannot be reached.
+var ErrNoJourney = errors.New("no transit journey found")
+
+// Leg is a single walking or riding part of a journey.
+type Leg struct {
+	Kind      string
+	Line      string
+	FromName  string
+	ToName    string
+	From      domain.Coordinate
+	To        domain.Coordinate
+	Departure time.Time
+	Arrival   time.Time
+	// Path holds the stops passed by a vehicle leg, including both ends.
+	Path []domain.Coordinate
+	// DistanceM is the straight-line estimate for walking legs.
+	DistanceM float64
+}
+
+// Journey is an itinerary between two points.
+type Journey struct {
+	Legs      []Leg
+	Departure time.Time
+	Arrival   time.Time
+}
+
+// Planner finds earliest-arrival journeys with the Connection Scan Algorithm.
+type Planner struct {
+	feed     *Feed
+	location *time.Location
+}
+
+// NewPlanner creates a planner. Timetable times are interpreted in location.
+func NewPlanner(feed *Feed, location *time.Location) *Planner {
+	if location == nil {
+		location = time.UTC
+	}
+	return &Planner{feed: feed, location: location}
+}
+
+type arrivalKind uint8
+
+const (
+	reachedNone arrivalKind = iota
+	reachedAccess
+	reachedRide
+	reachedFoot
+)
+
+type label struct {
+	kind     arrivalKind
+	enter    int32
+	exit     int32
+	fromStop int32
+	// offset shifts the times of the ridden trip onto this day: 0 or
+	// -daySeconds for a trip of the previous service day.
+	offset int32
+}
+
+// tripRun is a trip on a given service day.
+type tripRun struct {
+	trip   int32
+	offset int32
+}
+
+type serviceDay struct {
+	service int32
+	offset  int32
+}
+
+// Plan returns the earliest-arrival journey from one point to another
+// departing not earlier than departure. Trips of the previous service day
+// that run past midnight are scanned too. Walking the whole way is
+// returned when it is faster than any transit option.
+func (p *Planner) Plan(from, to domain.Coordinate, departure time.Time) (*Journey, error) {
+	local := departure.In(p.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, p.location)
+	t0 := int32(local.Sub(midnight) / time.Second)
+	at := func(s int32) time.Time { return midnight.Add(time.Duration(s) * time.Second) }
+
+	feed := p.feed
+	arrival := make([]int32, len(feed.Stops))
+	for i := range arrival {
+		arrival[i] = math.MaxInt32
+	}
+	labels := make([]label, len(feed.Stops))
+
+	for _, near := range feed.NearbyStops(from, maxAccessWalkM) {
+		arrival[near.Stop] = t0 + walkSeconds(near.DistanceM)
+		labels[near.Stop] = label{kind: reachedAccess}
+	}
+
+	egress := make(map[int32]int32)
+	for _, near := range feed.NearbyStops(to, maxAccessWalkM) {
+		egress[near.Stop] = walkSeconds(near.DistanceM)
+	}
+
+	directM := distanceM(from, to)
+	best := t0 + walkSeconds(directM)
+	bestStop := int32(-1)
+
+	reach := func(stop, t int32, l label) {
+		if t >= arrival[stop] {
+			return
+		}
+		arrival[stop] = t
+		labels[stop] = l
+		if w, ok := egress[stop]; ok && t+w < best {
+			best = t + w
+			bestStop = stop
+		}
+	}
+
+	boarded := make(map[tripRun]int32)
+	activeService := make(map[serviceDay]bool)
+
+	n := len(feed.Connections)
+	firstFrom := func(t int32) int {
+		return sort.Search(n, func(i int) bool { return feed.Connections[i].Departure >= t })
+	}
+
+	// Today's connections and the previous day's ones past midnight are
+	// merged by departure time.
+	today, yesterday := firstFrom(t0), firstFrom(t0+daySeconds)
+
+	next := func() (int, int32, bool) {
+		switch {
+		case yesterday < n && (today >= n || feed.Connections[yesterday].Departure-daySeconds < feed.Connections[today].Departure):
+			yesterday++
+			return yesterday - 1, -daySeconds, true
+		case today < n:
+			today++
+			return today - 1, 0, true
+		default:
+			return 0, 0, false
+		}
+	}
+
+	for {
+		i, offset, ok := next()
+		if !ok {
+			break
+		}
+
+		c := feed.Connections[i]
+		c.Departure += offset
+		c.Arrival += offset
+		if c.Departure >= best || c.Departure > t0+maxJourneyS {
+			break
+		}
+
+		trip := &feed.Trips[c.Trip]
+		day := serviceDay{service: trip.Service, offset: offset}
+		active, ok := activeService[day]
+		if !ok {
+			active = feed.serviceActive(trip.Service, midnight.AddDate(0, 0, int(offset/daySeconds)))
+			activeService[day] = active
+		}
+		if !active {
+			continue
+		}
+
+		fromStop := trip.Stops[c.Seq]
+		toStop := trip.Stops[c.Seq+1]
+
+		run := tripRun{trip: c.Trip, offset: offset}
+		enter, onBoard := boarded[run]
+		if !onBoard {
+			ready := arrival[fromStop]
+			if ready == math.MaxInt32 {
+				continue
+			}
+			if labels[fromStop].kind == reachedRide {
+				ready += minTransferS
+			}
+			if ready > c.Departure {
+				continue
+			}
+			enter = int32(i)
+			boarded[run] = enter
+		}
+
+		if c.Arrival >= arrival[toStop] {
+			continue
+		}
+		reach(toStop, c.Arrival, label{kind: reachedRide, enter: enter, exit: int32(i), offset: offset})
+
+		for _, fp := range feed.footpaths[toStop] {
+			reach(fp.stop, c.Arrival+fp.duration, label{kind: reachedFoot, fromStop: toStop})
+		}
+	}
+
+	if bestStop < 0 {
+		if directM > maxAccessWalkM*3 {
+			return nil, ErrNoJourney
+		}
+		return &Journey{
+			Legs:      []Leg{p.walkLeg(from, to, "", "", directM, at(t0), at(best))},
+			Departure: at(t0),
+			Arrival:   at(best),
+		}, nil
+	}
+
+	var legs []Leg
+	last := feed.Stops[bestStop]
+	legs = append(legs, p.walkLeg(last.Location, to, last.Name, "", distanceM(last.Location, to), at(arrival[bestStop]), at(best)))
+
+	stop := bestStop
+	for {
+		l := labels[stop]
+		current := feed.Stops[stop]
+
+		switch l.kind {
+		case reachedAccess:
+			legs = append(legs, p.walkLeg(from, current.Location, "", current.Name, distanceM(from, current.Location), at(t0), at(arrival[stop])))
+			reverse(legs)
+			return &Journey{Legs: legs, Departure: at(t0), Arrival: at(best)}, nil
+
+		case reachedFoot:
+			prev := feed.Stops[l.fromStop]
+			legs = append(legs, p.walkLeg(prev.Location, current.Location, prev.Name, current.Name,
+				distanceM(prev.Location, current.Location), at(arrival[l.fromStop]), at(arrival[stop])))
+			stop = l.fromStop
+
+		case reachedRide:
+			legs = append(legs, p.rideLeg(l.enter, l.exit, l.offset, at))
+			enter := feed.Connections[l.enter]
+			stop = feed.Trips[enter.Trip].Stops[enter.Seq]
+
+		default:
+			return nil, ErrNoJourney
+		}
+	}
+}
+
+func (p *Planner) walkLeg(from, to domain.Coordinate, fromName, toName string, meters float64, dep, arr time.Time) Leg {
+	return Leg{
+		Kind:      LegWalk,
+		FromName:  fromName,
+		ToName:    toName,
+		From:      from,
+		To:        to,
+		Departure: dep,
+		Arrival:   arr,
+		Path:      []domain.Coordinate{from, to},
+		DistanceM: meters * walkCircuity,
+	}
+}
+
+func (p *Planner) rideLeg(enterIdx, exitIdx, offset int32, at func(int32) time.Time) Leg {
+	feed := p.feed
+	enter := feed.Connections[enterIdx]
+	exit := feed.Connections[exitIdx]
+	trip := &feed.Trips[enter.Trip]
+
+	path := make([]domain.Coordinate, 0, exit.Seq-enter.Seq+2)
+	for seq := enter.Seq; seq <= exit.Seq+1; seq++ {
+		path = append(path, feed.Stops[trip.Stops[seq]].Location)
+	}
+
+	line := feed.Lines[trip.Line]
+	first := feed.Stops[trip.Stops[enter.Seq]]
+	final := feed.Stops[trip.Stops[exit.Seq+1]]
+
+	return Leg{
+		Kind:      legKind(line.Type),
+		Line:      line.Name,
+		FromName:  first.Name,
+		ToName:    final.Name,
+		From:      first.Location,
+		To:        final.Location,
+		Departure: at(enter.Departure + offset),
+		Arrival:   at(exit.Arrival + offset),
+		Path:      path,
+	}
+}
+
+// legKind maps GTFS route_type, including the extended Hierarchical Vehicle
+// Types ranges, to a leg kind.
+func legKind(routeType int) string {
+	switch {
+	case routeType == 0 || (routeType >= 900 && routeType < 1000):
+		return LegTram
+	case routeType == 1 || (routeType >= 400 && routeType < 500):
+		return LegSubway
+	case routeType == 2 || (routeType >= 100 && routeType < 200):
+		return LegRail
+	case routeType == 4 || (routeType >= 1000 && routeType < 1100):
+		return LegFerry
+	case routeType == 11 || routeType == 800:
+		return LegTrolleybus
+	case routeType == 12 || routeType == 405:
+		return LegMonorail
+	default:
+		return LegBus
+	}
+}
+
+func reverse(legs []Leg) {
+	for i, j := 0, len(legs)-1; i < j; i, j = i+1, j-1 {
+		legs[i], legs[j] = legs[j], legs[i]
+	}
+}
+
+func walkSeconds(straightM float64) int32 {
+	return int32(math.Ceil(straightM * walkCircuity / walkSpeedMps))
+}
+
+func distanceM(a, b domain.Coordinate) float64 {
+	const earthRadiusM = 6371000
+
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(h))
+}
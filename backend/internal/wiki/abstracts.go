@@ -0,0 +1,119 @@
+package wiki
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// minAbstractLength drops abstracts that are leftovers of templates and
+// tables rather than text.
+const minAbstractLength = 40
+
+// Abstract is the lead paragraph of a Wikipedia article.
+type Abstract struct {
+	Title string
+	URL   string
+	Text  string
+}
+
+type abstractDoc struct {
+	Title    string `xml:"title"`
+	URL      string `xml:"url"`
+	Abstract string `xml:"abstract"`
+}
+
+// ReadAbstracts scans a Wikipedia abstract dump
+// (ruwiki-latest-abstract.xml, optionally .gz or .bz2) and returns the
+// abstracts of the articles with the given titles, keyed by NormalizeTitle.
+func ReadAbstracts(ctx context.Context, path string, titles map[string]bool) (map[string]Abstract, error) {
+	abstracts := make(map[string]Abstract, len(titles))
+	if len(titles) == 0 {
+		return abstracts, nil
+	}
+
+	dump, err := openDump(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dump.Close()
+
+	dec := xml.NewDecoder(dump)
+	for n := 0; ; {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return abstracts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read wikipedia dump: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "doc" {
+			continue
+		}
+
+		if n++; n%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		var doc abstractDoc
+		if err := dec.DecodeElement(&doc, &start); err != nil {
+			return nil, fmt.Errorf("read wikipedia dump: %w", err)
+		}
+
+		title := NormalizeTitle(trimTitlePrefix(doc.Title))
+		text := strings.TrimSpace(doc.Abstract)
+		if !titles[title] || utf8.RuneCountInString(text) < minAbstractLength || strings.ContainsAny(text[:1], "{|[") {
+			continue
+		}
+		abstracts[title] = Abstract{Title: title, URL: doc.URL, Text: text}
+		if len(abstracts) == len(titles) {
+			return abstracts, nil
+		}
+	}
+}
+
+// trimTitlePrefix removes the "Википедия: " prefix the dumps add to
+// article titles.
+func trimTitlePrefix(title string) string {
+	for _, prefix := range []string{"Википедия: ", "Wikipedia: "} {
+		if strings.HasPrefix(title, prefix) {
+			return title[len(prefix):]
+		}
+	}
+	return title
+}
+
+// NormalizeTitle makes titles from OSM tags, Wikidata sitelinks and dumps
+// comparable: underscores become spaces and the first letter is upper
+// case, as in Wikipedia.
+func NormalizeTitle(title string) string {
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	r, size := utf8.DecodeRuneInString(title)
+	if size == 0 {
+		return ""
+	}
+	return strings.ToUpper(string(r)) + title[size:]
+}
+
+// RuTitle returns the title of a Russian article from an OSM wikipedia tag
+// ("ru:Храм Христа Спасителя"); tags of other languages give "".
+func RuTitle(tag string) string {
+	lang, title, ok := strings.Cut(tag, ":")
+	if !ok || strings.TrimSpace(lang) != "ru" {
+		return ""
+	}
+	return NormalizeTitle(title)
+}
+
+// ArticleURL returns the address of a Russian Wikipedia article.
+func ArticleURL(title string) string {
+	return "https://ru.wikipedia.org/wiki/" + strings.ReplaceAll(title, " ", "_")
+}
@@ -0,0 +1,53 @@
+// Package wiki reads the entities and abstracts of POIs from locally
+// downloaded Wikidata JSON and Wikipedia abstract dumps.
+package wiki
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// dumpFile is an open dump, decompressed according to its extension
+// (.gz or .bz2).
+type dumpFile struct {
+	io.Reader
+	file *os.File
+	gz   *gzip.Reader
+}
+
+func openDump(path string) (*dumpFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open dump: %w", err)
+	}
+
+	d := &dumpFile{file: f}
+	buffered := bufio.NewReaderSize(f, 1<<20)
+
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		d.gz, err = gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("open gzip dump: %w", err)
+		}
+		d.Reader = d.gz
+	case strings.HasSuffix(path, ".bz2"):
+		d.Reader = bzip2.NewReader(buffered)
+	default:
+		d.Reader = buffered
+	}
+	return d, nil
+}
+
+func (d *dumpFile) Close() error {
+	if d.gz != nil {
+		d.gz.Close()
+	}
+	return d.file.Close()
+}
@@ -0,0 +1,322 @@
+package wiki
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dremotha/mapbot/internal/domain"
+)
+
+// Wikidata properties and items read from the dump.
+const (
+	propInception = "P571"
+	propImage     = "P18"
+	propHeritage  = "P1435"
+	// propSourcing qualifies a date as approximate with itemCirca.
+	propSourcing = "P1480"
+	itemCirca    = "Q5727902"
+)
+
+// designationLabels names common heritage designations, so that POIs get
+// their status even when the designation item precedes them in the dump
+// and cannot be picked up in the same pass.
+var designationLabels = map[string]string{
+	"Q9259":     "объект всемирного наследия ЮНЕСКО",
+	"Q8346700":  "объект культурного наследия России",
+	"Q23668083": "объект культурного наследия России федерального значения",
+}
+
+// Entity is the part of a Wikidata item the enrichment uses. Texts are
+// Russian; Label falls back to English.
+type Entity struct {
+	ID          string
+	Label       string
+	Description string
+	// RuWikiTitle is the title of the Russian Wikipedia article.
+	RuWikiTitle string
+	Inception   *Date
+	// Heritage lists the items of the heritage designations (P1435).
+	Heritage []string
+	// HeritageLabels names the designations of Heritage that have a known
+	// label, in the same order.
+	HeritageLabels []string
+	// Image is a Wikimedia Commons file name (P18).
+	Image string
+}
+
+// Date is a year with its precision; for decades and centuries the year is
+// the first one.
+type Date struct {
+	Year      int
+	Precision domain.DatePrecision
+}
+
+type rawEntity struct {
+	ID           string                            `json:"id"`
+	Labels       map[string]langValue              `json:"labels"`
+	Descriptions map[string]langValue              `json:"descriptions"`
+	Claims       map[string]json.RawMessage        `json:"claims"`
+	Sitelinks    map[string]struct{ Title string } `json:"sitelinks"`
+}
+
+type langValue struct {
+	Value string `json:"value"`
+}
+
+type rawStatement struct {
+	Mainsnak   rawSnak              `json:"mainsnak"`
+	Qualifiers map[string][]rawSnak `json:"qualifiers"`
+	Rank       string               `json:"rank"`
+}
+
+type rawSnak struct {
+	Snaktype  string `json:"snaktype"`
+	Datavalue struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"datavalue"`
+}
+
+type timeValue struct {
+	Time      string `json:"time"`
+	Precision int    `json:"precision"`
+}
+
+type itemValue struct {
+	ID string `json:"id"`
+}
+
+// ReadEntities scans a Wikidata JSON dump (latest-all.json, optionally
+// .gz or .bz2, or a filtered dump of the same format) and returns the
+// entities with the given IDs. The dump holds one entity per line; lines
+// of other entities are skipped without decoding.
+//
+// Heritage designations are named in the same pass: labels of designation
+// items found after an entity that refers to them are collected, the
+// common ones are known in advance. The scan stops once every ID is found
+// and every designation named.
+func ReadEntities(ctx context.Context, path string, ids map[string]bool) (map[string]*Entity, error) {
+	entities := make(map[string]*Entity, len(ids))
+	if len(ids) == 0 {
+		return entities, nil
+	}
+
+	labels := make(map[string]string, len(designationLabels))
+	for id, label := range designationLabels {
+		labels[id] = label
+	}
+	pending := make(map[string]bool)
+	wanted := func(id string) bool { return ids[id] || pending[id] }
+
+	done := func() map[string]*Entity {
+		for _, e := range entities {
+			for _, id := range e.Heritage {
+				if label := labels[id]; label != "" {
+					e.HeritageLabels = append(e.HeritageLabels, label)
+				}
+			}
+		}
+		return entities
+	}
+
+	dump, err := openDump(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dump.Close()
+
+	r := bufio.NewReaderSize(dump, 1<<20)
+	for n := 0; ; n++ {
+		if n%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if e, ok, perr := parseEntityLine(line, wanted); perr != nil {
+				return nil, fmt.Errorf("wikidata dump line %d: %w", n+1, perr)
+			} else if ok {
+				if pending[e.ID] {
+					delete(pending, e.ID)
+					labels[e.ID] = e.Label
+				}
+				if ids[e.ID] && entities[e.ID] == nil {
+					entities[e.ID] = e
+					for _, id := range e.Heritage {
+						if _, known := labels[id]; !known {
+							pending[id] = true
+						}
+					}
+				}
+				if len(entities) == len(ids) && len(pending) == 0 {
+					return done(), nil
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return done(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read wikidata dump: %w", err)
+		}
+	}
+}
+
+// parseEntityLine decodes a dump line if it holds a wanted entity. The
+// array brackets around the entities are skipped.
+func parseEntityLine(line []byte, wanted func(string) bool) (*Entity, bool, error) {
+	line = bytes.TrimRight(bytes.TrimSpace(line), ",")
+	if len(line) < 2 || line[0] != '{' {
+		return nil, false, nil
+	}
+
+	if id, ok := peekID(line); ok && !wanted(id) {
+		return nil, false, nil
+	}
+
+	var raw rawEntity
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, false, err
+	}
+	if !wanted(raw.ID) {
+		return nil, false, nil
+	}
+	return newEntity(&raw), true, nil
+}
+
+// peekID finds the entity ID near the start of the line, where the dumps
+// put it, without decoding the rest.
+func peekID(line []byte) (string, bool) {
+	head := line
+	if len(head) > 256 {
+		head = head[:256]
+	}
+	i := bytes.Index(head, []byte(`"id":"`))
+	if i < 0 {
+		return "", false
+	}
+	rest := head[i+len(`"id":"`):]
+	end := bytes.IndexByte(rest, '"')
+	if end < 0 {
+		return "", false
+	}
+	return string(rest[:end]), true
+}
+
+func newEntity(raw *rawEntity) *Entity {
+	e := &Entity{
+		ID:          raw.ID,
+		Label:       raw.Labels["ru"].Value,
+		Description: raw.Descriptions["ru"].Value,
+		RuWikiTitle: raw.Sitelinks["ruwiki"].Title,
+	}
+	if e.Label == "" {
+		e.Label = raw.Labels["en"].Value
+	}
+
+	for _, st := range statements(raw.Claims[propInception]) {
+		if d, ok := parseTime(st); ok {
+			e.Inception = &d
+			break
+		}
+	}
+	for _, st := range statements(raw.Claims[propImage]) {
+		var image string
+		if json.Unmarshal(st.Mainsnak.Datavalue.Value, &image) == nil && image != "" {
+			e.Image = image
+			break
+		}
+	}
+	for _, st := range statements(raw.Claims[propHeritage]) {
+		var item itemValue
+		if json.Unmarshal(st.Mainsnak.Datavalue.Value, &item) == nil && item.ID != "" {
+			e.Heritage = append(e.Heritage, item.ID)
+		}
+	}
+	return e
+}
+
+// statements decodes the statements of a property that have a value:
+// preferred ones first, deprecated ones dropped.
+func statements(data json.RawMessage) []rawStatement {
+	var all []rawStatement
+	if len(data) == 0 || json.Unmarshal(data, &all) != nil {
+		return nil
+	}
+
+	var preferred, normal []rawStatement
+	for _, st := range all {
+		if st.Mainsnak.Snaktype != "value" {
+			continue
+		}
+		switch st.Rank {
+		case "preferred":
+			preferred = append(preferred, st)
+		case "deprecated":
+		default:
+			normal = append(normal, st)
+		}
+	}
+	return append(preferred, normal...)
+}
+
+// parseTime converts a Wikidata time value ("+1765-00-00T00:00:00Z" with
+// precision 9 for a year, 8 for a decade, 7 for a century, 10 and 11 for
+// a month and a day). Years before the Common Era and dates less precise
+// than a century are skipped.
+func parseTime(st rawStatement) (Date, bool) {
+	var t timeValue
+	if err := json.Unmarshal(st.Mainsnak.Datavalue.Value, &t); err != nil {
+		return Date{}, false
+	}
+	if !strings.HasPrefix(t.Time, "+") {
+		return Date{}, false
+	}
+	yearStr, _, _ := strings.Cut(t.Time[1:], "-")
+	year, err := strconv.Atoi(yearStr)
+	if err != nil || year < 1 {
+		return Date{}, false
+	}
+
+	d := Date{Year: year}
+	switch {
+	case t.Precision >= 11:
+		d.Precision = domain.DatePrecisionDay
+	case t.Precision == 10:
+		d.Precision = domain.DatePrecisionMonth
+	case t.Precision == 9:
+		d.Precision = domain.DatePrecisionYear
+	case t.Precision == 8:
+		d.Year -= year % 10
+		d.Precision = domain.DatePrecisionDecade
+	case t.Precision == 7:
+		// The year is any year of the century, usually its last one
+		d.Year = (domain.Century(year)-1)*100 + 1
+		d.Precision = domain.DatePrecisionCentury
+	default:
+		return Date{}, false
+	}
+
+	if d.Precision == domain.DatePrecisionYear || d.Precision == domain.DatePrecisionMonth || d.Precision == domain.DatePrecisionDay {
+		for _, q := range st.Qualifiers[propSourcing] {
+			var item itemValue
+			if json.Unmarshal(q.Datavalue.Value, &item) == nil && item.ID == itemCirca {
+				d.Precision = domain.DatePrecisionCirca
+			}
+		}
+	}
+	return d, true
+}
+
+// EntityURL returns the address of a Wikidata item.
+func EntityURL(id string) string {
+	return "https://www.wikidata.org/wiki/" + id
+}
@@ -0,0 +1,19 @@
+-- Сохранённые маршруты
+CREATE TABLE IF NOT EXISTS saved_routes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    share_code VARCHAR(16) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    mode VARCHAR(20) NOT NULL,
+
+    distance_km FLOAT NOT NULL,
+    duration_min FLOAT NOT NULL,
+    geometry TEXT NOT NULL,
+
+    waypoints JSONB NOT NULL DEFAULT '[]',
+    pois JSONB NOT NULL DEFAULT '[]',
+    metadata JSONB NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_routes_created ON saved_routes(created_at DESC);
@@ -0,0 +1,32 @@
+-- Готовые тематические маршруты
+CREATE TABLE IF NOT EXISTS preset_routes (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    category VARCHAR(50) REFERENCES categories(id),
+    mode VARCHAR(20) NOT NULL DEFAULT 'walking',
+    difficulty VARCHAR(20) NOT NULL DEFAULT 'easy',
+    duration_hours FLOAT DEFAULT 0,
+    distance_km FLOAT DEFAULT 0,
+    image VARCHAR(500),
+    tags JSONB DEFAULT '[]',
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_preset_routes_category ON preset_routes(category);
+
+-- Остановки маршрута в порядке посещения
+CREATE TABLE IF NOT EXISTS preset_route_stops (
+    preset_id VARCHAR(64) NOT NULL REFERENCES preset_routes(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    poi_id UUID REFERENCES poi(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    note TEXT,
+    location GEOGRAPHY(POINT, 4326) NOT NULL,
+
+    PRIMARY KEY (preset_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_preset_route_stops_poi ON preset_route_stops(poi_id);
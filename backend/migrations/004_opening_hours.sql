@@ -0,0 +1,3 @@
+-- Часы работы из тега OSM opening_hours:
+-- {"raw": "Tu-Su 10:00-18:00", "week": [[...], ...], "unparsed": false}
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS opening_hours JSONB;
@@ -0,0 +1,18 @@
+-- Повторный импорт обновляет POI вместо вставки копий.
+-- Идентификатор объекта OSM — (source, osm_type, osm_id): номера узлов,
+-- линий и отношений в OSM пересекаются.
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS osm_type VARCHAR(10);
+
+-- POI, исчезнувшие из OSM, помечаются удалёнными и не попадают в выдачу
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Копии, оставшиеся от прежних импортов: сохраняется самая ранняя запись
+DELETE FROM poi a
+USING poi b
+WHERE a.osm_id IS NOT NULL
+    AND a.source = b.source
+    AND a.osm_id = b.osm_id
+    AND a.name = b.name
+    AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poi_source_osm ON poi(source, osm_type, osm_id);
@@ -0,0 +1,8 @@
+-- Последний применённый diff репликации OSM для каждого источника
+-- (importer -update)
+CREATE TABLE IF NOT EXISTS osm_replication_state (
+    source VARCHAR(500) PRIMARY KEY,
+    sequence_number BIGINT NOT NULL,
+    timestamp TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
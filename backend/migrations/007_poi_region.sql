@@ -0,0 +1,8 @@
+-- Регион импорта (id из configs/regions.yaml): один экземпляр сервиса
+-- может обслуживать несколько городов
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS region VARCHAR(50);
+
+-- До появления регионов импортировалась только Москва
+UPDATE poi SET region = 'moscow' WHERE region IS NULL AND source = 'osm';
+
+CREATE INDEX IF NOT EXISTS idx_poi_region ON poi(region);
@@ -0,0 +1,12 @@
+-- Прогресс импорта из Overpass по тайлам: прерванный импорт продолжается
+-- с первого незавершённого тайла. Для каждого тайла хранятся объекты OSM,
+-- импортированные из него, — по ним в конце определяются исчезнувшие POI.
+CREATE TABLE IF NOT EXISTS osm_import_tiles (
+    run_key VARCHAR(500) NOT NULL,
+    tile INTEGER NOT NULL,
+    bbox VARCHAR(100) NOT NULL,
+    osm_types TEXT[] NOT NULL DEFAULT '{}',
+    osm_ids BIGINT[] NOT NULL DEFAULT '{}',
+    completed_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (run_key, tile)
+);
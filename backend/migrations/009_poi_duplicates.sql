@@ -0,0 +1,20 @@
+-- Объединение дублей: один объект в OSM часто отмечен дважды (точка
+-- amenity=place_of_worship и контур building=church). Дубль остаётся в
+-- таблице, чтобы импорт по-прежнему сопоставлял его объект OSM, но скрыт
+-- (deleted_at) и ссылается на основную запись.
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS merged_into UUID REFERENCES poi(id) ON DELETE SET NULL;
+
+-- Другие названия объекта, собранные с объединённых дублей
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS aliases JSONB DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_poi_merged_into ON poi(merged_into);
+
+-- Теги объекта: собственные теги и теги объединённых с ним дублей, которых
+-- среди собственных нет. Импорт пересчитывает их при каждом обновлении.
+CREATE OR REPLACE FUNCTION poi_merged_tags(canonical UUID, own JSONB) RETURNS JSONB AS $$
+    SELECT own || COALESCE((
+        SELECT jsonb_agg(DISTINCT t.tag)
+        FROM poi d, jsonb_array_elements(d.tags) AS t(tag)
+        WHERE d.merged_into = canonical AND NOT own @> jsonb_build_array(t.tag)
+    ), '[]'::jsonb)
+$$ LANGUAGE SQL STABLE;
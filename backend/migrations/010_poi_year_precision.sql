@@ -0,0 +1,8 @@
+-- Точность годов постройки и разрушения: день, месяц, год, circa
+-- («~1700»), range (начало диапазона «1890..1895»), decade (первый год
+-- десятилетия), century (первый год века, 1701 для «C18»)
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS year_built_precision VARCHAR(10);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS year_destroyed_precision VARCHAR(10);
+
+-- Фильтр поиска по периоду постройки
+CREATE INDEX IF NOT EXISTS idx_poi_year_built ON poi(year_built);
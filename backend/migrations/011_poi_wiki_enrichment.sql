@@ -0,0 +1,23 @@
+-- Ссылки объекта OSM на Викиданные (Q-идентификатор) и Википедию ("ru:Название")
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS wikidata VARCHAR(20);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS wikipedia VARCHAR(255);
+
+-- Данные из дампов Викиданных и Википедии (importer -enrich). Хранятся
+-- отдельно от полей OSM, чтобы импорт их не затирал; описание и год
+-- постройки из них используются, когда в OSM этих данных нет.
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS wiki_description TEXT;
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS wiki_year_built INTEGER;
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS wiki_year_built_precision VARCHAR(10);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS heritage VARCHAR(500);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS image VARCHAR(255);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS enriched_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_poi_wikidata ON poi(wikidata) WHERE wikidata IS NOT NULL;
+
+-- Фильтр по периоду учитывает и год постройки из Викиданных
+DROP INDEX IF EXISTS idx_poi_year_built;
+CREATE INDEX IF NOT EXISTS idx_poi_year_built ON poi((COALESCE(year_built, wiki_year_built)));
+
+-- Источник факта: wikidata или пусто для добавленных вручную.
+-- Обогащение заменяет только факты из Викиданных.
+ALTER TABLE historical_facts ADD COLUMN IF NOT EXISTS source VARCHAR(20);
@@ -0,0 +1,3 @@
+-- Удаление сохранённого маршрута только по токену, выданному при сохранении.
+-- У маршрутов, сохранённых раньше, хэш остаётся NULL: через API они не удаляются.
+ALTER TABLE saved_routes ADD COLUMN IF NOT EXISTS delete_token_hash VARCHAR(64);
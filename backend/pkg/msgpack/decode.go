@@ -0,0 +1,501 @@
+package msgpack
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var errShortData = errors.New("msgpack: unexpected end of data")
+
+// Unmarshal decodes MessagePack data into the value pointed to by v.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("msgpack: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+
+	d := &decoder{data: data}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errShortData
+	}
+	return d.data[d.pos], nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errShortData
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) decode(v reflect.Value) error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+
+	if c == 0xc0 {
+		d.pos++
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	}
+
+	if v.CanAddr() && implementsTextUnmarshaler(v.Type()) {
+		text, err := d.readRaw()
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(text)
+	}
+
+	if v.Kind() == reflect.Interface {
+		value, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("msgpack: cannot assign %s to %s", rv.Type(), v.Type())
+		}
+		v.Set(rv)
+		return nil
+	}
+
+	switch {
+	case c == 0xc2 || c == 0xc3:
+		d.pos++
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("msgpack: cannot decode bool into %s", v.Type())
+		}
+		v.SetBool(c == 0xc3)
+		return nil
+	case isNumber(c):
+		return d.decodeNumber(v)
+	case isString(c) || isBin(c):
+		b, err := d.readRaw()
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+		default:
+			return fmt.Errorf("msgpack: cannot decode string into %s", v.Type())
+		}
+		return nil
+	case isArray(c):
+		n, err := d.readArrayLen()
+		if err != nil {
+			return err
+		}
+		return d.decodeArray(v, n)
+	case isMap(c):
+		n, err := d.readMapLen()
+		if err != nil {
+			return err
+		}
+		return d.decodeMap(v, n)
+	}
+
+	return fmt.Errorf("msgpack: unsupported format 0x%02x", c)
+}
+
+func (d *decoder) decodeArray(v reflect.Value, n int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if n > len(d.data)-d.pos {
+			return errShortData
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := d.decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < n; i++ {
+			if i >= v.Len() {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		for i := n; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot decode array into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(v reflect.Value, n int) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := structFieldMap(v.Type())
+		for i := 0; i < n; i++ {
+			key, err := d.readRaw()
+			if err != nil {
+				return err
+			}
+			f, ok := fields[string(key)]
+			if !ok {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(fieldByIndex(v, f.index, true)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), n))
+		}
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(value); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot decode map into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) decodeNumber(v reflect.Value) error {
+	i, u, f, kind, err := d.readNumber()
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch kind {
+		case numUint:
+			i = int64(u)
+		case numFloat:
+			i = int64(f)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch kind {
+		case numInt:
+			if i < 0 {
+				return fmt.Errorf("msgpack: %d overflows %s", i, v.Type())
+			}
+			u = uint64(i)
+		case numFloat:
+			u = uint64(f)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("msgpack: %d overflows %s", u, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch kind {
+		case numInt:
+			f = float64(i)
+		case numUint:
+			f = float64(u)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("msgpack: cannot decode number into %s", v.Type())
+	}
+	return nil
+}
+
+const (
+	numInt = iota
+	numUint
+	numFloat
+)
+
+func (d *decoder) readNumber() (int64, uint64, float64, int, error) {
+	c, err := d.peek()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	d.pos++
+
+	switch {
+	case c <= 0x7f:
+		return 0, uint64(c), 0, numUint, nil
+	case c >= 0xe0:
+		return int64(int8(c)), 0, 0, numInt, nil
+	}
+
+	switch c {
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (c - 0xcc))
+		return 0, u, 0, numUint, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.readUint(size)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+		switch size {
+		case 1:
+			return int64(int8(u)), 0, 0, numInt, nil
+		case 2:
+			return int64(int16(u)), 0, 0, numInt, nil
+		case 4:
+			return int64(int32(u)), 0, 0, numInt, nil
+		default:
+			return int64(u), 0, 0, numInt, nil
+		}
+	case 0xca:
+		u, err := d.readUint(4)
+		return 0, 0, float64(math.Float32frombits(uint32(u))), numFloat, err
+	case 0xcb:
+		u, err := d.readUint(8)
+		return 0, 0, math.Float64frombits(u), numFloat, err
+	}
+
+	return 0, 0, 0, 0, fmt.Errorf("msgpack: 0x%02x is not a number", c)
+}
+
+// readRaw reads a str or bin value.
+func (d *decoder) readRaw() ([]byte, error) {
+	c, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	d.pos++
+
+	var n uint64
+	switch {
+	case c >= 0xa0 && c <= 0xbf:
+		n = uint64(c & 0x1f)
+	case c == 0xd9 || c == 0xc4:
+		n, err = d.readUint(1)
+	case c == 0xda || c == 0xc5:
+		n, err = d.readUint(2)
+	case c == 0xdb || c == 0xc6:
+		n, err = d.readUint(4)
+	default:
+		return nil, fmt.Errorf("msgpack: 0x%02x is not a string", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.read(int(n))
+}
+
+func (d *decoder) readArrayLen() (int, error) {
+	c, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	d.pos++
+
+	switch {
+	case c >= 0x90 && c <= 0x9f:
+		return int(c & 0x0f), nil
+	case c == 0xdc:
+		n, err := d.readUint(2)
+		return int(n), err
+	case c == 0xdd:
+		n, err := d.readUint(4)
+		return int(n), err
+	}
+	return 0, fmt.Errorf("msgpack: 0x%02x is not an array", c)
+}
+
+func (d *decoder) readMapLen() (int, error) {
+	c, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	d.pos++
+
+	switch {
+	case c >= 0x80 && c <= 0x8f:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		n, err := d.readUint(2)
+		return int(n), err
+	case c == 0xdf:
+		n, err := d.readUint(4)
+		return int(n), err
+	}
+	return 0, fmt.Errorf("msgpack: 0x%02x is not a map", c)
+}
+
+// decodeAny decodes the next value into nil, bool, int64, uint64, float64,
+// string, []byte, []interface{} or map[string]interface{}.
+func (d *decoder) decodeAny() (interface{}, error) {
+	c, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c == 0xc0:
+		d.pos++
+		return nil, nil
+	case c == 0xc2 || c == 0xc3:
+		d.pos++
+		return c == 0xc3, nil
+	case isNumber(c):
+		i, u, f, kind, err := d.readNumber()
+		switch kind {
+		case numInt:
+			return i, err
+		case numUint:
+			return u, err
+		default:
+			return f, err
+		}
+	case isString(c):
+		b, err := d.readRaw()
+		return string(b), err
+	case isBin(c):
+		b, err := d.readRaw()
+		return append([]byte(nil), b...), err
+	case isArray(c):
+		n, err := d.readArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > len(d.data)-d.pos {
+			return nil, errShortData
+		}
+		result := make([]interface{}, n)
+		for i := range result {
+			if result[i], err = d.decodeAny(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case isMap(c):
+		n, err := d.readMapLen()
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = value
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("msgpack: unsupported format 0x%02x", c)
+}
+
+func (d *decoder) skip() error {
+	_, err := d.decodeAny()
+	return err
+}
+
+var textUnmarshalerCache sync.Map // map[reflect.Type]bool
+
+// implementsTextUnmarshaler reports whether *t implements
+// encoding.TextUnmarshaler.
+func implementsTextUnmarshaler(t reflect.Type) bool {
+	if cached, ok := textUnmarshalerCache.Load(t); ok {
+		return cached.(bool)
+	}
+	ok := reflect.PointerTo(t).Implements(textUnmarshalerType)
+	textUnmarshalerCache.Store(t, ok)
+	return ok
+}
+
+func isNumber(c byte) bool {
+	return c <= 0x7f || c >= 0xe0 || (c >= 0xca && c <= 0xd3)
+}
+
+func isString(c byte) bool {
+	return (c >= 0xa0 && c <= 0xbf) || (c >= 0xd9 && c <= 0xdb)
+}
+
+func isBin(c byte) bool {
+	return c >= 0xc4 && c <= 0xc6
+}
+
+func isArray(c byte) bool {
+	return (c >= 0x90 && c <= 0x9f) || c == 0xdc || c == 0xdd
+}
+
+func isMap(c byte) bool {
+	return (c >= 0x80 && c <= 0x8f) || c == 0xde || c == 0xdf
+}
@@ -0,0 +1,139 @@
+// Package msgpack implements MessagePack encoding of Go values. Structs are
+// encoded as maps keyed by their json tag names, so a type serializes to the
+// same shape as with encoding/json. Types implementing encoding.TextMarshaler
+// (uuid.UUID, time.Time) are encoded as strings.
+package msgpack
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := collectFields(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, parent []int) []field {
+	var fields []field
+	var embedded []field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, collectFields(ft, index)...)
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			index:     index,
+			omitEmpty: strings.Contains(opts, "omitempty"),
+		})
+	}
+
+	// Like encoding/json, fields of the outer struct win over promoted ones.
+	for _, f := range embedded {
+		if !hasField(fields, f.name) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func hasField(fields []field, name string) bool {
+	for _, f := range fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the field value, allocating nil embedded pointers
+// when alloc is set. It returns an invalid value if a nil embedded pointer
+// is in the way and alloc is not set.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+var fieldMapCache sync.Map // map[reflect.Type]map[string]field
+
+func structFieldMap(t reflect.Type) map[string]field {
+	if cached, ok := fieldMapCache.Load(t); ok {
+		return cached.(map[string]field)
+	}
+
+	fields := structFields(t)
+	m := make(map[string]field, len(fields))
+	for _, f := range fields {
+		m[f.name] = f
+	}
+	fieldMapCache.Store(t, m)
+	return m
+}
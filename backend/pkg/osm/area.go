@@ -0,0 +1,221 @@
+package osm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseBBox parses "south,west,north,east" in degrees.
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("bbox must be south,west,north,east: %q", s)
+	}
+
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("invalid bbox coordinate %q", p)
+		}
+		v[i] = f
+	}
+
+	b := BBox{South: v[0], West: v[1], North: v[2], East: v[3]}
+	if err := b.Validate(); err != nil {
+		return BBox{}, err
+	}
+	return b, nil
+}
+
+func (b BBox) Validate() error {
+	if b.South < -90 || b.North > 90 || b.West < -180 || b.East > 180 {
+		return fmt.Errorf("bbox out of range: %v", b)
+	}
+	if b.South >= b.North || b.West >= b.East {
+		return fmt.Errorf("bbox is empty: %v", b)
+	}
+	return nil
+}
+
+// Split divides the box into a grid of tiles at most size degrees on each
+// side, so that every Overpass query stays small enough not to time out.
+func (b BBox) Split(size float64) []BBox {
+	if size <= 0 {
+		return []BBox{b}
+	}
+
+	rows := int(math.Ceil((b.North - b.South) / size))
+	cols := int(math.Ceil((b.East - b.West) / size))
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 1 {
+		cols = 1
+	}
+
+	latStep := (b.North - b.South) / float64(rows)
+	lonStep := (b.East - b.West) / float64(cols)
+
+	tiles := make([]BBox, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			tile := BBox{
+				South: b.South + float64(r)*latStep,
+				West:  b.West + float64(c)*lonStep,
+				North: b.South + float64(r+1)*latStep,
+				East:  b.West + float64(c+1)*lonStep,
+			}
+			// Avoid gaps from rounding at the outer edges
+			if r == rows-1 {
+				tile.North = b.North
+			}
+			if c == cols-1 {
+				tile.East = b.East
+			}
+			tiles = append(tiles, tile)
+		}
+	}
+	return tiles
+}
+
+func (b BBox) String() string {
+	return fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", b.South, b.West, b.North, b.East)
+}
+
+// Polygon is a GeoJSON-style multipolygon: a list of polygons, each a list
+// of closed rings where the first ring is the outer boundary and the rest
+// are holes.
+type Polygon [][][]Point
+
+// Contains reports whether the point lies inside the polygon (even-odd
+// rule over all rings).
+func (p Polygon) Contains(lat, lon float64) bool {
+	for _, poly := range p {
+		inside := false
+		for _, ring := range poly {
+			if ringContains(ring, lat, lon) {
+				inside = !inside
+			}
+		}
+		if inside {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the bounding box of the outer rings.
+func (p Polygon) Bounds() BBox {
+	b := BBox{South: 90, West: 180, North: -90, East: -180}
+	for _, poly := range p {
+		if len(poly) == 0 {
+			continue
+		}
+		for _, pt := range poly[0] {
+			b.South = math.Min(b.South, pt.Lat)
+			b.North = math.Max(b.North, pt.Lat)
+			b.West = math.Min(b.West, pt.Lon)
+			b.East = math.Max(b.East, pt.Lon)
+		}
+	}
+	return b
+}
+
+// Intersects reports whether the polygon overlaps the box.
+func (p Polygon) Intersects(b BBox) bool {
+	corners := []Point{
+		{Lat: b.South, Lon: b.West}, {Lat: b.South, Lon: b.East},
+		{Lat: b.North, Lon: b.East}, {Lat: b.North, Lon: b.West},
+	}
+	for _, c := range corners {
+		if p.Contains(c.Lat, c.Lon) {
+			return true
+		}
+	}
+
+	for _, poly := range p {
+		for _, ring := range poly {
+			for i := 0; i+1 < len(ring); i++ {
+				if segmentIntersectsBox(ring[i], ring[i+1], b) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// WKT returns the polygon as an EWKT multipolygon for PostGIS.
+func (p Polygon) WKT() string {
+	var sb strings.Builder
+	sb.WriteString("SRID=4326;MULTIPOLYGON(")
+	for i, poly := range p {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("(")
+		for j, ring := range poly {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("(")
+			for k, pt := range ring {
+				if k > 0 {
+					sb.WriteString(",")
+				}
+				fmt.Fprintf(&sb, "%f %f", pt.Lon, pt.Lat)
+			}
+			sb.WriteString(")")
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func ringContains(ring []Point, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentIntersectsBox clips the segment to the box (Liang–Barsky).
+func segmentIntersectsBox(a, b Point, box BBox) bool {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.Lon-a.Lon, b.Lat-a.Lat
+
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q >= 0
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+		return true
+	}
+
+	return clip(-dx, a.Lon-box.West) && clip(dx, box.East-a.Lon) &&
+		clip(-dy, a.Lat-box.South) && clip(dy, box.North-a.Lat)
+}
@@ -0,0 +1,242 @@
+package osm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// ChangeSet is the net effect of an osmChange document on the elements
+// selected by a filter.
+type ChangeSet struct {
+	// Upserts are created or modified elements that match the filter. Ways
+	// and relations get a Center only when all their nodes are in the
+	// document; otherwise the stored location has to be kept.
+	Upserts []Element
+	// Deletes are deleted elements and modified ones that no longer match
+	// the filter or left the bbox. Only Type and ID are set.
+	Deletes []Element
+}
+
+type changeTag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+type changeNode struct {
+	ID   int64       `xml:"id,attr"`
+	Lat  float64     `xml:"lat,attr"`
+	Lon  float64     `xml:"lon,attr"`
+	Tags []changeTag `xml:"tag"`
+}
+
+type changeWay struct {
+	ID    int64 `xml:"id,attr"`
+	Nodes []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []changeTag `xml:"tag"`
+}
+
+type changeRelation struct {
+	ID      int64 `xml:"id,attr"`
+	Members []struct {
+		Type string `xml:"type,attr"`
+		Ref  int64  `xml:"ref,attr"`
+	} `xml:"member"`
+	Tags []changeTag `xml:"tag"`
+}
+
+// changeEntry is the last state of an element in the document.
+type changeEntry struct {
+	action   string
+	typ      string
+	id       int64
+	tags     map[string]string
+	lat, lon float64
+	refs     []int64
+	members  []pbfMember
+}
+
+// ReadChange parses an osmChange document (.osc). When an element occurs
+// several times, its last occurrence wins.
+func ReadChange(r io.Reader, filter TagFilter, bbox *BBox) (*ChangeSet, error) {
+	type key struct {
+		typ string
+		id  int64
+	}
+
+	entries := make(map[key]*changeEntry)
+	var order []key
+	nodes := make(map[int64]Point)
+
+	dec := xml.NewDecoder(r)
+	action := ""
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse osmChange: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var entry *changeEntry
+
+		switch start.Name.Local {
+		case "osmChange":
+			continue
+		case "create", "modify", "delete":
+			action = start.Name.Local
+			continue
+		case "node":
+			var n changeNode
+			if err := dec.DecodeElement(&n, &start); err != nil {
+				return nil, fmt.Errorf("parse node: %w", err)
+			}
+			entry = &changeEntry{typ: "node", id: n.ID, tags: changeTags(n.Tags), lat: n.Lat, lon: n.Lon}
+			if action == "delete" {
+				delete(nodes, n.ID)
+			} else {
+				nodes[n.ID] = Point{Lat: n.Lat, Lon: n.Lon}
+			}
+		case "way":
+			var w changeWay
+			if err := dec.DecodeElement(&w, &start); err != nil {
+				return nil, fmt.Errorf("parse way: %w", err)
+			}
+			entry = &changeEntry{typ: "way", id: w.ID, tags: changeTags(w.Tags)}
+			for _, nd := range w.Nodes {
+				entry.refs = append(entry.refs, nd.Ref)
+			}
+		case "relation":
+			var rel changeRelation
+			if err := dec.DecodeElement(&rel, &start); err != nil {
+				return nil, fmt.Errorf("parse relation: %w", err)
+			}
+			entry = &changeEntry{typ: "relation", id: rel.ID, tags: changeTags(rel.Tags)}
+			for _, m := range rel.Members {
+				switch m.Type {
+				case "node":
+					entry.members = append(entry.members, pbfMember{id: m.Ref, typ: 0})
+				case "way":
+					entry.members = append(entry.members, pbfMember{id: m.Ref, typ: 1})
+				}
+			}
+		default:
+			if err := dec.Skip(); err != nil {
+				return nil, fmt.Errorf("parse osmChange: %w", err)
+			}
+			continue
+		}
+
+		if action == "" {
+			return nil, fmt.Errorf("parse osmChange: %s %d outside of create/modify/delete", entry.typ, entry.id)
+		}
+		entry.action = action
+
+		k := key{entry.typ, entry.id}
+		if _, seen := entries[k]; !seen {
+			order = append(order, k)
+		}
+		entries[k] = entry
+	}
+
+	lookup := func(id int64) (Point, bool) {
+		p, ok := nodes[id]
+		return p, ok
+	}
+
+	// Centres only from complete geometry: a partial way would move the POI.
+	var center func(e *changeEntry) *Point
+	center = func(e *changeEntry) *Point {
+		switch e.typ {
+		case "node":
+			return &Point{Lat: e.lat, Lon: e.lon}
+		case "way":
+			for _, ref := range e.refs {
+				if _, ok := nodes[ref]; !ok {
+					return nil
+				}
+			}
+			if p, ok := wayCenter(e.refs, lookup); ok {
+				return &p
+			}
+		case "relation":
+			points := make([]Point, 0, len(e.members))
+			for _, m := range e.members {
+				var p *Point
+				if m.typ == 0 {
+					if np, ok := nodes[m.id]; ok {
+						p = &np
+					}
+				} else if w, ok := entries[key{"way", m.id}]; ok && w.action != "delete" {
+					p = center(w)
+				}
+				if p == nil {
+					return nil
+				}
+				points = append(points, *p)
+			}
+			if len(points) > 0 {
+				p := meanPoint(points)
+				return &p
+			}
+		}
+		return nil
+	}
+
+	cs := &ChangeSet{}
+	for _, k := range order {
+		e := entries[k]
+		ref := Element{Type: e.typ, ID: e.id}
+
+		if e.action == "delete" {
+			cs.Deletes = append(cs.Deletes, ref)
+			continue
+		}
+
+		if !filter(e.typ, e.tags) {
+			if e.action == "modify" {
+				cs.Deletes = append(cs.Deletes, ref)
+			}
+			continue
+		}
+
+		el := Element{Type: e.typ, ID: e.id, Tags: e.tags}
+		c := center(e)
+		if c != nil && bbox != nil && !bbox.Contains(c.Lat, c.Lon) {
+			if e.action == "modify" {
+				cs.Deletes = append(cs.Deletes, ref)
+			}
+			continue
+		}
+
+		switch {
+		case e.typ == "node":
+			el.Lat, el.Lon = e.lat, e.lon
+		case c != nil:
+			el.Center = c
+		}
+		cs.Upserts = append(cs.Upserts, el)
+	}
+
+	return cs, nil
+}
+
+func changeTags(tags []changeTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.K] = t.V
+	}
+	return m
+}
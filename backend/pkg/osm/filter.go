@@ -0,0 +1,53 @@
+package osm
+
+import "fmt"
+
+// TagFilter selects elements for import. elementType is "node", "way" or
+// "relation", as in Overpass responses.
+type TagFilter func(elementType string, tags map[string]string) bool
+
+// HistoricPlacesFilter matches the elements selected by QueryHistoricPlaces.
+func HistoricPlacesFilter(elementType string, tags map[string]string) bool {
+	if tags["historic"] != "" {
+		return true
+	}
+	if elementType == "relation" {
+		return false
+	}
+	return tags["amenity"] == "place_of_worship" || isChurchBuilding(tags)
+}
+
+// ChurchesFilter matches the elements selected by QueryChurches.
+func ChurchesFilter(elementType string, tags map[string]string) bool {
+	if elementType == "relation" {
+		return false
+	}
+	return (tags["amenity"] == "place_of_worship" && tags["religion"] == "christian") || isChurchBuilding(tags)
+}
+
+// MemorialsFilter matches the elements selected by QueryMemorials.
+func MemorialsFilter(elementType string, tags map[string]string) bool {
+	if elementType == "relation" {
+		return false
+	}
+	return tags["historic"] == "memorial" || tags["historic"] == "monument"
+}
+
+func isChurchBuilding(tags map[string]string) bool {
+	switch tags["building"] {
+	case "church", "cathedral", "chapel":
+		return true
+	}
+	return false
+}
+
+// Contains reports whether the point lies inside the bbox.
+func (b BBox) Contains(lat, lon float64) bool {
+	return lat >= b.South && lat <= b.North && lon >= b.West && lon <= b.East
+}
+
+// WKT returns the box as an EWKT polygon for PostGIS.
+func (b BBox) WKT() string {
+	return fmt.Sprintf("SRID=4326;POLYGON((%f %f,%f %f,%f %f,%f %f,%f %f))",
+		b.West, b.South, b.East, b.South, b.East, b.North, b.West, b.North, b.West, b.South)
+}
@@ -3,30 +3,52 @@ package osm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const defaultOverpassURL = "https://overpass-api.de/api/interpreter"
+const (
+	DefaultOverpassURL     = "https://overpass-api.de/api/interpreter"
+	DefaultOverpassTimeout = 5 * time.Minute
+
+	overpassMaxAttempts = 5
+	overpassBackoff     = 15 * time.Second
+	overpassMaxBackoff  = 5 * time.Minute
+)
 
 type OverpassClient struct {
 	httpClient *http.Client
 	baseURL    string
+	statusURL  string
 }
 
-func NewOverpassClient() *OverpassClient {
+// NewOverpassClient creates a client for the interpreter at baseURL
+// (DefaultOverpassURL if empty), e.g. a private or local Overpass instance.
+func NewOverpassClient(baseURL string, timeout time.Duration) *OverpassClient {
+	if baseURL == "" {
+		baseURL = DefaultOverpassURL
+	}
+	if timeout <= 0 {
+		timeout = DefaultOverpassTimeout
+	}
+
 	return &OverpassClient{
-		httpClient: &http.Client{Timeout: 5 * time.Minute},
-		baseURL:    defaultOverpassURL,
+		httpClient: &http.Client{Timeout: timeout},
+		baseURL:    baseURL,
+		statusURL:  strings.TrimSuffix(baseURL, "/interpreter") + "/status",
 	}
 }
 
 type OverpassResponse struct {
 	Elements []Element `json:"elements"`
+	Remark   string    `json:"remark,omitempty"`
 }
 
 type Element struct {
@@ -36,13 +58,86 @@ type Element struct {
 	Lon  float64           `json:"lon,omitempty"`
 	Tags map[string]string `json:"tags,omitempty"`
 
-	Center *struct {
-		Lat float64 `json:"lat"`
-		Lon float64 `json:"lon"`
-	} `json:"center,omitempty"`
+	Center *Point `json:"center,omitempty"`
+}
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// OverpassError is a non-200 response of the interpreter.
+type OverpassError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is taken from the Retry-After header of 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *OverpassError) Error() string {
+	return fmt.Sprintf("overpass error: %d %s - %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Temporary reports whether the request may succeed when repeated: rate
+// limiting, an overloaded server or a gateway timeout.
+func (e *OverpassError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
 }
 
+// Query runs an Overpass QL query. It waits for a free slot according to
+// /api/status and retries rate limiting, gateway errors and network
+// failures with exponential backoff.
 func (c *OverpassClient) Query(ctx context.Context, query string) (*OverpassResponse, error) {
+	backoff := overpassBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= overpassMaxAttempts; attempt++ {
+		if err := c.WaitForSlot(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.query(ctx, query)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		wait := backoff
+		var oe *OverpassError
+		if errors.As(err, &oe) {
+			if !oe.Temporary() {
+				return nil, err
+			}
+			if oe.RetryAfter > wait {
+				wait = oe.RetryAfter
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt == overpassMaxAttempts {
+			break
+		}
+
+		log.Printf("Overpass query failed (attempt %d/%d), retrying in %s: %v", attempt, overpassMaxAttempts, wait, err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		backoff *= 2
+		if backoff > overpassMaxBackoff {
+			backoff = overpassMaxBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("overpass query failed after %d attempts: %w", overpassMaxAttempts, lastErr)
+}
+
+func (c *OverpassClient) query(ctx context.Context, query string) (*OverpassResponse, error) {
 	data := url.Values{}
 	data.Set("data", query)
 
@@ -60,8 +155,12 @@ func (c *OverpassClient) Query(ctx context.Context, query string) (*OverpassResp
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("overpass error: %s - %s", resp.Status, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		oe := &OverpassError{StatusCode: resp.StatusCode, Body: string(body)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			oe.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, oe
 	}
 
 	var result OverpassResponse
@@ -69,6 +168,12 @@ func (c *OverpassClient) Query(ctx context.Context, query string) (*OverpassResp
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
+	// A query that runs out of time or memory still returns 200 with
+	// partial elements and a remark
+	if strings.Contains(result.Remark, "runtime error") {
+		return nil, &OverpassError{StatusCode: http.StatusGatewayTimeout, Body: result.Remark}
+	}
+
 	return &result, nil
 }
 
@@ -79,13 +184,6 @@ type BBox struct {
 	East  float64
 }
 
-var MoscowBBox = BBox{
-	South: 55.1,
-	West:  36.8,
-	North: 56.1,
-	East:  38.2,
-}
-
 func (c *OverpassClient) QueryHistoricPlaces(ctx context.Context, bbox BBox) (*OverpassResponse, error) {
 	query := fmt.Sprintf(`
 [out:json][timeout:300];
@@ -0,0 +1,126 @@
+package osm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OverpassStatus is the slot information from /api/status.
+type OverpassStatus struct {
+	// RateLimit is the number of slots per client; 0 means unlimited.
+	RateLimit int
+	// Available is the number of slots free now.
+	Available int
+	// NextSlot is the wait until the next slot frees up when none is
+	// available.
+	NextSlot time.Duration
+}
+
+const maxSlotWait = 5 * time.Minute
+
+var (
+	rateLimitLine = regexp.MustCompile(`^Rate limit: (\d+)`)
+	availableLine = regexp.MustCompile(`^(\d+) slots? available now`)
+	slotAfterLine = regexp.MustCompile(`^Slot available after: .*, in (-?\d+) seconds?`)
+)
+
+// Status fetches /api/status. Instances without the endpoint (e.g. some
+// local setups) return ok = false.
+func (c *OverpassClient) Status(ctx context.Context) (*OverpassStatus, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.statusURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("overpass status: %s", resp.Status)
+	}
+
+	status, err := parseOverpassStatus(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return status, true, nil
+}
+
+// WaitForSlot blocks until the server has a free slot for this client.
+// Status errors are ignored: the query itself then reports 429 if needed.
+func (c *OverpassClient) WaitForSlot(ctx context.Context) error {
+	for {
+		status, ok, err := c.Status(ctx)
+		if err != nil || !ok || status.RateLimit == 0 || status.Available > 0 {
+			return ctx.Err()
+		}
+
+		// Without "Slot available after" lines all slots run queries
+		wait := status.NextSlot + time.Second
+		if status.NextSlot == 0 {
+			wait = 5 * time.Second
+		}
+		if wait > maxSlotWait {
+			wait = maxSlotWait
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func parseOverpassStatus(r io.Reader) (*OverpassStatus, error) {
+	status := &OverpassStatus{}
+	first := true
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := rateLimitLine.FindStringSubmatch(line); m != nil {
+			status.RateLimit, _ = strconv.Atoi(m[1])
+		} else if m := availableLine.FindStringSubmatch(line); m != nil {
+			status.Available, _ = strconv.Atoi(m[1])
+		} else if m := slotAfterLine.FindStringSubmatch(line); m != nil {
+			seconds, _ := strconv.Atoi(m[1])
+			wait := time.Duration(seconds) * time.Second
+			if wait < 0 {
+				wait = 0
+			}
+			if first || wait < status.NextSlot {
+				status.NextSlot = wait
+				first = false
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read overpass status: %w", err)
+	}
+	return status, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
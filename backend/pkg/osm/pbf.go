@@ -0,0 +1,689 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Limits from the PBF specification.
+const (
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+var supportedPBFFeatures = map[string]bool{
+	"OsmSchema-V0.6":        true,
+	"DenseNodes":            true,
+	"HistoricalInformation": true,
+}
+
+type pbfMember struct {
+	id  int64
+	typ int // 0 node, 1 way, 2 relation
+}
+
+// pbfHandler receives decoded elements. Nil callbacks skip the element
+// type. Untagged nodes are passed with nil tags.
+type pbfHandler struct {
+	node     func(id int64, lat, lon float64, tags map[string]string)
+	way      func(id int64, tags map[string]string, refs []int64)
+	relation func(id int64, tags map[string]string, members []pbfMember)
+	// skipTags leaves tags nil for ways and relations.
+	skipTags bool
+}
+
+// ReadPBF streams an OSM PBF extract and returns the elements accepted by
+// filter, with the same shape as an Overpass "out center" response: nodes
+// carry coordinates, ways and relations carry a Center. A nil bbox keeps
+// the whole extract.
+//
+// The file is read up to three times (ways and relations, relation member
+// ways, nodes) so that only coordinates of referenced nodes are kept in
+// memory.
+func ReadPBF(ctx context.Context, path string, filter TagFilter, bbox *BBox) ([]Element, error) {
+	type way struct {
+		id   int64
+		tags map[string]string
+		refs []int64
+	}
+	type relation struct {
+		id      int64
+		tags    map[string]string
+		members []pbfMember
+	}
+
+	var ways []way
+	var relations []relation
+	memberWays := make(map[int64][]int64)
+
+	err := scanPBF(ctx, path, pbfHandler{
+		way: func(id int64, tags map[string]string, refs []int64) {
+			if filter("way", tags) {
+				ways = append(ways, way{id: id, tags: tags, refs: refs})
+			}
+		},
+		relation: func(id int64, tags map[string]string, members []pbfMember) {
+			if !filter("relation", tags) {
+				return
+			}
+			relations = append(relations, relation{id: id, tags: tags, members: members})
+			for _, m := range members {
+				if m.typ == 1 {
+					memberWays[m.id] = nil
+				}
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(memberWays) > 0 {
+		err = scanPBF(ctx, path, pbfHandler{
+			way: func(id int64, _ map[string]string, refs []int64) {
+				if _, ok := memberWays[id]; ok {
+					memberWays[id] = refs
+				}
+			},
+			skipTags: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	coords := make(map[int64]Point)
+	for _, w := range ways {
+		for _, ref := range w.refs {
+			coords[ref] = Point{}
+		}
+	}
+	for _, refs := range memberWays {
+		for _, ref := range refs {
+			coords[ref] = Point{}
+		}
+	}
+	for _, r := range relations {
+		for _, m := range r.members {
+			if m.typ == 0 {
+				coords[m.id] = Point{}
+			}
+		}
+	}
+
+	var elements []Element
+	found := make(map[int64]bool, len(coords))
+
+	err = scanPBF(ctx, path, pbfHandler{
+		node: func(id int64, lat, lon float64, tags map[string]string) {
+			if _, ok := coords[id]; ok {
+				coords[id] = Point{Lat: lat, Lon: lon}
+				found[id] = true
+			}
+			if tags != nil && filter("node", tags) && (bbox == nil || bbox.Contains(lat, lon)) {
+				elements = append(elements, Element{Type: "node", ID: id, Lat: lat, Lon: lon, Tags: tags})
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := func(id int64) (Point, bool) {
+		if !found[id] {
+			return Point{}, false
+		}
+		return coords[id], true
+	}
+
+	wayCenters := make(map[int64]Point, len(memberWays))
+	for id, refs := range memberWays {
+		if center, ok := wayCenter(refs, lookup); ok {
+			wayCenters[id] = center
+		}
+	}
+
+	addWithCenter := func(typ string, id int64, tags map[string]string, center Point) {
+		if bbox != nil && !bbox.Contains(center.Lat, center.Lon) {
+			return
+		}
+		c := center
+		elements = append(elements, Element{Type: typ, ID: id, Tags: tags, Center: &c})
+	}
+
+	for _, w := range ways {
+		if center, ok := wayCenter(w.refs, lookup); ok {
+			addWithCenter("way", w.id, w.tags, center)
+		}
+	}
+
+	for _, r := range relations {
+		var points []Point
+		for _, m := range r.members {
+			switch m.typ {
+			case 0:
+				if p, ok := lookup(m.id); ok {
+					points = append(points, p)
+				}
+			case 1:
+				if p, ok := wayCenters[m.id]; ok {
+					points = append(points, p)
+				}
+			}
+		}
+		if len(points) > 0 {
+			addWithCenter("relation", r.id, r.tags, meanPoint(points))
+		}
+	}
+
+	return elements, nil
+}
+
+// wayCenter returns the area centroid of a closed way and the mean of the
+// nodes of an open one. Nodes missing from the extract are ignored.
+func wayCenter(refs []int64, lookup func(int64) (Point, bool)) (Point, bool) {
+	points := make([]Point, 0, len(refs))
+	for _, ref := range refs {
+		if p, ok := lookup(ref); ok {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return Point{}, false
+	}
+
+	closed := len(refs) >= 4 && refs[0] == refs[len(refs)-1] && len(points) == len(refs)
+	if !closed {
+		return meanPoint(points), true
+	}
+
+	// Shoelace formula relative to the first vertex to keep precision.
+	origin := points[0]
+	var area, cx, cy float64
+	for i := 0; i < len(points)-1; i++ {
+		x0, y0 := points[i].Lon-origin.Lon, points[i].Lat-origin.Lat
+		x1, y1 := points[i+1].Lon-origin.Lon, points[i+1].Lat-origin.Lat
+		cross := x0*y1 - x1*y0
+		area += cross
+		cx += (x0 + x1) * cross
+		cy += (y0 + y1) * cross
+	}
+	if area == 0 {
+		return meanPoint(points[:len(points)-1]), true
+	}
+
+	return Point{
+		Lat: origin.Lat + cy/(3*area),
+		Lon: origin.Lon + cx/(3*area),
+	}, true
+}
+
+func meanPoint(points []Point) Point {
+	var lat, lon float64
+	for _, p := range points {
+		lat += p.Lat
+		lon += p.Lon
+	}
+	n := float64(len(points))
+	return Point{Lat: lat / n, Lon: lon / n}
+}
+
+// scanPBF decodes the file block by block and passes elements to h.
+func scanPBF(ctx context.Context, path string, h pbfHandler) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open pbf: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	var sizeBuf [4]byte
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("read blob header size: %w", err)
+		}
+
+		headerSize := binary.BigEndian.Uint32(sizeBuf[:])
+		if headerSize > maxBlobHeaderSize {
+			return fmt.Errorf("blob header too large: %d bytes", headerSize)
+		}
+
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("read blob header: %w", err)
+		}
+
+		blobType, dataSize, err := parseBlobHeader(header)
+		if err != nil {
+			return err
+		}
+		if dataSize > maxBlobSize {
+			return fmt.Errorf("blob too large: %d bytes", dataSize)
+		}
+
+		blob := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return fmt.Errorf("read blob: %w", err)
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			data, err := decodeBlob(blob)
+			if err != nil {
+				return err
+			}
+			if err := checkPBFHeader(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			if h.node == nil && h.way == nil && h.relation == nil {
+				continue
+			}
+			data, err := decodeBlob(blob)
+			if err != nil {
+				return err
+			}
+			if err := decodePrimitiveBlock(data, h); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pbfField is a protobuf field: varint holds varint values, bytes holds
+// length-delimited values.
+type pbfField struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+func parseMessage(b []byte, fn func(f pbfField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("pbf: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		f := pbfField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("pbf: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendVarints handles both packed and unpacked repeated varint fields.
+func appendVarints(dst []uint64, f pbfField) ([]uint64, error) {
+	switch f.typ {
+	case protowire.VarintType:
+		return append(dst, f.varint), nil
+	case protowire.BytesType:
+		b := f.bytes
+		for len(b) > 0 {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, fmt.Errorf("pbf: %w", protowire.ParseError(n))
+			}
+			dst = append(dst, v)
+			b = b[n:]
+		}
+		return dst, nil
+	}
+	return dst, nil
+}
+
+// deltaDecode turns zigzag-encoded deltas into absolute values.
+func deltaDecode(values []uint64) []int64 {
+	result := make([]int64, len(values))
+	var acc int64
+	for i, v := range values {
+		acc += protowire.DecodeZigZag(v)
+		result[i] = acc
+	}
+	return result
+}
+
+func parseBlobHeader(b []byte) (string, int, error) {
+	var blobType string
+	dataSize := -1
+
+	err := parseMessage(b, func(f pbfField) error {
+		switch f.num {
+		case 1:
+			blobType = string(f.bytes)
+		case 3:
+			dataSize = int(f.varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if dataSize < 0 {
+		return "", 0, errors.New("pbf: blob header without datasize")
+	}
+	return blobType, dataSize, nil
+}
+
+func decodeBlob(b []byte) ([]byte, error) {
+	var raw, zlibData []byte
+	rawSize := 0
+	compression := ""
+
+	err := parseMessage(b, func(f pbfField) error {
+		switch f.num {
+		case 1:
+			raw = f.bytes
+		case 2:
+			rawSize = int(f.varint)
+		case 3:
+			zlibData = f.bytes
+		case 4:
+			compression = "lzma"
+		case 6:
+			compression = "lz4"
+		case 7:
+			compression = "zstd"
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case raw != nil:
+		return raw, nil
+	case zlibData != nil:
+		if rawSize > maxBlobSize {
+			return nil, fmt.Errorf("pbf: blob raw size too large: %d bytes", rawSize)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(zlibData))
+		if err != nil {
+			return nil, fmt.Errorf("pbf: zlib: %w", err)
+		}
+		defer zr.Close()
+
+		buf := bytes.NewBuffer(make([]byte, 0, rawSize))
+		if _, err := io.Copy(buf, io.LimitReader(zr, maxBlobSize+1)); err != nil {
+			return nil, fmt.Errorf("pbf: zlib: %w", err)
+		}
+		if buf.Len() > maxBlobSize {
+			return nil, errors.New("pbf: decompressed blob too large")
+		}
+		return buf.Bytes(), nil
+	case compression != "":
+		return nil, fmt.Errorf("pbf: unsupported %s compression", compression)
+	}
+	return nil, errors.New("pbf: empty blob")
+}
+
+func checkPBFHeader(b []byte) error {
+	return parseMessage(b, func(f pbfField) error {
+		if f.num == 4 && !supportedPBFFeatures[string(f.bytes)] {
+			return fmt.Errorf("pbf: unsupported required feature %q", f.bytes)
+		}
+		return nil
+	})
+}
+
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *primitiveBlock) coord(offset, value int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*value)
+}
+
+func (b *primitiveBlock) str(i uint64) string {
+	if i < uint64(len(b.strings)) {
+		return b.strings[i]
+	}
+	return ""
+}
+
+func (b *primitiveBlock) tags(keys, vals []uint64) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(keys))
+	for i, k := range keys {
+		if i < len(vals) {
+			tags[b.str(k)] = b.str(vals[i])
+		}
+	}
+	return tags
+}
+
+func decodePrimitiveBlock(data []byte, h pbfHandler) error {
+	block := &primitiveBlock{granularity: 100}
+	var groups [][]byte
+
+	err := parseMessage(data, func(f pbfField) error {
+		switch f.num {
+		case 1:
+			return parseMessage(f.bytes, func(s pbfField) error {
+				if s.num == 1 {
+					block.strings = append(block.strings, string(s.bytes))
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, f.bytes)
+		case 17:
+			block.granularity = int64(f.varint)
+		case 19:
+			block.latOffset = int64(f.varint)
+		case 20:
+			block.lonOffset = int64(f.varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		err := parseMessage(group, func(f pbfField) error {
+			switch {
+			case f.num == 1 && h.node != nil:
+				return block.decodeNode(f.bytes, h)
+			case f.num == 2 && h.node != nil:
+				return block.decodeDenseNodes(f.bytes, h)
+			case f.num == 3 && h.way != nil:
+				return block.decodeWay(f.bytes, h)
+			case f.num == 4 && h.relation != nil:
+				return block.decodeRelation(f.bytes, h)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *primitiveBlock) decodeNode(data []byte, h pbfHandler) error {
+	var id, lat, lon int64
+	var keys, vals []uint64
+
+	err := parseMessage(data, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			id = protowire.DecodeZigZag(f.varint)
+		case 2:
+			keys, err = appendVarints(keys, f)
+		case 3:
+			vals, err = appendVarints(vals, f)
+		case 8:
+			lat = protowire.DecodeZigZag(f.varint)
+		case 9:
+			lon = protowire.DecodeZigZag(f.varint)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.node(id, b.coord(b.latOffset, lat), b.coord(b.lonOffset, lon), b.tags(keys, vals))
+	return nil
+}
+
+func (b *primitiveBlock) decodeDenseNodes(data []byte, h pbfHandler) error {
+	var ids, lats, lons, keysVals []uint64
+
+	err := parseMessage(data, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			ids, err = appendVarints(ids, f)
+		case 8:
+			lats, err = appendVarints(lats, f)
+		case 9:
+			lons, err = appendVarints(lons, f)
+		case 10:
+			keysVals, err = appendVarints(keysVals, f)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("pbf: dense nodes with mismatched arrays")
+	}
+
+	idValues := deltaDecode(ids)
+	latValues := deltaDecode(lats)
+	lonValues := deltaDecode(lons)
+
+	kv := 0
+	for i, id := range idValues {
+		var tags map[string]string
+		for kv < len(keysVals) && keysVals[kv] != 0 {
+			if kv+1 >= len(keysVals) {
+				return errors.New("pbf: truncated dense node tags")
+			}
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[b.str(keysVals[kv])] = b.str(keysVals[kv+1])
+			kv += 2
+		}
+		kv++ // skip the 0 delimiter
+
+		h.node(id, b.coord(b.latOffset, latValues[i]), b.coord(b.lonOffset, lonValues[i]), tags)
+	}
+	return nil
+}
+
+func (b *primitiveBlock) decodeWay(data []byte, h pbfHandler) error {
+	var id int64
+	var keys, vals, refs []uint64
+
+	err := parseMessage(data, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			id = int64(f.varint)
+		case 2:
+			if !h.skipTags {
+				keys, err = appendVarints(keys, f)
+			}
+		case 3:
+			if !h.skipTags {
+				vals, err = appendVarints(vals, f)
+			}
+		case 8:
+			refs, err = appendVarints(refs, f)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.way(id, b.tags(keys, vals), deltaDecode(refs))
+	return nil
+}
+
+func (b *primitiveBlock) decodeRelation(data []byte, h pbfHandler) error {
+	var id int64
+	var keys, vals, memIDs, types []uint64
+
+	err := parseMessage(data, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			id = int64(f.varint)
+		case 2:
+			if !h.skipTags {
+				keys, err = appendVarints(keys, f)
+			}
+		case 3:
+			if !h.skipTags {
+				vals, err = appendVarints(vals, f)
+			}
+		case 9:
+			memIDs, err = appendVarints(memIDs, f)
+		case 10:
+			types, err = appendVarints(types, f)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := deltaDecode(memIDs)
+	members := make([]pbfMember, len(ids))
+	for i, memberID := range ids {
+		members[i] = pbfMember{id: memberID}
+		if i < len(types) {
+			members[i].typ = int(types[i])
+		}
+	}
+
+	h.relation(id, b.tags(keys, vals), members)
+	return nil
+}
@@ -0,0 +1,176 @@
+package osm
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReplicationState describes a diff in a replication directory.
+type ReplicationState struct {
+	Sequence  int64
+	Timestamp time.Time
+}
+
+// Replication reads osmChange diffs from an OSM replication directory
+// (planet minute/hour/day diffs, Geofabrik updates). The base is either an
+// http(s) URL or a local directory with the same layout:
+//
+//	state.txt
+//	000/004/123.osc.gz
+//	000/004/123.state.txt
+type Replication struct {
+	base       string
+	httpClient *http.Client
+}
+
+func NewReplication(base string) *Replication {
+	return &Replication{
+		base:       strings.TrimSuffix(strings.TrimPrefix(base, "file://"), "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// State returns the latest published diff.
+func (r *Replication) State(ctx context.Context) (*ReplicationState, error) {
+	return r.readState(ctx, "state.txt")
+}
+
+// DiffState returns the state of the diff with the given sequence number.
+func (r *Replication) DiffState(ctx context.Context, seq int64) (*ReplicationState, error) {
+	return r.readState(ctx, sequencePath(seq)+".state.txt")
+}
+
+// Diff opens the uncompressed osmChange document with the given sequence
+// number. Local directories may also hold uncompressed .osc files.
+func (r *Replication) Diff(ctx context.Context, seq int64) (io.ReadCloser, error) {
+	name := sequencePath(seq) + ".osc.gz"
+
+	rc, err := r.open(ctx, name)
+	if errors.Is(err, os.ErrNotExist) && !r.isRemote() {
+		return r.open(ctx, sequencePath(seq)+".osc")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+
+	return &gzipReadCloser{Reader: zr, file: rc}, nil
+}
+
+func (r *Replication) readState(ctx context.Context, name string) (*ReplicationState, error) {
+	rc, err := r.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	state, err := parseReplicationState(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return state, nil
+}
+
+func (r *Replication) isRemote() bool {
+	return strings.HasPrefix(r.base, "http://") || strings.HasPrefix(r.base, "https://")
+}
+
+func (r *Replication) open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !r.isRemote() {
+		return os.Open(filepath.Join(r.base, filepath.FromSlash(name)))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.base+"/"+name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("replication error: %s - %s", name, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// sequencePath splits the sequence number into the AAA/BBB/CCC layout.
+func sequencePath(seq int64) string {
+	s := fmt.Sprintf("%09d", seq)
+	return s[0:3] + "/" + s[3:6] + "/" + s[6:9]
+}
+
+// parseReplicationState reads a state.txt file (Java properties format).
+func parseReplicationState(r io.Reader) (*ReplicationState, error) {
+	state := &ReplicationState{Sequence: -1}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.ReplaceAll(value, `\`, "")
+
+		switch key {
+		case "sequenceNumber":
+			seq, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sequenceNumber %q", value)
+			}
+			state.Sequence = seq
+		case "timestamp":
+			ts, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q", value)
+			}
+			state.Timestamp = ts
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if state.Sequence < 0 {
+		return nil, fmt.Errorf("sequenceNumber missing")
+	}
+
+	return state, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}